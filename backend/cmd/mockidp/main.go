@@ -0,0 +1,252 @@
+// Command mockidp runs a minimal OpenID Connect provider for local development and
+// testing of the SSO flow. It auto-approves every authorization request as the
+// identity supplied in the query string (or the MOCK_IDP_* defaults), so never
+// expose it outside localhost.
+//
+// Point the server at it with:
+//
+//	OIDC_ISSUER=http://localhost:9999 OIDC_CLIENT_ID=grub-exchange OIDC_CLIENT_SECRET=dev-secret
+//
+// and override the identity per login, e.g.
+// /api/auth/oidc/login → IdP → /authorize?...&login_email=alex@example.com&login_name=Alex+Smith
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-key-1"
+
+type authRequest struct {
+	clientID      string
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	email         string
+	name          string
+	verified      bool
+	expiresAt     time.Time
+}
+
+type mockIdP struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*authRequest
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func main() {
+	port := getenv("MOCK_IDP_PORT", "9999")
+
+	idp, err := newMockIdP(
+		getenv("MOCK_IDP_ISSUER", "http://localhost:"+port),
+		getenv("MOCK_IDP_CLIENT_ID", "grub-exchange"),
+		getenv("MOCK_IDP_CLIENT_SECRET", "dev-secret"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+
+	log.Printf("Mock IdP listening on :%s (issuer %s, client %s)", port, idp.issuer, idp.clientID)
+	if err := http.ListenAndServe(":"+port, idp.routes()); err != nil {
+		log.Fatalf("Mock IdP stopped: %v", err)
+	}
+}
+
+func newMockIdP(issuer, clientID, clientSecret string) (*mockIdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &mockIdP{
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		key:          key,
+		codes:        make(map[string]*authRequest),
+	}, nil
+}
+
+func (m *mockIdP) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/jwks", m.jwks)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (m *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                m.issuer,
+		"authorization_endpoint":                m.issuer + "/authorize",
+		"token_endpoint":                        m.issuer + "/token",
+		"jwks_uri":                              m.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize skips the login screen and immediately redirects back with a code.
+func (m *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != m.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "only the code flow with S256 PKCE is supported", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	req := &authRequest{
+		clientID:      m.clientID,
+		redirectURI:   q.Get("redirect_uri"),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		email:         strings.ToLower(getParam(q, "login_email", getenv("MOCK_IDP_EMAIL", "dev@example.com"))),
+		name:          getParam(q, "login_name", getenv("MOCK_IDP_NAME", "Dev User")),
+		verified:      getParam(q, "login_email_verified", getenv("MOCK_IDP_EMAIL_VERIFIED", "true")) == "true",
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+
+	code := randomString(24)
+	m.mu.Lock()
+	m.codes[code] = req
+	m.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (m *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	if r.PostForm.Get("client_id") != m.clientID || r.PostForm.Get("client_secret") != m.clientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	req, ok := m.codes[code]
+	delete(m.codes, code) // codes are single-use
+	m.mu.Unlock()
+
+	if !ok || time.Now().After(req.expiresAt) || req.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != req.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	givenName := strings.SplitN(req.name, " ", 2)[0]
+	claims := jwt.MapClaims{
+		"iss":                m.issuer,
+		"sub":                "mock|" + req.email,
+		"aud":                m.clientID,
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              req.nonce,
+		"email":              req.email,
+		"email_verified":     req.verified,
+		"name":               req.name,
+		"given_name":         givenName,
+		"preferred_username": strings.SplitN(req.email, "@", 2)[0],
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(m.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(24),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (m *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := m.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func getParam(q url.Values, key, fallback string) string {
+	if v := q.Get(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func randomString(numBytes int) string {
+	buf := make([]byte, numBytes)
+	if _, err := rand.Read(buf); err != nil {
+		log.Fatalf("Failed to read random bytes: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
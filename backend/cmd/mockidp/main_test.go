@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"grub-exchange/internal/oidc"
+)
+
+const testRedirectURL = "http://app.test/api/auth/oidc/callback"
+
+// newTestIdP starts the mock IdP on a local server and returns a provider configured for it.
+func newTestIdP(t *testing.T) *oidc.Provider {
+	t.Helper()
+
+	var idp *mockIdP
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.routes().ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	var err error
+	idp, err = newMockIdP(srv.URL, "grub-exchange", "dev-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return oidc.NewProvider(&oidc.Config{
+		Issuer:       srv.URL,
+		ClientID:     "grub-exchange",
+		ClientSecret: "dev-secret",
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+}
+
+// authorize follows the authorization URL, with extra query parameters choosing the
+// identity, and returns the code and state the IdP redirects back with.
+func authorize(t *testing.T, authURL string, identity url.Values) (code, state string) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL + "&" + identity.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loc.Scheme + "://" + loc.Host + loc.Path; got != testRedirectURL {
+		t.Fatalf("redirected to %s, want %s", got, testRedirectURL)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestIdP(t)
+
+	tests := []struct {
+		name          string
+		identity      url.Values
+		wrongVerifier bool
+		wantErr       bool
+		wantEmail     string
+		wantVerified  bool
+		wantGiven     string
+		wantUsername  string
+	}{
+		{
+			name:         "verified email",
+			identity:     url.Values{"login_email": {"Alex@Example.com"}, "login_name": {"Alex Smith"}},
+			wantEmail:    "alex@example.com",
+			wantVerified: true,
+			wantGiven:    "Alex",
+			wantUsername: "alex",
+		},
+		{
+			name: "unverified email",
+			identity: url.Values{
+				"login_email":          {"sam@example.com"},
+				"login_name":           {"Sam"},
+				"login_email_verified": {"false"},
+			},
+			wantEmail:    "sam@example.com",
+			wantGiven:    "Sam",
+			wantUsername: "sam",
+		},
+		{
+			name:          "wrong code verifier",
+			identity:      url.Values{"login_email": {"eve@example.com"}},
+			wrongVerifier: true,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, _ := oidc.RandomString(24)
+			nonce, _ := oidc.RandomString(24)
+			verifier, _ := oidc.RandomString(48)
+
+			authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, gotState := authorize(t, authURL, tt.identity)
+			if gotState != state {
+				t.Fatalf("state = %q, want %q", gotState, state)
+			}
+
+			if tt.wrongVerifier {
+				verifier, _ = oidc.RandomString(48)
+			}
+			identity, err := provider.Exchange(ctx, code, verifier)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Exchange succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if identity.Nonce != nonce {
+				t.Errorf("Nonce = %q, want %q", identity.Nonce, nonce)
+			}
+			if identity.Subject != "mock|"+tt.wantEmail {
+				t.Errorf("Subject = %q, want %q", identity.Subject, "mock|"+tt.wantEmail)
+			}
+			if identity.Email != tt.wantEmail || identity.EmailVerified != tt.wantVerified {
+				t.Errorf("Email = %q (verified %v), want %q (verified %v)",
+					identity.Email, identity.EmailVerified, tt.wantEmail, tt.wantVerified)
+			}
+			if identity.GivenName != tt.wantGiven || identity.PreferredUsername != tt.wantUsername {
+				t.Errorf("GivenName, PreferredUsername = %q, %q, want %q, %q",
+					identity.GivenName, identity.PreferredUsername, tt.wantGiven, tt.wantUsername)
+			}
+
+			// Codes are single-use
+			if _, err := provider.Exchange(ctx, code, verifier); err == nil {
+				t.Error("second Exchange of the same code succeeded")
+			}
+		})
+	}
+}
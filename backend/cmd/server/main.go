@@ -4,10 +4,13 @@ import (
 	"grub-exchange/internal/api"
 	"grub-exchange/internal/api/handlers"
 	"grub-exchange/internal/database"
+	"grub-exchange/internal/oidc"
 	"grub-exchange/internal/repository"
 	"grub-exchange/internal/services"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,37 +28,82 @@ func main() {
 	txnRepo := repository.NewTransactionRepo(db)
 	notifRepo := repository.NewNotificationRepo(db)
 	achieveRepo := repository.NewAchievementRepo(db)
+	twoFactorRepo := repository.NewTwoFactorRepo(db)
+	apiKeyRepo := repository.NewAPIKeyRepo(db)
 
 	postRepo := repository.NewPostRepo(db)
 	snapshotRepo := repository.NewMarketSnapshotRepo(db)
+	seasonRepo := repository.NewSeasonRepo(db)
+	leagueRepo := repository.NewLeagueRepo(db)
+	alertRepo := repository.NewAlertRepo(db)
+	followRepo := repository.NewFollowRepo(db)
+	privacyRepo := repository.NewPrivacyRepo(db)
+	moderationRepo := repository.NewModerationRepo(db)
+	candleRepo := repository.NewCandleRepo(db)
+	retentionRepo := repository.NewRetentionRepo(db)
 
 	// Initialize services
-	authService := services.NewAuthService(db, userRepo, balanceRepo, txnRepo)
-	achieveSvc := services.NewAchievementService(achieveRepo, balanceRepo, portfolioRepo, userRepo)
-	tradingService := services.NewTradingService(db, userRepo, balanceRepo, portfolioRepo, txnRepo, notifRepo, achieveSvc)
-	portfolioService := services.NewPortfolioService(userRepo, balanceRepo, portfolioRepo, txnRepo)
-	marketService := services.NewMarketService(userRepo, balanceRepo, portfolioRepo, txnRepo, snapshotRepo, notifRepo)
-	marketMaker := services.NewMarketMaker(db, userRepo, balanceRepo, portfolioRepo, txnRepo, postRepo)
+	tickerService := services.NewTickerService(db, userRepo, portfolioRepo, notifRepo)
+	authService := services.NewAuthService(db, userRepo, balanceRepo, txnRepo, twoFactorRepo, tickerService)
+	achieveSvc := services.NewAchievementService(achieveRepo, balanceRepo, portfolioRepo, userRepo, notifRepo)
+	alertService := services.NewAlertService(alertRepo, userRepo, txnRepo, notifRepo)
+	tradingService := services.NewTradingService(db, userRepo, balanceRepo, portfolioRepo, txnRepo, notifRepo, privacyRepo, achieveSvc, alertService)
+	portfolioService := services.NewPortfolioService(userRepo, balanceRepo, portfolioRepo, txnRepo, achieveSvc)
+	marketService := services.NewMarketService(userRepo, balanceRepo, portfolioRepo, txnRepo, snapshotRepo, notifRepo, privacyRepo, achieveSvc, alertService)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	accountService := services.NewAccountService(db, authService, userRepo, balanceRepo, portfolioRepo, txnRepo, notifRepo, leagueRepo, alertService)
+	feedService := services.NewFeedService(followRepo, userRepo, notifRepo)
+	privacyService := services.NewPrivacyService(privacyRepo)
+	profileService := services.NewProfileService(userRepo, portfolioRepo, txnRepo, postRepo, achieveRepo, followRepo, privacyRepo, portfolioService)
+	moderationService := services.NewModerationService(db, moderationRepo, postRepo, userRepo, notifRepo, reportHideThresholdFromEnv())
+	entityService := services.NewPostEntityService(postRepo, userRepo, leagueRepo, notifRepo)
+	leagueService := services.NewLeagueService(db, leagueRepo, postRepo, userRepo, marketService, achieveSvc, moderationService, entityService)
+	seasonLength, firstSeasonStart := seasonConfigFromEnv()
+	seasonService := services.NewSeasonService(db, seasonRepo, balanceRepo, notifRepo, privacyRepo, seasonLength, firstSeasonStart)
+	sentimentService := services.NewSentimentService(postRepo)
+	searchService := services.NewSearchService(userRepo, postRepo)
+	candleService := services.NewCandleService(userRepo, candleRepo)
+	retentionService := services.NewRetentionService(retentionRepo, candleRepo)
+	marketMaker := services.NewMarketMaker(db, userRepo, balanceRepo, portfolioRepo, txnRepo, sentimentService, alertService)
+
+	// Single sign-on is optional; enabled when OIDC_ISSUER and OIDC_CLIENT_ID are set
+	var oidcProvider *oidc.Provider
+	if cfg := oidc.ConfigFromEnv(); cfg != nil {
+		oidcProvider = oidc.NewProvider(cfg)
+		log.Printf("OIDC sign-in enabled (issuer: %s)", cfg.Issuer)
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, tickerService, oidcProvider)
 	tradingHandler := handlers.NewTradingHandler(tradingService)
 	portfolioHandler := handlers.NewPortfolioHandler(portfolioService)
-	marketHandler := handlers.NewMarketHandler(marketService)
-	profileHandler := handlers.NewProfileHandler(authService, userRepo)
+	marketHandler := handlers.NewMarketHandler(marketService, candleService)
+	profileHandler := handlers.NewProfileHandler(authService, tickerService, accountService, privacyService, profileService, userRepo)
 	notifHandler := handlers.NewNotificationHandler(notifRepo)
 	achieveHandler := handlers.NewAchievementHandler(achieveSvc)
-	postHandler := handlers.NewPostHandler(postRepo, userRepo)
+	postHandler := handlers.NewPostHandler(postRepo, userRepo, notifRepo, achieveSvc, leagueService, moderationService, entityService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	seasonHandler := handlers.NewSeasonHandler(seasonService)
+	leagueHandler := handlers.NewLeagueHandler(leagueService)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	feedHandler := handlers.NewFeedHandler(feedService)
+	moderationHandler := handlers.NewModerationHandler(moderationService, sentimentService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+
+	grantAdminsFromEnv(moderationRepo)
 
 	// Backfill market snapshots from historical data on first run
 	snapshotRepo.BackfillFromHistory()
+	achieveRepo.BackfillEvents()
+	entityService.BackfillEntities()
 
 	// Start background jobs
-	go runScheduledJobs(marketService, achieveSvc, userRepo)
+	go runScheduledJobs(marketService, achieveSvc, seasonService, candleService, retentionService, userRepo)
 	go marketMaker.Run(60 * time.Second) // nudge prices every 60 seconds
 
 	// Setup router
-	router := api.SetupRouter(authHandler, tradingHandler, portfolioHandler, marketHandler, profileHandler, notifHandler, achieveHandler, postHandler)
+	router := api.SetupRouter(authHandler, tradingHandler, portfolioHandler, marketHandler, profileHandler, notifHandler, achieveHandler, postHandler, apiKeyHandler, seasonHandler, leagueHandler, alertHandler, feedHandler, moderationHandler, searchHandler, retentionHandler, userRepo, apiKeyRepo)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -68,7 +116,14 @@ func main() {
 	}
 }
 
-func runScheduledJobs(marketService *services.MarketService, achieveSvc *services.AchievementService, userRepo *repository.UserRepo) {
+func runScheduledJobs(
+	marketService *services.MarketService,
+	achieveSvc *services.AchievementService,
+	seasonService *services.SeasonService,
+	candleService *services.CandleService,
+	retentionService *services.RetentionService,
+	userRepo *repository.UserRepo,
+) {
 	// Daily decay runs every 24h
 	decayTicker := time.NewTicker(24 * time.Hour)
 	defer decayTicker.Stop()
@@ -77,7 +132,7 @@ func runScheduledJobs(marketService *services.MarketService, achieveSvc *service
 	dividendTicker := time.NewTicker(24 * time.Hour)
 	defer dividendTicker.Stop()
 
-	// Achievement check runs every hour (for Diamond Hands, Whale, etc.)
+	// Achievement check runs every hour (for Diamond Hands and other state-based rules)
 	achieveTicker := time.NewTicker(1 * time.Hour)
 	defer achieveTicker.Stop()
 
@@ -85,8 +140,27 @@ func runScheduledJobs(marketService *services.MarketService, achieveSvc *service
 	snapshotTicker := time.NewTicker(5 * time.Minute)
 	defer snapshotTicker.Stop()
 
-	// Record initial snapshot on startup
+	// Leaderboards are materialized every minute rather than per request
+	leaderboardTicker := time.NewTicker(1 * time.Minute)
+	defer leaderboardTicker.Stop()
+
+	// Season rollover check every 10 minutes (finalizes ended seasons, starts the next one)
+	seasonTicker := time.NewTicker(10 * time.Minute)
+	defer seasonTicker.Stop()
+
+	// Price candles are rolled up every minute; requests aggregate the rest on the fly
+	candleTicker := time.NewTicker(1 * time.Minute)
+	defer candleTicker.Stop()
+
+	// Old price history and portfolio snapshots are compacted hourly
+	retentionTicker := time.NewTicker(1 * time.Hour)
+	defer retentionTicker.Stop()
+
+	// Record initial snapshot and make sure a season is running on startup
 	marketService.RecordMarketSnapshot()
+	marketService.RefreshLeaderboards()
+	seasonService.RunSeasonJobs()
+	candleService.RollUp()
 
 	for {
 		select {
@@ -101,6 +175,14 @@ func runScheduledJobs(marketService *services.MarketService, achieveSvc *service
 			checkPeriodicAchievements(achieveSvc, userRepo)
 		case <-snapshotTicker.C:
 			marketService.RecordMarketSnapshot()
+		case <-leaderboardTicker.C:
+			marketService.RefreshLeaderboards()
+		case <-seasonTicker.C:
+			seasonService.RunSeasonJobs()
+		case <-candleTicker.C:
+			candleService.RollUp()
+		case <-retentionTicker.C:
+			retentionService.Run()
 		}
 	}
 }
@@ -112,8 +194,75 @@ func checkPeriodicAchievements(achieveSvc *services.AchievementService, userRepo
 		return
 	}
 	for _, u := range users {
-		achieveSvc.CheckDiamondHands(u.ID)
-		// Whale is also checked after trades, but check periodically too
-		achieveSvc.CheckAfterTrade(u.ID)
+		achieveSvc.EvaluateAll(u.ID)
+	}
+}
+
+// seasonConfigFromEnv reads SEASON_LENGTH_DAYS (default 30) and SEASON_START, an optional
+// YYYY-MM-DD date for when the first season begins.
+func seasonConfigFromEnv() (time.Duration, time.Time) {
+	lengthDays := 30
+	if v := os.Getenv("SEASON_LENGTH_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			lengthDays = days
+		} else {
+			log.Printf("Ignoring invalid SEASON_LENGTH_DAYS %q", v)
+		}
+	}
+
+	var firstStart time.Time
+	if v := os.Getenv("SEASON_START"); v != "" {
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			firstStart = t
+		} else {
+			log.Printf("Ignoring invalid SEASON_START %q", v)
+		}
+	}
+
+	return time.Duration(lengthDays) * 24 * time.Hour, firstStart
+}
+
+// reportHideThresholdFromEnv reads REPORT_HIDE_THRESHOLD: how many open reports hide a post
+// until an admin reviews it (default 3, 0 disables automatic hiding).
+func reportHideThresholdFromEnv() int {
+	threshold := 3
+	if v := os.Getenv("REPORT_HIDE_THRESHOLD"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			threshold = n
+		} else {
+			log.Printf("Ignoring invalid REPORT_HIDE_THRESHOLD %q", v)
+		}
+	}
+	return threshold
+}
+
+// grantAdminsFromEnv makes the users listed in ADMIN_USER_IDS (comma-separated) admins.
+// Admins are named by user ID rather than username, because usernames of closed accounts
+// (and names nobody has registered yet) can be taken by anyone. Admin rights are never
+// revoked here; remove them in the database.
+func grantAdminsFromEnv(moderationRepo *repository.ModerationRepo) {
+	var userIDs []int
+	for _, v := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			log.Printf("Ignoring invalid user ID %q in ADMIN_USER_IDS", v)
+			continue
+		}
+		userIDs = append(userIDs, id)
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	granted, err := moderationRepo.GrantAdmin(userIDs)
+	if err != nil {
+		log.Printf("Error granting admin rights: %v", err)
+		return
+	}
+	if granted > 0 {
+		log.Printf("Granted admin rights to %d user(s) from ADMIN_USER_IDS", granted)
 	}
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"grub-exchange/internal/models"
 	"grub-exchange/internal/services"
 	"net/http"
 
@@ -27,7 +28,11 @@ func (h *AchievementHandler) GetMyAchievements(c *gin.Context) {
 		return
 	}
 
-	all, err := h.achievementService.GetAllAchievements()
+	if earned == nil {
+		earned = []models.UserAchievement{}
+	}
+
+	all, err := h.achievementService.GetStatuses(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1,22 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"grub-exchange/internal/models"
+	"grub-exchange/internal/oidc"
 	"grub-exchange/internal/services"
+	"grub-exchange/internal/utils"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
+	authService   *services.AuthService
+	tickerService *services.TickerService
+	oidcProvider  *oidc.Provider // nil when single sign-on isn't configured
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, tickerService *services.TickerService, oidcProvider *oidc.Provider) *AuthHandler {
+	return &AuthHandler{authService: authService, tickerService: tickerService, oidcProvider: oidcProvider}
 }
 
+const oidcStateCookie = "grub_oidc_state"
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -26,6 +35,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	user, token, err := h.authService.Register(&req)
 	if err != nil {
+		var taken *services.TickerTakenError
+		if errors.As(err, &taken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "suggestions": taken.Suggestions})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -34,6 +48,27 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"user": user, "token": token})
 }
 
+// CheckTicker reports whether a ticker (or the one a first name would produce) is free,
+// with suggestions when it isn't. Used by the signup form before submitting.
+func (h *AuthHandler) CheckTicker(c *gin.Context) {
+	ticker := c.Query("ticker")
+	if ticker == "" {
+		ticker = c.Query("first_name")
+	}
+	if ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ticker or first_name is required"})
+		return
+	}
+
+	result, err := h.tickerService.Check(ticker)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -41,26 +76,206 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := h.authService.Login(&req)
+	user, token, challenge, err := h.authService.Login(&req)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 2FA enabled: the client must POST the code with this challenge to /auth/login/2fa
+	if challenge != "" {
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": challenge})
+		return
+	}
+
 	setAuthCookie(c, token)
 	c.JSON(http.StatusOK, gin.H{"user": user, "token": token})
 }
 
-func (h *AuthHandler) Logout(c *gin.Context) {
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	user, token, err := h.authService.CompleteTwoFactorLogin(&req)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	setAuthCookie(c, token)
+	c.JSON(http.StatusOK, gin.H{"user": user, "token": token})
+}
+
+// OIDCLogin starts the authorization code + PKCE flow by redirecting to the identity provider.
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if h.oidcProvider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+
+	state, err1 := oidc.RandomString(24)
+	nonce, err2 := oidc.RandomString(24)
+	verifier, err3 := oidc.RandomString(48)
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return
+	}
+
+	authURL, err := h.oidcProvider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider unavailable"})
+		return
+	}
+
+	stateToken, err := utils.GenerateOIDCStateToken(state, nonce, verifier)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return
+	}
+
+	// Lax is required here: the callback is a top-level redirect from the IdP
 	http.SetCookie(c.Writer, &http.Cookie{
-		Name:     "grub_token",
+		Name:     oidcStateCookie,
+		Value:    stateToken,
+		MaxAge:   10 * 60,
+		Path:     "/api/auth/oidc",
+		HttpOnly: true,
+		Secure:   isProduction(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes the flow and redirects back to the frontend with a session cookie set.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if h.oidcProvider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "single sign-on is not configured"})
+		return
+	}
+
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
 		Value:    "",
 		MaxAge:   -1,
-		Path:     "/",
-		Secure:   true,
+		Path:     "/api/auth/oidc",
 		HttpOnly: true,
-		SameSite: http.SameSiteNoneMode,
+		Secure:   isProduction(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if errParam := c.Query("error"); errParam != "" {
+		redirectToFrontend(c, "/login", url.Values{"error": {errParam}})
+		return
+	}
+
+	stored, err := utils.ValidateOIDCStateToken(stateToken)
+	if err != nil || c.Query("state") == "" || c.Query("state") != stored.State {
+		redirectToFrontend(c, "/login", url.Values{"error": {"sign-in session expired, please try again"}})
+		return
+	}
+
+	identity, err := h.oidcProvider.Exchange(c.Request.Context(), c.Query("code"), stored.CodeVerifier)
+	if err != nil || identity.Nonce != stored.Nonce {
+		redirectToFrontend(c, "/login", url.Values{"error": {"could not verify your identity"}})
+		return
+	}
+
+	_, token, challenge, err := h.authService.LoginWithOIDC(identity)
+	if err != nil {
+		redirectToFrontend(c, "/login", url.Values{"error": {err.Error()}})
+		return
+	}
+
+	if challenge != "" {
+		redirectToFrontend(c, "/login", url.Values{"challenge": {challenge}})
+		return
+	}
+
+	setAuthCookie(c, token)
+	redirectToFrontend(c, "/dashboard", nil)
+}
+
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	enabled, remaining, err := h.authService.GetTwoFactorStatus(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled, "recovery_codes_remaining": remaining})
+}
+
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.authService.BeginTwoFactorEnrollment(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactorEnrollment(userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "two-factor authentication enabled",
+		"recovery_codes": codes,
 	})
+}
+
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(userID, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	clearAuthCookie(c)
 	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
 }
 
@@ -79,8 +294,38 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+func isProduction() bool {
+	return os.Getenv("FRONTEND_URL") != "" && os.Getenv("FRONTEND_URL") != "http://localhost:3000"
+}
+
+func frontendURL() string {
+	if u := os.Getenv("FRONTEND_URL"); u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	return "http://localhost:3000"
+}
+
+func redirectToFrontend(c *gin.Context, path string, params url.Values) {
+	target := frontendURL() + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+func clearAuthCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "grub_token",
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+	})
+}
+
 func setAuthCookie(c *gin.Context, token string) {
-	isProduction := os.Getenv("FRONTEND_URL") != "" && os.Getenv("FRONTEND_URL") != "http://localhost:3000"
 
 	cookie := &http.Cookie{
 		Name:     "grub_token",
@@ -90,7 +335,7 @@ func setAuthCookie(c *gin.Context, token string) {
 		HttpOnly: true,
 	}
 
-	if isProduction {
+	if isProduction() {
 		// Cross-domain: Vercel frontend → Fly.io backend
 		cookie.Secure = true
 		cookie.SameSite = http.SameSiteNoneMode
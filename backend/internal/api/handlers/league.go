@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LeagueHandler struct {
+	leagueService *services.LeagueService
+}
+
+func NewLeagueHandler(leagueService *services.LeagueService) *LeagueHandler {
+	return &LeagueHandler{leagueService: leagueService}
+}
+
+func (h *LeagueHandler) ListLeagues(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	leagues, err := h.leagueService.ListLeagues(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leagues": leagues})
+}
+
+func (h *LeagueHandler) CreateLeague(c *gin.Context) {
+	var req models.CreateLeagueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	league, err := h.leagueService.CreateLeague(userID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, league)
+}
+
+func (h *LeagueHandler) JoinLeague(c *gin.Context) {
+	var req models.JoinLeagueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	league, err := h.leagueService.JoinLeague(userID, req.InviteCode)
+	if err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, league)
+}
+
+func (h *LeagueHandler) GetLeague(c *gin.Context) {
+	userID, leagueID, ok := leagueParams(c)
+	if !ok {
+		return
+	}
+
+	league, members, err := h.leagueService.GetLeague(userID, leagueID)
+	if err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"league": league, "members": members})
+}
+
+func (h *LeagueHandler) LeaveLeague(c *gin.Context) {
+	userID, leagueID, ok := leagueParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.leagueService.LeaveLeague(userID, leagueID); err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "left league"})
+}
+
+func (h *LeagueHandler) KickMember(c *gin.Context) {
+	userID, leagueID, ok := leagueParams(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.leagueService.KickMember(userID, leagueID, memberID); err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "member removed"})
+}
+
+func (h *LeagueHandler) RotateInviteCode(c *gin.Context) {
+	userID, leagueID, ok := leagueParams(c)
+	if !ok {
+		return
+	}
+
+	code, err := h.leagueService.RotateInviteCode(userID, leagueID)
+	if err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invite_code": code})
+}
+
+func (h *LeagueHandler) GetLeaderboard(c *gin.Context) {
+	userID, leagueID, ok := leagueParams(c)
+	if !ok {
+		return
+	}
+
+	q, ok := parseLeaderboardQuery(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.leagueService.GetLeaderboard(userID, leagueID, q)
+	if err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+func (h *LeagueHandler) GetPosts(c *gin.Context) {
+	userID, leagueID, ok := leagueParams(c)
+	if !ok {
+		return
+	}
+
+	posts, err := h.leagueService.GetPosts(userID, leagueID)
+	if err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"posts": posts})
+}
+
+func (h *LeagueHandler) CreatePost(c *gin.Context) {
+	var req models.CreateLeaguePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, leagueID, ok := leagueParams(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.leagueService.CreatePost(userID, leagueID, &req)
+	if err != nil {
+		respondLeagueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, post)
+}
+
+func leagueParams(c *gin.Context) (userID, leagueID int, ok bool) {
+	userID, ok = getUserID(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	leagueID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid league ID"})
+		return 0, 0, false
+	}
+	return userID, leagueID, true
+}
+
+func respondLeagueError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrLeagueNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotLeagueAdmin), errors.Is(err, services.ErrLeagueForbidden),
+		errors.Is(err, services.ErrBannedFromPosting):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -1,18 +1,25 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
+	"grub-exchange/internal/models"
 	"grub-exchange/internal/services"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type MarketHandler struct {
 	marketService *services.MarketService
+	candleService *services.CandleService
 }
 
-func NewMarketHandler(marketService *services.MarketService) *MarketHandler {
-	return &MarketHandler{marketService: marketService}
+func NewMarketHandler(marketService *services.MarketService, candleService *services.CandleService) *MarketHandler {
+	return &MarketHandler{marketService: marketService, candleService: candleService}
 }
 
 func (h *MarketHandler) GetMarketOverview(c *gin.Context) {
@@ -25,14 +32,34 @@ func (h *MarketHandler) GetMarketOverview(c *gin.Context) {
 	c.JSON(http.StatusOK, overview)
 }
 
+const (
+	defaultStockListLimit = 50
+	maxStockListLimit     = 200
+)
+
+// GetStocks handles GET /api/stocks?sort=&order=&min_price=&max_price=&min_volume=&held=&watchlisted=&limit=&cursor=
 func (h *MarketHandler) GetStocks(c *gin.Context) {
-	stocks, err := h.marketService.GetAllStocks()
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	q, ok := parseStockListQuery(c, userID)
+	if !ok {
+		return
+	}
+
+	page, err := h.marketService.ListStocks(q)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"stocks": stocks})
+	c.JSON(http.StatusOK, page)
 }
 
 func (h *MarketHandler) GetStockDetail(c *gin.Context) {
@@ -51,8 +78,125 @@ func (h *MarketHandler) GetStockDetail(c *gin.Context) {
 	c.JSON(http.StatusOK, detail)
 }
 
+const (
+	defaultHoldersLimit = 50
+	maxHoldersLimit     = 200
+)
+
+func (h *MarketHandler) GetStockHolders(c *gin.Context) {
+	limit := defaultHoldersLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxHoldersLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		offset = n
+	}
+
+	holders, err := h.marketService.GetStockHolders(c.Param("ticker"), limit, offset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "stock not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stock holders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holders)
+}
+
+// GetCandles handles GET /api/stocks/:ticker/candles?interval=&from=&to=. from and to are
+// RFC 3339 times or Unix seconds.
+func (h *MarketHandler) GetCandles(c *gin.Context) {
+	from, ok := queryTime(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := queryTime(c, "to")
+	if !ok {
+		return
+	}
+
+	candles, err := h.candleService.GetCandles(c.Param("ticker"), c.DefaultQuery("interval", "1h"), from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCandleRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "stock not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, candles)
+}
+
+const maxIndicatorWindow = 200
+
+// GetIndicators handles GET /api/stocks/:ticker/indicators?interval=&from=&to= with optional
+// ?sma, ?ema, ?rsi, ?bollinger and ?volatility windows, counted in candles.
+func (h *MarketHandler) GetIndicators(c *gin.Context) {
+	from, ok := queryTime(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := queryTime(c, "to")
+	if !ok {
+		return
+	}
+	windows, ok := parseIndicatorWindows(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.candleService.GetIndicators(c.Param("ticker"), c.DefaultQuery("interval", "1h"), from, to, windows)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCandleRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "stock not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
 func (h *MarketHandler) GetLeaderboard(c *gin.Context) {
-	data, err := h.marketService.GetLeaderboard()
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	q, ok := parseLeaderboardQuery(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.marketService.GetLeaderboard(userID, q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -70,3 +214,188 @@ func (h *MarketHandler) GetRecentTransactions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"transactions": txns})
 }
+
+// parseLeaderboardQuery reads ?window, ?category, ?limit and ?offset, responding with 400
+// and returning false if any is invalid.
+func parseLeaderboardQuery(c *gin.Context) (models.LeaderboardQuery, bool) {
+	q := models.LeaderboardQuery{
+		Window:   c.DefaultQuery("window", "24h"),
+		Category: c.Query("category"),
+		Limit:    defaultLeaderboardLimit,
+	}
+	if !containsString(models.LeaderboardWindows, q.Window) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be one of 1h, 24h, 7d, 30d, all"})
+		return q, false
+	}
+	if q.Category != "" && !containsString(models.LeaderboardCategories, q.Category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown leaderboard category"})
+		return q, false
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return q, false
+		}
+		q.Limit = limit
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return q, false
+		}
+		q.Offset = offset
+	}
+
+	return q, true
+}
+
+// parseIndicatorWindows reads each indicator's window, responding with 400 and returning
+// false if any is invalid.
+func parseIndicatorWindows(c *gin.Context) (models.IndicatorWindows, bool) {
+	w := models.IndicatorWindows{SMA: 20, EMA: 20, RSI: 14, Bollinger: 20, Volatility: 20}
+	for _, p := range []struct {
+		name   string
+		window *int
+	}{
+		{"sma", &w.SMA},
+		{"ema", &w.EMA},
+		{"rsi", &w.RSI},
+		{"bollinger", &w.Bollinger},
+		{"volatility", &w.Volatility},
+	} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 || n > maxIndicatorWindow {
+			c.JSON(http.StatusBadRequest, gin.H{"error": p.name + " must be between 2 and 200"})
+			return w, false
+		}
+		*p.window = n
+	}
+	return w, true
+}
+
+// parseStockListQuery reads the stock list's sort, filters, ?limit and ?cursor, responding
+// with 400 and returning false if any is invalid. ?held and ?watchlisted filter to the
+// requesting user's holdings and watchlist.
+func parseStockListQuery(c *gin.Context, userID int) (models.StockListQuery, bool) {
+	q := models.StockListQuery{
+		Sort:   c.DefaultQuery("sort", models.StockSortPrice),
+		Limit:  defaultStockListLimit,
+		Cursor: c.Query("cursor"),
+	}
+	if !containsString(models.StockSorts, q.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of price, change_24h, volume, market_cap, newest"})
+		return q, false
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		q.Ascending = true
+	case "desc":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return q, false
+	}
+
+	var ok bool
+	if q.MinPrice, ok = queryNonNegativeFloat(c, "min_price"); !ok {
+		return q, false
+	}
+	if q.MaxPrice, ok = queryNonNegativeFloat(c, "max_price"); !ok {
+		return q, false
+	}
+	if q.MinVolume, ok = queryNonNegativeFloat(c, "min_volume"); !ok {
+		return q, false
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_price must not exceed max_price"})
+		return q, false
+	}
+
+	held, ok := queryBool(c, "held")
+	if !ok {
+		return q, false
+	}
+	watchlisted, ok := queryBool(c, "watchlisted")
+	if !ok {
+		return q, false
+	}
+	if held {
+		q.HeldBy = userID
+	}
+	if watchlisted {
+		q.WatchedBy = userID
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxStockListLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return q, false
+		}
+		q.Limit = limit
+	}
+
+	return q, true
+}
+
+// queryNonNegativeFloat reads an optional non-negative number, responding with 400 and
+// returning false if it's invalid.
+func queryNonNegativeFloat(c *gin.Context, name string) (*float64, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a non-negative number"})
+		return nil, false
+	}
+	return &n, true
+}
+
+// queryBool reads an optional true/false flag, responding with 400 and returning false if
+// it's invalid.
+func queryBool(c *gin.Context, name string) (bool, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return false, true
+	}
+	on, err := strconv.ParseBool(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be true or false"})
+		return false, false
+	}
+	return on, true
+}
+
+// queryTime reads an optional time given as RFC 3339 or Unix seconds, responding with 400
+// and returning false if it's invalid.
+func queryTime(c *gin.Context, name string) (time.Time, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return time.Time{}, true
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be an RFC 3339 time or Unix seconds"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
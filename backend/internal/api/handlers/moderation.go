@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultModerationLogLimit = 50
+	maxModerationLogLimit     = 500
+)
+
+// ModerationHandler serves the admin moderation endpoints. Users report posts through
+// PostHandler.ReportPost.
+type ModerationHandler struct {
+	moderationSvc *services.ModerationService
+	sentimentSvc  *services.SentimentService
+}
+
+func NewModerationHandler(moderationSvc *services.ModerationService, sentimentSvc *services.SentimentService) *ModerationHandler {
+	return &ModerationHandler{moderationSvc: moderationSvc, sentimentSvc: sentimentSvc}
+}
+
+func (h *ModerationHandler) GetQueue(c *gin.Context) {
+	queue, err := h.moderationSvc.GetQueue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": queue})
+}
+
+func (h *ModerationHandler) HidePost(c *gin.Context) {
+	h.postAction(c, h.moderationSvc.HidePost, "post hidden")
+}
+
+func (h *ModerationHandler) RestorePost(c *gin.Context) {
+	h.postAction(c, h.moderationSvc.RestorePost, "post restored")
+}
+
+func (h *ModerationHandler) DismissReports(c *gin.Context) {
+	h.postAction(c, h.moderationSvc.DismissReports, "reports dismissed")
+}
+
+func (h *ModerationHandler) BanUser(c *gin.Context) {
+	h.setBanned(c, true, "user banned")
+}
+
+func (h *ModerationHandler) UnbanUser(c *gin.Context) {
+	h.setBanned(c, false, "user unbanned")
+}
+
+func (h *ModerationHandler) GetLog(c *gin.Context) {
+	limit := defaultModerationLogLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxModerationLogLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.moderationSvc.GetLog(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"log": entries})
+}
+
+func (h *ModerationHandler) GetBannedWords(c *gin.Context) {
+	words, err := h.moderationSvc.GetBannedWords()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"words": words})
+}
+
+func (h *ModerationHandler) AddBannedWord(c *gin.Context) {
+	var req models.AddBannedWordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	adminID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.moderationSvc.AddBannedWord(adminID, req.Word); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "word blocked"})
+}
+
+func (h *ModerationHandler) RemoveBannedWord(c *gin.Context) {
+	adminID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.moderationSvc.RemoveBannedWord(adminID, c.Param("word")); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "word unblocked"})
+}
+
+// GetSentimentReport shows how each stock's sentiment was computed and which votes were
+// discounted. ?ticker narrows it to one stock.
+func (h *ModerationHandler) GetSentimentReport(c *gin.Context) {
+	report, err := h.sentimentSvc.GetReport(c.Query("ticker"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *ModerationHandler) postAction(c *gin.Context, action func(adminID, postID int, note string) error, message string) {
+	var req models.ModerationNoteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	adminID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post ID"})
+		return
+	}
+
+	if err := action(adminID, postID, req.Note); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func (h *ModerationHandler) setBanned(c *gin.Context, banned bool, message string) {
+	var req models.ModerationNoteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	adminID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.moderationSvc.SetBanned(adminID, c.Param("username"), banned, req.Note); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// moderationErrorStatus maps the moderation service's expected errors to a response status.
+var moderationErrorStatus = map[error]int{
+	services.ErrBannedFromPosting:    http.StatusForbidden,
+	services.ErrBlockedContent:       http.StatusBadRequest,
+	services.ErrReportDetailsTooLong: http.StatusBadRequest,
+	services.ErrReportOwnPost:        http.StatusBadRequest,
+	services.ErrAlreadyReported:      http.StatusConflict,
+	services.ErrPostNotHideable:      http.StatusConflict,
+	services.ErrPostNotHidden:        http.StatusConflict,
+	services.ErrBanSelf:              http.StatusBadRequest,
+	services.ErrAlreadyBanned:        http.StatusConflict,
+	services.ErrNotBanned:            http.StatusConflict,
+	services.ErrUserNotFound:         http.StatusNotFound,
+	services.ErrInvalidBannedWord:    http.StatusBadRequest,
+	services.ErrWordAlreadyBlocked:   http.StatusConflict,
+	services.ErrWordNotBlocked:       http.StatusNotFound,
+}
+
+// respondModerationError reports an expected moderation error with its own message and
+// anything else as a generic 500, so database errors never reach the client.
+func respondModerationError(c *gin.Context, err error) {
+	for target, status := range moderationErrorStatus {
+		if errors.Is(err, target) {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Moderation request failed"})
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"fmt"
 	"grub-exchange/internal/models"
 	"grub-exchange/internal/repository"
+	"grub-exchange/internal/services"
 	"net/http"
 	"strconv"
 	"strings"
@@ -11,12 +13,33 @@ import (
 )
 
 type PostHandler struct {
-	postRepo *repository.PostRepo
-	userRepo *repository.UserRepo
+	postRepo      *repository.PostRepo
+	userRepo      *repository.UserRepo
+	notifRepo     *repository.NotificationRepo
+	achieveSvc    *services.AchievementService
+	leagueService *services.LeagueService
+	moderationSvc *services.ModerationService
+	entitySvc     *services.PostEntityService
 }
 
-func NewPostHandler(postRepo *repository.PostRepo, userRepo *repository.UserRepo) *PostHandler {
-	return &PostHandler{postRepo: postRepo, userRepo: userRepo}
+func NewPostHandler(
+	postRepo *repository.PostRepo,
+	userRepo *repository.UserRepo,
+	notifRepo *repository.NotificationRepo,
+	achieveSvc *services.AchievementService,
+	leagueService *services.LeagueService,
+	moderationSvc *services.ModerationService,
+	entitySvc *services.PostEntityService,
+) *PostHandler {
+	return &PostHandler{
+		postRepo:      postRepo,
+		userRepo:      userRepo,
+		notifRepo:     notifRepo,
+		achieveSvc:    achieveSvc,
+		leagueService: leagueService,
+		moderationSvc: moderationSvc,
+		entitySvc:     entitySvc,
+	}
 }
 
 func (h *PostHandler) CreatePost(c *gin.Context) {
@@ -45,19 +68,25 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Content must be 1-500 characters"})
 		return
 	}
+	if !h.checkCanPost(c, userID, content) {
+		return
+	}
 
-	post, err := h.postRepo.Create(userID, stockUser.ID, content)
+	post, err := h.postRepo.Create(userID, stockUser.ID, nil, content, h.entitySvc.Resolve(content))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
 
 	// Fill in the author username
+	post.StockTicker = stockUser.Ticker
 	author, _ := h.userRepo.GetByID(userID)
 	if author != nil {
 		post.AuthorUsername = author.Username
+		h.entitySvc.NotifyMentions(post, author.Username, nil)
 	}
-	post.StockTicker = stockUser.Ticker
+
+	h.achieveSvc.RecordEvent(userID, models.EventPost, 1)
 
 	c.JSON(http.StatusCreated, post)
 }
@@ -114,15 +143,356 @@ func (h *PostHandler) VotePost(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "vote_type must be 1 (like) or -1 (dislike)"})
 		return
 	}
+	if err := h.moderationSvc.CheckNotBanned(userID); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	// League posts can only be voted on by league members
+	post, ok := h.getVisiblePost(c, userID, postID)
+	if !ok {
+		return
+	}
+	if post.Deleted || post.Hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if post.StockUserID == userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't vote on posts about your own stock"})
+		return
+	}
 
-	if err := h.postRepo.Vote(postID, userID, req.VoteType); err != nil {
+	newVote, err := h.postRepo.Vote(postID, userID, req.VoteType)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to vote"})
 		return
 	}
+	if newVote {
+		h.achieveSvc.RecordEvent(userID, models.EventVote, 1)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Vote recorded"})
 }
 
+func (h *PostHandler) CreateReply(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" || len(content) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content must be 1-500 characters"})
+		return
+	}
+	if !h.checkCanPost(c, userID, content) {
+		return
+	}
+
+	parent, ok := h.getVisiblePost(c, userID, postID)
+	if !ok {
+		return
+	}
+	if parent.Deleted || parent.Hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+	if parent.Depth >= models.MaxReplyDepth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This thread can't be nested any deeper"})
+		return
+	}
+
+	reply, err := h.postRepo.CreateReply(userID, parent, content, h.entitySvc.Resolve(content))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reply"})
+		return
+	}
+
+	author, _ := h.userRepo.GetByID(userID)
+	if author != nil {
+		reply.AuthorUsername = author.Username
+		if parent.AuthorID != userID && h.notifRepo != nil {
+			msg := fmt.Sprintf("%s replied to your post on %s", author.Username, parent.StockTicker)
+			_ = h.notifRepo.Create(parent.AuthorID, "post_reply", msg, author.Username, parent.StockTicker, 0)
+		}
+		// The parent's author already heard about the reply
+		h.entitySvc.NotifyMentions(reply, author.Username, map[int]bool{parent.AuthorID: true})
+	}
+
+	h.achieveSvc.RecordEvent(userID, models.EventPost, 1)
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+func (h *PostHandler) EditPost(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" || len(content) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content must be 1-500 characters"})
+		return
+	}
+	if !h.checkCanPost(c, userID, content) {
+		return
+	}
+
+	post, ok := h.getOwnPost(c, userID, postID)
+	if !ok {
+		return
+	}
+	if post.Hidden {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This post was hidden by moderators"})
+		return
+	}
+	if content == post.Content {
+		c.JSON(http.StatusOK, post)
+		return
+	}
+
+	previous := services.MentionedUsers(post.Entities)
+	edited, err := h.postRepo.Edit(postID, content, h.entitySvc.Resolve(content))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit post"})
+		return
+	}
+	if !edited {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	post, err = h.postRepo.GetByID(postID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit post"})
+		return
+	}
+	// Only users newly mentioned by the edit are notified
+	h.entitySvc.NotifyMentions(post, post.AuthorUsername, previous)
+
+	c.JSON(http.StatusOK, post)
+}
+
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	if _, ok := h.getOwnPost(c, userID, postID); !ok {
+		return
+	}
+
+	deleted, err := h.postRepo.Delete(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post deleted"})
+}
+
+// GetRevisions returns the earlier versions of an edited post.
+func (h *PostHandler) GetRevisions(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	post, ok := h.getVisiblePost(c, userID, postID)
+	if !ok {
+		return
+	}
+	if post.Deleted || post.Hidden {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	revisions, err := h.postRepo.GetRevisions(postID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revisions"})
+		return
+	}
+	if revisions == nil {
+		revisions = []models.PostRevision{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"post": post, "revisions": revisions})
+}
+
+func (h *PostHandler) ReportPost(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	var req models.ReportPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if !containsString(models.ReportReasons, req.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be one of spam, abuse, manipulation, other"})
+		return
+	}
+
+	post, ok := h.getVisiblePost(c, userID, postID)
+	if !ok {
+		return
+	}
+	if post.Deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return
+	}
+
+	if err := h.moderationSvc.ReportPost(userID, post, &req); err != nil {
+		respondModerationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report submitted"})
+}
+
+const maxThreadReplies = 500
+
+// GetThread returns a post with its replies nested under it.
+func (h *PostHandler) GetThread(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	if _, ok := h.getVisiblePost(c, userID, postID); !ok {
+		return
+	}
+
+	posts, err := h.postRepo.GetThread(postID, userID, maxThreadReplies)
+	if err != nil || len(posts) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get thread"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"post": nestReplies(posts)})
+}
+
+// getVisiblePost loads a post the user is allowed to see, responding with 404 if there's
+// no such post or it belongs to a league they aren't in.
+func (h *PostHandler) getVisiblePost(c *gin.Context, userID, postID int) (*models.StockPost, bool) {
+	post, err := h.postRepo.GetByID(postID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return nil, false
+	}
+	if visible, err := h.leagueService.CanSeePost(userID, post.LeagueID); err != nil || !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return nil, false
+	}
+	return post, true
+}
+
+// checkCanPost applies bans and the word filter to new content, responding with an error
+// and returning false if it's rejected.
+func (h *PostHandler) checkCanPost(c *gin.Context, userID int, content string) bool {
+	if err := h.moderationSvc.CheckCanPost(userID, content); err != nil {
+		respondModerationError(c, err)
+		return false
+	}
+	return true
+}
+
+// getOwnPost loads one of the user's own posts, responding with 404 if there's no such post
+// or it's deleted, and 403 if someone else wrote it.
+func (h *PostHandler) getOwnPost(c *gin.Context, userID, postID int) (*models.StockPost, bool) {
+	post, err := h.postRepo.GetByID(postID, userID)
+	if err != nil || post.Deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return nil, false
+	}
+	if post.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only change your own posts"})
+		return nil, false
+	}
+	return post, true
+}
+
+// nestReplies builds a thread from its posts, which must start with the thread's root.
+// Replies may come in any order after it; siblings keep their relative order.
+func nestReplies(posts []models.StockPost) models.StockPost {
+	children := make(map[int][]int)
+	for i, p := range posts[1:] {
+		if p.ParentID != nil {
+			children[*p.ParentID] = append(children[*p.ParentID], i+1)
+		}
+	}
+
+	var build func(i int) models.StockPost
+	build = func(i int) models.StockPost {
+		post := posts[i]
+		for _, child := range children[post.ID] {
+			post.Replies = append(post.Replies, build(child))
+		}
+		return post
+	}
+	return build(0)
+}
+
 func (h *PostHandler) GetRecentPosts(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
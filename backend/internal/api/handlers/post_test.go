@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"grub-exchange/internal/models"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// threadShape writes a thread as nested IDs, e.g. 1(2(3),4).
+func threadShape(p models.StockPost) string {
+	if len(p.Replies) == 0 {
+		return strconv.Itoa(p.ID)
+	}
+	replies := make([]string, len(p.Replies))
+	for i, r := range p.Replies {
+		replies[i] = threadShape(r)
+	}
+	return strconv.Itoa(p.ID) + "(" + strings.Join(replies, ",") + ")"
+}
+
+func TestNestReplies(t *testing.T) {
+	post := func(id, parentID int) models.StockPost {
+		p := models.StockPost{ID: id}
+		if parentID != 0 {
+			p.ParentID = &parentID
+		}
+		return p
+	}
+
+	tests := []struct {
+		name  string
+		posts []models.StockPost
+		want  string
+	}{
+		{"root alone", []models.StockPost{post(1, 0)}, "1"},
+		{"replies keep their order", []models.StockPost{post(1, 0), post(2, 1), post(3, 1), post(4, 2)}, "1(2(4),3)"},
+		{"a child listed before its parent", []models.StockPost{post(1, 0), post(4, 2), post(2, 1), post(3, 1)}, "1(2(4),3)"},
+		{"a subtree rooted at a reply", []models.StockPost{post(2, 1), post(3, 2), post(5, 3), post(4, 2)}, "2(3(5),4)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := threadShape(nestReplies(tt.posts)); got != tt.want {
+				t.Errorf("nestReplies() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
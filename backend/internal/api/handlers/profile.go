@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"grub-exchange/internal/models"
 	"grub-exchange/internal/repository"
 	"grub-exchange/internal/services"
@@ -11,12 +12,30 @@ import (
 )
 
 type ProfileHandler struct {
-	authService *services.AuthService
-	userRepo    *repository.UserRepo
+	authService    *services.AuthService
+	tickerService  *services.TickerService
+	accountService *services.AccountService
+	privacyService *services.PrivacyService
+	profileService *services.ProfileService
+	userRepo       *repository.UserRepo
 }
 
-func NewProfileHandler(authService *services.AuthService, userRepo *repository.UserRepo) *ProfileHandler {
-	return &ProfileHandler{authService: authService, userRepo: userRepo}
+func NewProfileHandler(
+	authService *services.AuthService,
+	tickerService *services.TickerService,
+	accountService *services.AccountService,
+	privacyService *services.PrivacyService,
+	profileService *services.ProfileService,
+	userRepo *repository.UserRepo,
+) *ProfileHandler {
+	return &ProfileHandler{
+		authService:    authService,
+		tickerService:  tickerService,
+		accountService: accountService,
+		privacyService: privacyService,
+		profileService: profileService,
+		userRepo:       userRepo,
+	}
 }
 
 func (h *ProfileHandler) GetProfile(c *gin.Context) {
@@ -31,7 +50,13 @@ func (h *ProfileHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	privacy, err := h.privacyService.GetSettings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "privacy": privacy})
 }
 
 func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
@@ -60,6 +85,74 @@ func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
 
+func (h *ProfileHandler) ChangeTicker(c *gin.Context) {
+	var req models.ChangeTickerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.tickerService.ChangeTicker(userID, req.Ticker); err != nil {
+		var taken *services.TickerTakenError
+		if errors.As(err, &taken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "suggestions": taken.Suggestions})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authService.GetMe(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+func (h *ProfileHandler) GetClosureQuote(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	quote, err := h.accountService.QuoteClosure(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+func (h *ProfileHandler) CloseAccount(c *gin.Context) {
+	var req models.CloseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.accountService.CloseAccount(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clearAuthCookie(c)
+	c.JSON(http.StatusOK, gin.H{"message": "account closed", "closure": result})
+}
+
 func (h *ProfileHandler) GetPortfolioGraph(c *gin.Context) {
 	userID, ok := getUserID(c)
 	if !ok {
@@ -75,3 +168,59 @@ func (h *ProfileHandler) GetPortfolioGraph(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"snapshots": snapshots})
 }
+
+func (h *ProfileHandler) GetPrivacy(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.privacyService.GetSettings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *ProfileHandler) UpdatePrivacy(c *gin.Context) {
+	var req models.UpdatePrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
+		return
+	}
+
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.privacyService.UpdateSettings(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetPublicProfile handles GET /api/users/:username
+func (h *ProfileHandler) GetPublicProfile(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.profileService.GetPublicProfile(userID, c.Param("username"))
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"grub-exchange/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionHandler struct {
+	retentionService *services.RetentionService
+}
+
+func NewRetentionHandler(retentionService *services.RetentionService) *RetentionHandler {
+	return &RetentionHandler{retentionService: retentionService}
+}
+
+// GetReport shows the price history retention policy and how many rows it has reclaimed.
+func (h *RetentionHandler) GetReport(c *gin.Context) {
+	report, err := h.retentionService.GetReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"grub-exchange/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSeasonLeaderboardLimit = 100
+	maxSeasonLeaderboardLimit     = 500
+)
+
+type SeasonHandler struct {
+	seasonService *services.SeasonService
+}
+
+func NewSeasonHandler(seasonService *services.SeasonService) *SeasonHandler {
+	return &SeasonHandler{seasonService: seasonService}
+}
+
+func (h *SeasonHandler) ListSeasons(c *gin.Context) {
+	seasons, err := h.seasonService.ListSeasons()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": seasons})
+}
+
+func (h *SeasonHandler) GetCurrentSeason(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	board, err := h.seasonService.GetCurrentLeaderboard(userID, seasonLeaderboardLimit(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+func (h *SeasonHandler) GetSeasonLeaderboard(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	seasonID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid season ID"})
+		return
+	}
+
+	board, err := h.seasonService.GetLeaderboard(seasonID, userID, seasonLeaderboardLimit(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+func (h *SeasonHandler) GetMyBadges(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	badges, err := h.seasonService.GetBadges(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"badges": badges})
+}
+
+func seasonLeaderboardLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return defaultSeasonLeaderboardLimit
+	}
+	if limit > maxSeasonLeaderboardLimit {
+		return maxSeasonLeaderboardLimit
+	}
+	return limit
+}
@@ -33,15 +33,16 @@ func (h *TradingHandler) Buy(c *gin.Context) {
 		return
 	}
 
-	txn, err := h.tradingService.ExecuteBuy(userID, req.StockTicker, req.NumShares, req.GrubAmount)
+	txn, unlocked, err := h.tradingService.ExecuteBuy(userID, req.StockTicker, req.NumShares, req.GrubAmount)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "buy order executed",
-		"transaction": txn,
+		"message":      "buy order executed",
+		"transaction":  txn,
+		"achievements": unlocked,
 	})
 }
 
@@ -62,14 +63,15 @@ func (h *TradingHandler) Sell(c *gin.Context) {
 		return
 	}
 
-	txn, err := h.tradingService.ExecuteSell(userID, req.StockTicker, req.NumShares, req.GrubAmount)
+	txn, unlocked, err := h.tradingService.ExecuteSell(userID, req.StockTicker, req.NumShares, req.GrubAmount)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "sell order executed",
-		"transaction": txn,
+		"message":      "sell order executed",
+		"transaction":  txn,
+		"achievements": unlocked,
 	})
 }
@@ -1,15 +1,53 @@
 package middleware
 
 import (
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
 	"grub-exchange/internal/utils"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthRequired() gin.HandlerFunc {
+// apiKeyRouteScopes lists the non-GET routes an API key may call and the scope each needs.
+// GET routes need the read scope unless listed in sessionOnlyReads; any other route is
+// session-only (e.g. profile changes, key management, 2FA), so a leaked key can't escalate
+// its own access.
+var apiKeyRouteScopes = map[string]string{
+	"POST /api/trade/buy":            models.ScopeTrade,
+	"POST /api/trade/sell":           models.ScopeTrade,
+	"POST /api/stocks/:ticker/posts": models.ScopePost,
+	"POST /api/posts/:id/vote":       models.ScopePost,
+	"POST /api/posts/:id/replies":    models.ScopePost,
+	"PUT /api/posts/:id":             models.ScopePost,
+	"DELETE /api/posts/:id":          models.ScopePost,
+	"POST /api/posts/:id/report":     models.ScopePost,
+	"POST /api/leagues/:id/posts":    models.ScopePost,
+}
+
+// sessionOnlyReads lists the GET routes an API key may not call, since they describe the
+// account's own credentials or closing it.
+var sessionOnlyReads = map[string]bool{
+	"/api/keys":                  true,
+	"/api/auth/2fa":              true,
+	"/api/profile/closure-quote": true,
+}
+
+func AuthRequired(userRepo *repository.UserRepo, apiKeyRepo *repository.APIKeyRepo) gin.HandlerFunc {
+	limiter := newRateLimiter()
+
 	return func(c *gin.Context) {
+		// API keys take precedence so scripts don't accidentally act with a browser session
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			authenticateAPIKey(c, apiKeyRepo, limiter, apiKey)
+			return
+		}
+
 		var tokenString string
 
 		// 1. Try cookie first
@@ -36,8 +74,92 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// Sessions outlive account closure otherwise (JWTs are valid for 7 days)
+		active, err := userRepo.IsActive(claims.UserID)
+		if err != nil || !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			c.Abort()
+			return
+		}
+
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Next()
 	}
 }
+
+func authenticateAPIKey(c *gin.Context, apiKeyRepo *repository.APIKeyRepo, limiter *rateLimiter, apiKey string) {
+	owner, err := apiKeyRepo.GetOwnerByHash(utils.HashAPIKey(apiKey))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or revoked API key"})
+		c.Abort()
+		return
+	}
+
+	required := models.ScopeRead
+	if c.Request.Method == http.MethodGet {
+		if sessionOnlyReads[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a signed-in session"})
+			c.Abort()
+			return
+		}
+	} else {
+		scope, ok := apiKeyRouteScopes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a signed-in session"})
+			c.Abort()
+			return
+		}
+		required = scope
+	}
+	if !hasScope(owner.Scopes, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key is missing the %q scope", required)})
+		c.Abort()
+		return
+	}
+
+	if ok, retryAfter := limiter.Allow(strconv.Itoa(owner.KeyID), owner.RateLimitPerMinute); !ok {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "API key rate limit exceeded"})
+		c.Abort()
+		return
+	}
+
+	if err := apiKeyRepo.TouchLastUsed(owner.KeyID); err != nil {
+		log.Printf("Error updating last_used_at for API key %d: %v", owner.KeyID, err)
+	}
+
+	c.Set("userID", owner.UserID)
+	c.Set("username", owner.Username)
+	c.Set("apiKeyID", owner.KeyID)
+	c.Next()
+}
+
+func hasScope(scopes []string, want string) bool {
+	for _, s := range scopes {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminRequired restricts a route group to admins. It must run after AuthRequired, and
+// only accepts signed-in sessions: admin actions can't be taken with an API key.
+func AdminRequired(userRepo *repository.UserRepo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, viaKey := c.Get("apiKeyID"); viaKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a signed-in session"})
+			c.Abort()
+			return
+		}
+
+		isAdmin, err := userRepo.IsAdmin(c.GetInt("userID"))
+		if err != nil || !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
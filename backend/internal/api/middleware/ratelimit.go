@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authAttemptsPerMinute limits sign-in attempts per client IP, so passwords and 2FA codes
+// can't be guessed quickly.
+const authAttemptsPerMinute = 10
+
+// rateLimiter enforces a requests-per-minute limit per key (an API key ID or a client IP)
+// using fixed one-minute windows. State is in memory, which is fine for the single backend
+// instance we run.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{windows: make(map[string]*rateWindow)}
+}
+
+// Allow records a request for key and reports whether it is within limit.
+// When rejected, it also returns how long until the window resets.
+func (l *rateLimiter) Allow(key string, limit int) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+		l.sweep(now)
+	}
+
+	if w.count >= limit {
+		return false, time.Minute - now.Sub(w.start)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep drops expired windows so keys that stop being used don't accumulate.
+func (l *rateLimiter) sweep(now time.Time) {
+	if len(l.windows) < 1000 {
+		return
+	}
+	for id, w := range l.windows {
+		if now.Sub(w.start) >= time.Minute {
+			delete(l.windows, id)
+		}
+	}
+}
+
+// AuthRateLimit limits the routes it guards to authAttemptsPerMinute requests per client IP,
+// shared across all of them.
+func AuthRateLimit() gin.HandlerFunc {
+	limiter := newRateLimiter()
+
+	return func(c *gin.Context) {
+		if ok, retryAfter := limiter.Allow(c.ClientIP(), authAttemptsPerMinute); !ok {
+			c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many sign-in attempts, try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"grub-exchange/internal/api/handlers"
 	"grub-exchange/internal/api/middleware"
+	"grub-exchange/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +17,16 @@ func SetupRouter(
 	notifHandler *handlers.NotificationHandler,
 	achieveHandler *handlers.AchievementHandler,
 	postHandler *handlers.PostHandler,
+	apiKeyHandler *handlers.APIKeyHandler,
+	seasonHandler *handlers.SeasonHandler,
+	leagueHandler *handlers.LeagueHandler,
+	alertHandler *handlers.AlertHandler,
+	feedHandler *handlers.FeedHandler,
+	moderationHandler *handlers.ModerationHandler,
+	searchHandler *handlers.SearchHandler,
+	retentionHandler *handlers.RetentionHandler,
+	userRepo *repository.UserRepo,
+	apiKeyRepo *repository.APIKeyRepo,
 ) *gin.Engine {
 	r := gin.Default()
 
@@ -26,17 +37,33 @@ func SetupRouter(
 		// Public routes
 		auth := api.Group("/auth")
 		{
+			authLimit := middleware.AuthRateLimit()
 			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			auth.POST("/login", authLimit, authHandler.Login)
+			auth.POST("/login/2fa", authLimit, authHandler.LoginTwoFactor)
 			auth.POST("/logout", authHandler.Logout)
+			auth.GET("/ticker-check", authHandler.CheckTicker)
+			auth.GET("/oidc/login", authHandler.OIDCLogin)
+			auth.GET("/oidc/callback", authHandler.OIDCCallback)
 		}
 
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthRequired())
+		protected.Use(middleware.AuthRequired(userRepo, apiKeyRepo))
 		{
 			protected.GET("/auth/me", authHandler.GetMe)
 
+			// Two-factor authentication
+			protected.GET("/auth/2fa", authHandler.GetTwoFactorStatus)
+			protected.POST("/auth/2fa/enroll", authHandler.EnrollTwoFactor)
+			protected.POST("/auth/2fa/verify", authHandler.VerifyTwoFactor)
+			protected.POST("/auth/2fa/disable", authHandler.DisableTwoFactor)
+
+			// API keys
+			protected.GET("/keys", apiKeyHandler.ListKeys)
+			protected.POST("/keys", apiKeyHandler.CreateKey)
+			protected.DELETE("/keys/:id", apiKeyHandler.RevokeKey)
+
 			// Trading
 			protected.POST("/trade/buy", tradingHandler.Buy)
 			protected.POST("/trade/sell", tradingHandler.Sell)
@@ -50,11 +77,19 @@ func SetupRouter(
 			// Profile
 			protected.GET("/profile", profileHandler.GetProfile)
 			protected.PUT("/profile", profileHandler.UpdateProfile)
+			protected.PUT("/profile/ticker", profileHandler.ChangeTicker)
+			protected.GET("/profile/closure-quote", profileHandler.GetClosureQuote)
+			protected.DELETE("/profile", profileHandler.CloseAccount)
+			protected.GET("/profile/privacy", profileHandler.GetPrivacy)
+			protected.PUT("/profile/privacy", profileHandler.UpdatePrivacy)
 
 			// Market
 			protected.GET("/market/overview", marketHandler.GetMarketOverview)
 			protected.GET("/stocks", marketHandler.GetStocks)
 			protected.GET("/stocks/:ticker", marketHandler.GetStockDetail)
+			protected.GET("/stocks/:ticker/holders", marketHandler.GetStockHolders)
+			protected.GET("/stocks/:ticker/candles", marketHandler.GetCandles)
+			protected.GET("/stocks/:ticker/indicators", marketHandler.GetIndicators)
 			protected.GET("/leaderboard", marketHandler.GetLeaderboard)
 			protected.GET("/transactions", marketHandler.GetRecentTransactions)
 
@@ -65,11 +100,73 @@ func SetupRouter(
 			// Achievements
 			protected.GET("/achievements", achieveHandler.GetMyAchievements)
 
+			// Seasons
+			protected.GET("/seasons", seasonHandler.ListSeasons)
+			protected.GET("/seasons/current", seasonHandler.GetCurrentSeason)
+			protected.GET("/seasons/badges", seasonHandler.GetMyBadges)
+			protected.GET("/seasons/:id/leaderboard", seasonHandler.GetSeasonLeaderboard)
+
+			// Leagues
+			protected.GET("/leagues", leagueHandler.ListLeagues)
+			protected.POST("/leagues", leagueHandler.CreateLeague)
+			protected.POST("/leagues/join", leagueHandler.JoinLeague)
+			protected.GET("/leagues/:id", leagueHandler.GetLeague)
+			protected.POST("/leagues/:id/leave", leagueHandler.LeaveLeague)
+			protected.POST("/leagues/:id/invite-code", leagueHandler.RotateInviteCode)
+			protected.DELETE("/leagues/:id/members/:userId", leagueHandler.KickMember)
+			protected.GET("/leagues/:id/leaderboard", leagueHandler.GetLeaderboard)
+			protected.GET("/leagues/:id/posts", leagueHandler.GetPosts)
+			protected.POST("/leagues/:id/posts", leagueHandler.CreatePost)
+
+			// Watchlist and price alerts
+			protected.GET("/watchlist", alertHandler.GetWatchlist)
+			protected.POST("/watchlist", alertHandler.AddToWatchlist)
+			protected.DELETE("/watchlist/:ticker", alertHandler.RemoveFromWatchlist)
+			protected.GET("/alerts", alertHandler.ListAlerts)
+			protected.POST("/alerts", alertHandler.CreateAlert)
+			protected.PUT("/alerts/:id", alertHandler.UpdateAlert)
+			protected.DELETE("/alerts/:id", alertHandler.DeleteAlert)
+
+			// Follows and activity feed
+			protected.GET("/feed", feedHandler.GetFeed)
+			protected.GET("/following", feedHandler.GetFollowing)
+			protected.GET("/followers", feedHandler.GetFollowers)
+			protected.GET("/users/:username", profileHandler.GetPublicProfile)
+			protected.POST("/users/:username/follow", feedHandler.Follow)
+			protected.DELETE("/users/:username/follow", feedHandler.Unfollow)
+
+			// Search across traders and posts
+			protected.GET("/search", searchHandler.Search)
+
 			// News / Posts
 			protected.GET("/posts/recent", postHandler.GetRecentPosts)
 			protected.GET("/stocks/:ticker/posts", postHandler.GetPosts)
 			protected.POST("/stocks/:ticker/posts", postHandler.CreatePost)
 			protected.POST("/posts/:id/vote", postHandler.VotePost)
+			protected.POST("/posts/:id/replies", postHandler.CreateReply)
+			protected.GET("/posts/:id/thread", postHandler.GetThread)
+			protected.PUT("/posts/:id", postHandler.EditPost)
+			protected.DELETE("/posts/:id", postHandler.DeletePost)
+			protected.GET("/posts/:id/revisions", postHandler.GetRevisions)
+			protected.POST("/posts/:id/report", postHandler.ReportPost)
+
+			// Moderation (admins only)
+			admin := protected.Group("/admin")
+			admin.Use(middleware.AdminRequired(userRepo))
+			{
+				admin.GET("/reports", moderationHandler.GetQueue)
+				admin.POST("/posts/:id/hide", moderationHandler.HidePost)
+				admin.POST("/posts/:id/restore", moderationHandler.RestorePost)
+				admin.POST("/posts/:id/dismiss", moderationHandler.DismissReports)
+				admin.POST("/users/:username/ban", moderationHandler.BanUser)
+				admin.POST("/users/:username/unban", moderationHandler.UnbanUser)
+				admin.GET("/moderation-log", moderationHandler.GetLog)
+				admin.GET("/sentiment", moderationHandler.GetSentimentReport)
+				admin.GET("/retention", retentionHandler.GetReport)
+				admin.GET("/banned-words", moderationHandler.GetBannedWords)
+				admin.POST("/banned-words", moderationHandler.AddBannedWord)
+				admin.DELETE("/banned-words/:word", moderationHandler.RemoveBannedWord)
+			}
 		}
 	}
 
@@ -22,19 +22,39 @@ func RunMigrations(db *sql.DB) error {
 		 VALUES ('MARKET', 'market@system', '', 'MARKET', 'Automated market maker', 0, 0)
 		 ON CONFLICT (username) DO NOTHING`)
 
-	// Seed achievement definitions
+	// Seed achievement definitions. These rows are the source of truth for the achievement
+	// engine, so existing rows are updated to pick up changed rules.
 	achievements := []struct {
 		ID, Name, Description, Icon string
+		EventType, Metric           string
+		Threshold                   float64
+		WindowHours                 int
+		Reward                      float64
 	}{
-		{"first_trade", "First Trade", "Execute your first buy or sell trade", "🎯"},
-		{"diamond_hands", "Diamond Hands", "Hold a stock for 30+ days", "💎"},
-		{"day_trader", "Day Trader", "Execute 10 trades in a single day", "⚡"},
-		{"whale", "Whale", "Portfolio worth 10,000+ Grub", "🐋"},
+		{"first_trade", "First Trade", "Execute your first buy or sell trade", "🎯", "trade", "event_count", 1, 0, 10},
+		{"day_trader", "Day Trader", "Execute 10 trades in 24 hours", "⚡", "trade", "event_count", 10, 24, 50},
+		{"centurion", "Centurion", "Execute 100 trades", "💯", "trade", "event_count", 100, 0, 100},
+		{"high_roller", "High Roller", "Trade 10,000 Grub in total", "🎰", "trade", "event_sum", 10000, 0, 100},
+		{"whale", "Whale", "Portfolio worth 10,000+ Grub", "🐋", "", "portfolio_value", 10000, 0, 250},
+		{"leviathan", "Leviathan", "Portfolio worth 50,000+ Grub", "🦑", "", "portfolio_value", 50000, 0, 1000},
+		{"diamond_hands", "Diamond Hands", "Hold a stock for 30+ days", "💎", "", "holding_days", 30, 0, 100},
+		{"diversified", "Diversified", "Hold 10 different stocks at once", "🧺", "", "distinct_holdings", 10, 0, 50},
+		{"first_post", "Breaking News", "Write your first post", "📰", "post", "event_count", 1, 0, 10},
+		{"town_crier", "Town Crier", "Write 50 posts", "📣", "post", "event_count", 50, 0, 75},
+		{"first_vote", "Civic Duty", "Vote on a post", "🗳️", "vote", "event_count", 1, 0, 5},
+		{"critic", "Critic", "Vote on 100 posts", "🧐", "vote", "event_count", 100, 0, 50},
+		{"regular", "Regular", "Claim your daily bonus 7 times in a week", "📅", "daily_claim", "event_count", 7, 168, 50},
+		{"loyalist", "Loyalist", "Claim your daily bonus 30 times", "🏅", "daily_claim", "event_count", 30, 0, 150},
+		{"passive_income", "Passive Income", "Earn 1,000 Grub in dividends", "🌱", "dividend", "event_sum", 1000, 0, 100},
 	}
 	for _, a := range achievements {
 		_, _ = db.Exec(
-			`INSERT INTO achievements (id, name, description, icon) VALUES ($1, $2, $3, $4) ON CONFLICT (id) DO NOTHING`,
-			a.ID, a.Name, a.Description, a.Icon,
+			`INSERT INTO achievements (id, name, description, icon, event_type, metric, threshold, window_hours, reward)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description,
+			     icon = EXCLUDED.icon, event_type = EXCLUDED.event_type, metric = EXCLUDED.metric,
+			     threshold = EXCLUDED.threshold, window_hours = EXCLUDED.window_hours, reward = EXCLUDED.reward`,
+			a.ID, a.Name, a.Description, a.Icon, a.EventType, a.Metric, a.Threshold, a.WindowHours, a.Reward,
 		)
 	}
 
@@ -136,3 +136,357 @@ CREATE INDEX IF NOT EXISTS idx_price_history_user_time ON price_history(user_id,
 CREATE INDEX IF NOT EXISTS idx_users_ticker ON users(ticker);
 CREATE INDEX IF NOT EXISTS idx_notifications_user_time ON notifications(user_id, created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_user_achievements_user ON user_achievements(user_id);
+
+-- Two-factor authentication (TOTP)
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id),
+    secret TEXT NOT NULL,
+    enabled BOOLEAN DEFAULT false,
+    last_used_step BIGINT DEFAULT 0,
+    enabled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Single-use recovery codes for 2FA (stored hashed)
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(user_id, code_hash)
+);
+
+-- Wrong second-factor codes since the last accepted one; too many lock 2FA until locked_until
+ALTER TABLE user_two_factor ADD COLUMN IF NOT EXISTS failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE user_two_factor ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+-- External identities (OIDC single sign-on) linked to local users
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ,
+    UNIQUE(issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- Personal API keys for bots and scripts (only the SHA-256 of the key is stored)
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    scopes TEXT NOT NULL DEFAULT 'read',
+    rate_limit_per_minute INTEGER NOT NULL DEFAULT 60,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+-- Previous tickers keep resolving to their user after a ticker change
+CREATE TABLE IF NOT EXISTS ticker_aliases (
+    old_ticker TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    changed_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticker_aliases_user ON ticker_aliases(user_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ticker_changed_at TIMESTAMPTZ;
+
+-- Closed accounts are anonymized in place rather than deleted, so other users' history stays intact
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Declarative achievement rules: progress is measured by metric (optionally filtered to
+-- event_type and a trailing window) and the achievement unlocks once it reaches threshold
+ALTER TABLE achievements ADD COLUMN IF NOT EXISTS event_type TEXT DEFAULT '';
+ALTER TABLE achievements ADD COLUMN IF NOT EXISTS metric TEXT DEFAULT 'event_count';
+ALTER TABLE achievements ADD COLUMN IF NOT EXISTS threshold DOUBLE PRECISION DEFAULT 1;
+ALTER TABLE achievements ADD COLUMN IF NOT EXISTS window_hours INTEGER DEFAULT 0;
+ALTER TABLE achievements ADD COLUMN IF NOT EXISTS reward DOUBLE PRECISION DEFAULT 0;
+
+-- Activity events that achievement rules are evaluated against
+CREATE TABLE IF NOT EXISTS achievement_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    event_type TEXT NOT NULL,
+    value DOUBLE PRECISION DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_achievement_events_user_type ON achievement_events(user_id, event_type, created_at);
+
+-- Competitive seasons. A season is finalized once it ends: standings are archived
+-- and rewards paid, then the next season starts
+CREATE TABLE IF NOT EXISTS seasons (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    finalized_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Final standings archived when a season ends
+CREATE TABLE IF NOT EXISTS season_standings (
+    id SERIAL PRIMARY KEY,
+    season_id INTEGER NOT NULL REFERENCES seasons(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    rank INTEGER NOT NULL,
+    start_value DOUBLE PRECISION NOT NULL,
+    end_value DOUBLE PRECISION NOT NULL,
+    return_percent DOUBLE PRECISION NOT NULL,
+    reward DOUBLE PRECISION DEFAULT 0,
+    badge TEXT DEFAULT '',
+    UNIQUE(season_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_season_standings_season_rank ON season_standings(season_id, rank);
+CREATE INDEX IF NOT EXISTS idx_season_standings_user ON season_standings(user_id);
+
+-- Private leagues: invite-only groups with their own leaderboard and post feed
+CREATE TABLE IF NOT EXISTS leagues (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    invite_code TEXT UNIQUE NOT NULL,
+    owner_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Kicked members keep a row (kicked_at set) so they can't rejoin with the invite code
+CREATE TABLE IF NOT EXISTS league_members (
+    league_id INTEGER NOT NULL REFERENCES leagues(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    role TEXT NOT NULL DEFAULT 'member', -- 'admin' or 'member'
+    joined_at TIMESTAMPTZ DEFAULT NOW(),
+    kicked_at TIMESTAMPTZ,
+    PRIMARY KEY (league_id, user_id)
+);
+
+-- League posts are only visible to league members
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS league_id INTEGER REFERENCES leagues(id);
+
+CREATE INDEX IF NOT EXISTS idx_league_members_user ON league_members(user_id);
+CREATE INDEX IF NOT EXISTS idx_stock_posts_league ON stock_posts(league_id, created_at DESC) WHERE league_id IS NOT NULL;
+
+-- Stocks a user follows without holding them
+CREATE TABLE IF NOT EXISTS watchlists (
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    stock_user_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, stock_user_id)
+);
+
+-- Price alerts. condition is 'above' or 'below' (target_price), or 'change' (change_percent
+-- over window_minutes; negative for drops). One-shot alerts disable themselves once fired
+CREATE TABLE IF NOT EXISTS price_alerts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    stock_user_id INTEGER NOT NULL REFERENCES users(id),
+    condition TEXT NOT NULL,
+    target_price DOUBLE PRECISION DEFAULT 0,
+    change_percent DOUBLE PRECISION DEFAULT 0,
+    window_minutes INTEGER DEFAULT 0,
+    one_shot BOOLEAN DEFAULT true,
+    enabled BOOLEAN DEFAULT true,
+    last_triggered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_alerts_stock_enabled ON price_alerts(stock_user_id) WHERE enabled;
+CREATE INDEX IF NOT EXISTS idx_price_alerts_user ON price_alerts(user_id);
+
+-- Follow graph between users
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id INTEGER NOT NULL REFERENCES users(id),
+    followee_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee ON follows(followee_id);
+
+-- Per-user privacy preferences; users without a row get the defaults
+CREATE TABLE IF NOT EXISTS privacy_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id),
+    hide_trades BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_posts_author ON stock_posts(author_id, created_at DESC);
+
+-- More privacy preferences: show trades as "Anonymous" and keep net worth off leaderboards
+ALTER TABLE privacy_settings ADD COLUMN IF NOT EXISTS anonymize_trades BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE privacy_settings ADD COLUMN IF NOT EXISTS hide_portfolio_value BOOLEAN NOT NULL DEFAULT false;
+
+-- Keep holdings off public profiles and stock holder lists
+ALTER TABLE privacy_settings ADD COLUMN IF NOT EXISTS hide_holdings BOOLEAN NOT NULL DEFAULT false;
+
+-- When each position was opened, for holding durations. Existing positions are dated
+-- from the purchase that reopened them after the holder's position last went to zero
+-- (within 0.01 shares, as sells leave dust), or from their first purchase if it never did.
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS opened_at TIMESTAMPTZ;
+UPDATE portfolios p SET opened_at = COALESCE(
+    (SELECT MIN(t.timestamp) FROM transactions t
+     WHERE t.buyer_id = p.owner_id AND t.stock_user_id = p.stock_user_id AND t.transaction_type = 'BUY'
+       AND t.timestamp > COALESCE(
+           (SELECT MAX(ledger.timestamp) FROM (
+                SELECT timestamp, transaction_type,
+                       SUM(CASE WHEN transaction_type = 'BUY' THEN num_shares ELSE -num_shares END)
+                           OVER (ORDER BY timestamp, id) AS position
+                FROM transactions
+                WHERE buyer_id = p.owner_id AND stock_user_id = p.stock_user_id
+            ) ledger
+            WHERE ledger.transaction_type != 'BUY' AND ledger.position <= 0.01),
+           '-infinity')),
+    NOW())
+WHERE p.opened_at IS NULL;
+ALTER TABLE portfolios ALTER COLUMN opened_at SET DEFAULT NOW();
+ALTER TABLE portfolios ALTER COLUMN opened_at SET NOT NULL;
+
+-- Threaded replies. reply_count counts direct replies
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES stock_posts(id) ON DELETE CASCADE;
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS reply_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_stock_posts_parent ON stock_posts(parent_id, created_at) WHERE parent_id IS NOT NULL;
+
+-- Post editing and soft deletion. post_revisions keeps each version an edit replaced
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES stock_posts(id) ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post ON post_revisions(post_id, created_at DESC);
+
+-- Moderation. Admins review reported posts; banned users can't post, reply or vote
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMPTZ;
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ;
+
+-- One report per user per post. resolved_at is set once a moderator acts on the post
+CREATE TABLE IF NOT EXISTS post_reports (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES stock_posts(id) ON DELETE CASCADE,
+    reporter_id INTEGER NOT NULL REFERENCES users(id),
+    reason VARCHAR(20) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+    UNIQUE(post_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reports_open ON post_reports(post_id) WHERE resolved_at IS NULL;
+
+-- Audit log of moderation actions. actor_id is NULL for automatic actions
+CREATE TABLE IF NOT EXISTS moderation_log (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER REFERENCES users(id),
+    action VARCHAR(20) NOT NULL,
+    post_id INTEGER REFERENCES stock_posts(id) ON DELETE SET NULL,
+    target_user_id INTEGER REFERENCES users(id),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_time ON moderation_log(created_at DESC);
+
+-- Words and phrases posts may not contain, matched case-insensitively on word boundaries
+CREATE TABLE IF NOT EXISTS banned_words (
+    word VARCHAR(100) PRIMARY KEY,
+    added_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Sentiment only looks at recent posts
+CREATE INDEX IF NOT EXISTS idx_stock_posts_created ON stock_posts(created_at DESC);
+
+-- Parsed @mentions, $cashtags and links. NULL until the post has been parsed
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS entities JSONB;
+
+-- Other stocks a post is cross-posted to through $cashtags
+CREATE TABLE IF NOT EXISTS post_stocks (
+    post_id INTEGER NOT NULL REFERENCES stock_posts(id) ON DELETE CASCADE,
+    stock_user_id INTEGER NOT NULL REFERENCES users(id),
+    PRIMARY KEY (post_id, stock_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_stocks_stock ON post_stocks(stock_user_id);
+
+-- Full-text search. Usernames and tickers are indexed as-is; bios and posts are stemmed
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', username), 'A') ||
+    setweight(to_tsvector('simple', ticker), 'A') ||
+    setweight(to_tsvector('english', COALESCE(bio, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN (search_vector);
+-- Ticker prefix matching with LIKE 'ABC%'
+CREATE INDEX IF NOT EXISTS idx_users_ticker_prefix ON users(ticker text_pattern_ops);
+
+ALTER TABLE stock_posts ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('english', content)
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_stock_posts_search ON stock_posts USING GIN (search_vector);
+
+-- OHLCV candles rolled up from price_history and transactions. A candle covers
+-- [bucket_start, bucket_start + resolution) and only exists if the price was recorded in it
+CREATE TABLE IF NOT EXISTS price_candles (
+    stock_user_id INTEGER NOT NULL REFERENCES users(id),
+    resolution VARCHAR(5) NOT NULL,
+    bucket_start TIMESTAMPTZ NOT NULL,
+    open DOUBLE PRECISION NOT NULL,
+    high DOUBLE PRECISION NOT NULL,
+    low DOUBLE PRECISION NOT NULL,
+    close DOUBLE PRECISION NOT NULL,
+    volume DOUBLE PRECISION NOT NULL DEFAULT 0,
+    trades INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (stock_user_id, resolution, bucket_start)
+);
+
+-- How far incremental background jobs have processed
+CREATE TABLE IF NOT EXISTS job_watermarks (
+    name VARCHAR(50) PRIMARY KEY,
+    watermark TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_time ON price_history(timestamp);
+CREATE INDEX IF NOT EXISTS idx_transactions_stock_time ON transactions(stock_user_id, timestamp);
+
+-- Retention job runs: rows deleted from each table when compacting it to each resolution
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id SERIAL PRIMARY KEY,
+    table_name VARCHAR(50) NOT NULL,
+    resolution VARCHAR(5) NOT NULL,
+    cutoff TIMESTAMPTZ NOT NULL,
+    rows_deleted BIGINT NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_runs_time ON retention_runs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_portfolio_snapshots_time ON portfolio_snapshots(timestamp);
+
+-- Everyone who has ever voted on a post, including votes since toggled off, so voting
+-- again after toggling a vote off doesn't count toward vote achievements
+CREATE TABLE IF NOT EXISTS post_voters (
+    post_id INTEGER NOT NULL REFERENCES stock_posts(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    PRIMARY KEY (post_id, user_id)
+);
+INSERT INTO post_voters (post_id, user_id) SELECT post_id, user_id FROM post_votes ON CONFLICT DO NOTHING;
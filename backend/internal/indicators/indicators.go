@@ -0,0 +1,178 @@
+// Package indicators computes technical indicators over price series.
+//
+// Every function returns a series as long as its input, aligned with it. Points where the
+// indicator isn't defined yet, because fewer than window values precede them, are NaN.
+package indicators
+
+import "math"
+
+// SMA is the simple moving average of the last window values.
+func SMA(values []float64, window int) []float64 {
+	out := undefined(len(values))
+	if window < 1 {
+		return out
+	}
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= window {
+			sum -= values[i-window]
+		}
+		if i >= window-1 {
+			out[i] = sum / float64(window)
+		}
+	}
+	return out
+}
+
+// EMA is the exponential moving average with smoothing 2/(window+1), seeded with the SMA
+// of the first window values.
+func EMA(values []float64, window int) []float64 {
+	out := undefined(len(values))
+	if window < 1 || len(values) < window {
+		return out
+	}
+	alpha := 2 / float64(window+1)
+	ema := 0.0
+	for _, v := range values[:window] {
+		ema += v
+	}
+	ema /= float64(window)
+	out[window-1] = ema
+	for i := window; i < len(values); i++ {
+		ema += alpha * (values[i] - ema)
+		out[i] = ema
+	}
+	return out
+}
+
+// RSI is Wilder's relative strength index, from 0 to 100, over window changes. It needs
+// window+1 values before the first point.
+func RSI(values []float64, window int) []float64 {
+	out := undefined(len(values))
+	if window < 1 || len(values) <= window {
+		return out
+	}
+
+	gain, loss := 0.0, 0.0
+	for i := 1; i <= window; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gain += change
+		} else {
+			loss -= change
+		}
+	}
+	gain /= float64(window)
+	loss /= float64(window)
+	out[window] = rsi(gain, loss)
+
+	for i := window + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		up, down := 0.0, 0.0
+		if change > 0 {
+			up = change
+		} else {
+			down = -change
+		}
+		gain = (gain*float64(window-1) + up) / float64(window)
+		loss = (loss*float64(window-1) + down) / float64(window)
+		out[i] = rsi(gain, loss)
+	}
+	return out
+}
+
+func rsi(gain, loss float64) float64 {
+	if loss == 0 {
+		if gain == 0 {
+			// A flat price is neither overbought nor oversold
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+gain/loss)
+}
+
+// Bollinger returns Bollinger bands: the SMA of the last window values, and bands k
+// population standard deviations above and below it.
+func Bollinger(values []float64, window int, k float64) (middle, upper, lower []float64) {
+	middle = SMA(values, window)
+	upper = undefined(len(values))
+	lower = undefined(len(values))
+	for i := range values {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		sd := stddev(values[i-window+1:i+1], middle[i])
+		upper[i] = middle[i] + k*sd
+		lower[i] = middle[i] - k*sd
+	}
+	return middle, upper, lower
+}
+
+// VWAP is the volume-weighted average price since the start of the series. Until anything
+// has traded it's the plain average price.
+func VWAP(prices, volumes []float64) []float64 {
+	out := undefined(len(prices))
+	weighted, volume, sum := 0.0, 0.0, 0.0
+	for i, p := range prices {
+		sum += p
+		if i < len(volumes) && volumes[i] > 0 {
+			weighted += p * volumes[i]
+			volume += volumes[i]
+		}
+		if volume > 0 {
+			out[i] = weighted / volume
+		} else {
+			out[i] = sum / float64(i+1)
+		}
+	}
+	return out
+}
+
+// Volatility is the standard deviation of the last window log returns, as a percentage
+// per period. It needs window+1 values before the first point; returns involving a
+// non-positive price count as zero.
+func Volatility(values []float64, window int) []float64 {
+	out := undefined(len(values))
+	if window < 1 || len(values) <= window {
+		return out
+	}
+
+	returns := make([]float64, len(values))
+	for i := 1; i < len(values); i++ {
+		if values[i] > 0 && values[i-1] > 0 {
+			returns[i] = math.Log(values[i] / values[i-1])
+		}
+	}
+	for i := window; i < len(values); i++ {
+		r := returns[i-window+1 : i+1]
+		out[i] = stddev(r, mean(r)) * 100
+	}
+	return out
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stddev is the population standard deviation of values around the given mean.
+func stddev(values []float64, mean float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func undefined(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+var nan = math.NaN()
+
+// assertSeries fails unless got matches want point for point, with NaN matching NaN.
+func assertSeries(t *testing.T, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d points %v, want %d points %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("point %d = %v, want %v (series %v)", i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestSMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		window int
+		want   []float64
+	}{
+		{"warm-up prefix", []float64{1, 2, 3, 4, 5}, 3, []float64{nan, nan, 2, 3, 4}},
+		{"window of one", []float64{4, 8, 6}, 1, []float64{4, 8, 6}},
+		{"window spans the input", []float64{2, 4, 9}, 3, []float64{nan, nan, 5}},
+		{"window longer than input", []float64{1, 2}, 3, []float64{nan, nan}},
+		{"empty", nil, 3, []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, SMA(tt.values, tt.window), tt.want)
+		})
+	}
+}
+
+func TestEMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		window int
+		want   []float64
+	}{
+		// alpha = 1/2, seeded with (1+2+3)/3 = 2; a linear series stays on the SMA
+		{"warm-up prefix", []float64{1, 2, 3, 4, 5}, 3, []float64{nan, nan, 2, 3, 4}},
+		// alpha = 2/3, seeded with (2+4)/2 = 3, then 3+2/3*3 = 5, 5+2/3*3 = 7, 7+2/3*5 = 31/3
+		{"smoothing", []float64{2, 4, 6, 8, 12}, 2, []float64{nan, 3, 5, 7, 31.0 / 3}},
+		{"window longer than input", []float64{1, 2}, 3, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, EMA(tt.values, tt.window), tt.want)
+		})
+	}
+}
+
+func TestRSI(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		window int
+		want   []float64
+	}{
+		// Changes +1 +1 -1 +1. Seed: gain 1, loss 0 → 100. Then gain (1+0)/2 = 0.5,
+		// loss (0+1)/2 = 0.5 → 50. Then gain (0.5+1)/2 = 0.75, loss 0.5/2 = 0.25 → RS 3 → 75.
+		{"Wilder smoothing", []float64{1, 2, 3, 2, 3}, 2, []float64{nan, nan, 100, 50, 75}},
+		{"flat series", []float64{5, 5, 5, 5}, 2, []float64{nan, nan, 50, 50}},
+		{"only falling", []float64{4, 3, 2}, 2, []float64{nan, nan, 0}},
+		{"window longer than input", []float64{1, 2}, 2, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, RSI(tt.values, tt.window), tt.want)
+		})
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	// Population standard deviation of each window of three consecutive integers
+	sd := math.Sqrt(2.0 / 3)
+
+	tests := []struct {
+		name                 string
+		values               []float64
+		window               int
+		k                    float64
+		middle, upper, lower []float64
+	}{
+		{
+			name:   "warm-up prefix",
+			values: []float64{1, 2, 3, 4, 5},
+			window: 3,
+			k:      2,
+			middle: []float64{nan, nan, 2, 3, 4},
+			upper:  []float64{nan, nan, 2 + 2*sd, 3 + 2*sd, 4 + 2*sd},
+			lower:  []float64{nan, nan, 2 - 2*sd, 3 - 2*sd, 4 - 2*sd},
+		},
+		{
+			name:   "flat series has no width",
+			values: []float64{7, 7, 7},
+			window: 2,
+			k:      2,
+			middle: []float64{nan, 7, 7},
+			upper:  []float64{nan, 7, 7},
+			lower:  []float64{nan, 7, 7},
+		},
+		{
+			// Window {1, 5}: mean 3, deviation 2
+			name:   "k scales the bands",
+			values: []float64{1, 5},
+			window: 2,
+			k:      1.5,
+			middle: []float64{nan, 3},
+			upper:  []float64{nan, 6},
+			lower:  []float64{nan, 0},
+		},
+		{
+			name:   "window longer than input",
+			values: []float64{1, 2},
+			window: 3,
+			k:      2,
+			middle: []float64{nan, nan},
+			upper:  []float64{nan, nan},
+			lower:  []float64{nan, nan},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middle, upper, lower := Bollinger(tt.values, tt.window, tt.k)
+			assertSeries(t, middle, tt.middle)
+			assertSeries(t, upper, tt.upper)
+			assertSeries(t, lower, tt.lower)
+		})
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	tests := []struct {
+		name            string
+		prices, volumes []float64
+		want            []float64
+	}{
+		// 10×1 / 1, then still 10 with nothing traded, then (10×1 + 30×3) / 4 = 25
+		{"volume weighted", []float64{10, 20, 30}, []float64{1, 0, 3}, []float64{10, 10, 25}},
+		{"zero total volume is the plain average", []float64{10, 20, 30}, []float64{0, 0, 0}, []float64{10, 15, 20}},
+		{"plain average until the first trade", []float64{10, 20, 30}, []float64{0, 2, 0}, []float64{10, 20, 20}},
+		{"missing volumes count as zero", []float64{10, 20}, nil, []float64{10, 15}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, VWAP(tt.prices, tt.volumes), tt.want)
+		})
+	}
+}
+
+func TestVolatility(t *testing.T) {
+	// Returns ln(1.1) and ln(0.9): the population standard deviation of two values is half
+	// their difference
+	up, down := math.Log(1.1), math.Log(0.9)
+
+	tests := []struct {
+		name   string
+		values []float64
+		window int
+		want   []float64
+	}{
+		{"warm-up prefix", []float64{100, 110, 99}, 2, []float64{nan, nan, (up - down) / 2 * 100}},
+		{"constant growth", []float64{1, 2, 4, 8}, 2, []float64{nan, nan, 0, 0}},
+		{"flat series", []float64{5, 5, 5}, 1, []float64{nan, 0, 0}},
+		{"window longer than input", []float64{1, 2}, 2, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, Volatility(tt.values, tt.window), tt.want)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+type CloseAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`    // required when 2FA is enabled
+	Confirm  string `json:"confirm"` // must be "DELETE"
+}
+
+// ClosureQuote previews what happens when an account is closed.
+type ClosureQuote struct {
+	Ticker        string                `json:"ticker"`
+	PayoutPrice   float64               `json:"payout_price"`
+	PayoutWindow  string                `json:"payout_window"`
+	HolderPayouts []ClosureHolderPayout `json:"holder_payouts"`
+	TotalPayout   float64               `json:"total_payout"`
+	Liquidations  []ClosureLiquidation  `json:"liquidations"`
+	GrubForfeited float64               `json:"grub_forfeited"`
+}
+
+type ClosureHolderPayout struct {
+	UserID    int     `json:"-"`
+	Username  string  `json:"username"`
+	NumShares float64 `json:"num_shares"`
+	Payout    float64 `json:"payout"`
+}
+
+type ClosureLiquidation struct {
+	StockUserID int     `json:"-"`
+	Ticker      string  `json:"ticker"`
+	NumShares   float64 `json:"num_shares"`
+	Proceeds    float64 `json:"proceeds"`
+}
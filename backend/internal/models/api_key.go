@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// API key scopes. A key may hold several; session (cookie/JWT) auth implicitly has all of them.
+const (
+	ScopeRead  = "read"
+	ScopeTrade = "trade"
+	ScopePost  = "post"
+)
+
+var ValidScopes = []string{ScopeRead, ScopeTrade, ScopePost}
+
+type APIKey struct {
+	ID                 int        `json:"id"`
+	UserID             int        `json:"user_id"`
+	Name               string     `json:"name"`
+	Prefix             string     `json:"prefix"`
+	Scopes             []string   `json:"scopes"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	LastUsedAt         *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// APIKeyOwner is what AuthRequired needs to authenticate a request made with a key.
+type APIKeyOwner struct {
+	KeyID              int
+	UserID             int
+	Username           string
+	Scopes             []string
+	RateLimitPerMinute int
+}
+
+type CreateAPIKeyRequest struct {
+	Name               string   `json:"name" binding:"required,min=1,max=50"`
+	Scopes             []string `json:"scopes" binding:"required,min=1"`
+	RateLimitPerMinute int      `json:"rate_limit_per_minute"`
+}
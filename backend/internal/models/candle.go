@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// CandleIntervals are the candle sizes GET /api/stocks/:ticker/candles?interval= accepts.
+var CandleIntervals = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// Candle summarizes a stock's price over one interval starting at Time. Volume is the
+// number of shares traded.
+type Candle struct {
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+	Trades int       `json:"trades"`
+}
+
+// StockCandles are a stock's candles from From to To, oldest first. Intervals in which the
+// price was never recorded have no candle.
+type StockCandles struct {
+	Ticker   string    `json:"ticker"`
+	Interval string    `json:"interval"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Candles  []Candle  `json:"candles"`
+}
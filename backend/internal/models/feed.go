@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Feed item types.
+const (
+	FeedTrade       = "trade"
+	FeedPost        = "post"
+	FeedAchievement = "achievement"
+	FeedBigMove     = "big_move"
+)
+
+// BigMovePercent is how far a portfolio's daily close must move from the previous
+// day's close to show up in followers' feeds.
+const BigMovePercent = 10.0
+
+// FeedItem is one entry in the personalized activity feed. Which detail fields are set
+// depends on Type.
+type FeedItem struct {
+	Type     string `json:"type"`
+	ID       int    `json:"id"`
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Ticker   string `json:"ticker"`
+
+	StockTicker string `json:"stock_ticker,omitempty"`
+
+	// trade
+	TransactionType string  `json:"transaction_type,omitempty"`
+	NumShares       float64 `json:"num_shares,omitempty"`
+	PricePerShare   float64 `json:"price_per_share,omitempty"`
+	TotalGrub       float64 `json:"total_grub,omitempty"`
+
+	// post
+	Content string `json:"content,omitempty"`
+
+	// achievement
+	AchievementID   string `json:"achievement_id,omitempty"`
+	AchievementName string `json:"achievement_name,omitempty"`
+	AchievementIcon string `json:"achievement_icon,omitempty"`
+
+	// big_move: the portfolio's previous and current daily close
+	PreviousValue float64 `json:"previous_value,omitempty"`
+	Value         float64 `json:"value,omitempty"`
+	ChangePercent float64 `json:"change_percent,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FeedPage struct {
+	Items      []FeedItem `json:"items"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type FollowUser struct {
+	UserID            int       `json:"user_id"`
+	Username          string    `json:"username"`
+	Ticker            string    `json:"ticker"`
+	CurrentSharePrice float64   `json:"current_share_price"`
+	FollowedAt        time.Time `json:"followed_at"`
+}
+
+// FeedCursor marks the last item of a feed page. Items are ordered by (CreatedAt, Type, ID)
+// descending, so the triple is unique and stable across pages.
+type FeedCursor struct {
+	CreatedAt time.Time
+	Type      string
+	ID        int
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// IndicatorWindows are the number of candles each indicator looks back over.
+type IndicatorWindows struct {
+	SMA        int `json:"sma"`
+	EMA        int `json:"ema"`
+	RSI        int `json:"rsi"`
+	Bollinger  int `json:"bollinger"`
+	Volatility int `json:"volatility"`
+}
+
+// IndicatorPoint is an indicator's value for the candle starting at Time.
+type IndicatorPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// BollingerPoint is the Bollinger bands for the candle starting at Time.
+type BollingerPoint struct {
+	Time   time.Time `json:"time"`
+	Middle float64   `json:"middle"`
+	Upper  float64   `json:"upper"`
+	Lower  float64   `json:"lower"`
+}
+
+// StockIndicators are technical indicators computed from a stock's candle closes between
+// From and To. VWAP is anchored at From, and Volatility is the standard deviation of
+// per-candle returns as a percentage.
+type StockIndicators struct {
+	Ticker     string           `json:"ticker"`
+	Interval   string           `json:"interval"`
+	From       time.Time        `json:"from"`
+	To         time.Time        `json:"to"`
+	Windows    IndicatorWindows `json:"windows"`
+	SMA        []IndicatorPoint `json:"sma"`
+	EMA        []IndicatorPoint `json:"ema"`
+	RSI        []IndicatorPoint `json:"rsi"`
+	Bollinger  []BollingerPoint `json:"bollinger"`
+	VWAP       []IndicatorPoint `json:"vwap"`
+	Volatility []IndicatorPoint `json:"volatility"`
+}
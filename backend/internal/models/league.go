@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	LeagueRoleAdmin  = "admin"
+	LeagueRoleMember = "member"
+)
+
+type League struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	InviteCode  string    `json:"invite_code"`
+	OwnerID     int       `json:"owner_id"`
+	MemberCount int       `json:"member_count"`
+	MyRole      string    `json:"my_role,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type LeagueMember struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	Ticker   string    `json:"ticker"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type CreateLeagueRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type JoinLeagueRequest struct {
+	InviteCode string `json:"invite_code" binding:"required"`
+}
+
+type CreateLeaguePostRequest struct {
+	Ticker  string `json:"ticker" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
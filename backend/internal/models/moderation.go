@@ -0,0 +1,67 @@
+package models
+
+// Reasons a post can be reported for
+const (
+	ReportSpam         = "spam"
+	ReportAbuse        = "abuse"
+	ReportManipulation = "manipulation"
+	ReportOther        = "other"
+)
+
+var ReportReasons = []string{ReportSpam, ReportAbuse, ReportManipulation, ReportOther}
+
+// Moderation log actions. Automatic hides are logged as ModAutoHide with no actor.
+const (
+	ModHide             = "hide"
+	ModAutoHide         = "auto_hide"
+	ModRestore          = "restore"
+	ModDismiss          = "dismiss"
+	ModBan              = "ban"
+	ModUnban            = "unban"
+	ModAddBannedWord    = "add_word"
+	ModRemoveBannedWord = "remove_word"
+)
+
+type ReportPostRequest struct {
+	Reason  string `json:"reason" binding:"required"`
+	Details string `json:"details"`
+}
+
+// ReportedPost is an entry in the moderation queue: a post with open reports.
+type ReportedPost struct {
+	PostID         int      `json:"post_id"`
+	AuthorID       int      `json:"author_id"`
+	AuthorUsername string   `json:"author_username"`
+	StockTicker    string   `json:"stock_ticker"`
+	Content        string   `json:"content"`
+	CreatedAt      string   `json:"created_at"`
+	Hidden         bool     `json:"hidden"`
+	ReportCount    int      `json:"report_count"`
+	Reasons        []string `json:"reasons"`
+	Details        []string `json:"details"`
+	LastReportedAt string   `json:"last_reported_at"`
+}
+
+type ModerationNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// ModerationLogEntry is one moderation action. ActorUsername is empty for automatic actions.
+type ModerationLogEntry struct {
+	ID             int    `json:"id"`
+	ActorUsername  string `json:"actor_username,omitempty"`
+	Action         string `json:"action"`
+	PostID         *int   `json:"post_id,omitempty"`
+	TargetUsername string `json:"target_username,omitempty"`
+	Note           string `json:"note"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type BannedWord struct {
+	Word      string `json:"word"`
+	CreatedAt string `json:"created_at"`
+}
+
+type AddBannedWordRequest struct {
+	Word string `json:"word" binding:"required"`
+}
@@ -14,11 +14,46 @@ type Notification struct {
 	CreatedAt     time.Time `json:"created_at"`
 }
 
+// Achievement event types, emitted by the actions that can progress an achievement.
+const (
+	EventTrade      = "trade"       // value: Grub traded
+	EventPost       = "post"        // value: 1
+	EventVote       = "vote"        // value: 1
+	EventDailyClaim = "daily_claim" // value: bonus claimed
+	EventDividend   = "dividend"    // value: dividend paid
+)
+
+// Achievement metrics. The event metrics aggregate achievement_events of the rule's event
+// type; the others measure the user's current state and ignore the event type.
+const (
+	MetricEventCount       = "event_count"
+	MetricEventSum         = "event_sum"
+	MetricPortfolioValue   = "portfolio_value"
+	MetricHoldingDays      = "holding_days"
+	MetricDistinctHoldings = "distinct_holdings"
+)
+
 type Achievement struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Icon        string `json:"icon"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Icon        string  `json:"icon"`
+	EventType   string  `json:"event_type,omitempty"`
+	Metric      string  `json:"metric"`
+	Threshold   float64 `json:"threshold"`
+	WindowHours int     `json:"window_hours"` // 0 = all time
+	Reward      float64 `json:"reward"`
+}
+
+// AchievementStatus is an achievement definition with one user's standing: whether and when
+// they earned it, how rare it is across users, and progress toward it (e.g. 7/10 trades today).
+type AchievementStatus struct {
+	Achievement
+	Earned        bool       `json:"earned"`
+	EarnedAt      *time.Time `json:"earned_at"`
+	RarityPercent float64    `json:"rarity_percent"`
+	Current       float64    `json:"current"`
+	Target        float64    `json:"target"`
 }
 
 type UserAchievement struct {
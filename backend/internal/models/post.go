@@ -1,18 +1,62 @@
 package models
 
+// StockPost is a post about a stock, or a reply to one. Replies carry their parent's ID
+// and nest at most MaxReplyDepth deep; Replies is only filled in for threads. EditedAt is
+// set once the author edits the post. Deleted posts and posts hidden by moderators only
+// show up in threads, without content.
 type StockPost struct {
-	ID             int    `json:"id"`
-	AuthorID       int    `json:"author_id"`
-	AuthorUsername string `json:"author_username"`
-	StockTicker    string `json:"stock_ticker"`
-	StockUserID    int    `json:"-"`
-	Content        string `json:"content"`
-	Likes          int    `json:"likes"`
-	Dislikes       int    `json:"dislikes"`
-	UserVote       int    `json:"user_vote"` // 1 = liked, -1 = disliked, 0 = none
-	CreatedAt      string `json:"created_at"`
+	ID             int          `json:"id"`
+	AuthorID       int          `json:"author_id"`
+	AuthorUsername string       `json:"author_username"`
+	StockTicker    string       `json:"stock_ticker"`
+	StockUserID    int          `json:"-"`
+	LeagueID       *int         `json:"league_id,omitempty"`
+	ParentID       *int         `json:"parent_id,omitempty"`
+	Depth          int          `json:"depth"`
+	Content        string       `json:"content"`
+	Entities       []PostEntity `json:"entities"`
+	Likes          int          `json:"likes"`
+	Dislikes       int          `json:"dislikes"`
+	ReplyCount     int          `json:"reply_count"`
+	UserVote       int          `json:"user_vote"` // 1 = liked, -1 = disliked, 0 = none
+	CreatedAt      string       `json:"created_at"`
+	EditedAt       *string      `json:"edited_at,omitempty"`
+	Deleted        bool         `json:"deleted,omitempty"`
+	Hidden         bool         `json:"hidden,omitempty"`
+	Replies        []StockPost  `json:"replies,omitempty"`
 }
 
+// PostEntity is an @mention, $cashtag or link in a post's content, resolved so clients
+// can render it without parsing. Start and End are character offsets, End exclusive.
+// Mentions carry the user's ID and username; cashtags carry the stock's user ID and
+// current ticker.
+type PostEntity struct {
+	Type     string `json:"type"` // EntityMention, EntityCashtag or EntityURL
+	Text     string `json:"text"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	UserID   int    `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Ticker   string `json:"ticker,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+const (
+	EntityMention = "mention"
+	EntityCashtag = "cashtag"
+	EntityURL     = "url"
+)
+
+// PostRevision is an earlier version of an edited post, replaced at ReplacedAt.
+type PostRevision struct {
+	ID         int    `json:"id"`
+	Content    string `json:"content"`
+	ReplacedAt string `json:"replaced_at"`
+}
+
+// MaxReplyDepth is how deep replies can nest; top-level posts are depth 0.
+const MaxReplyDepth = 5
+
 type PostVote struct {
 	ID        int    `json:"id"`
 	PostID    int    `json:"post_id"`
@@ -20,9 +64,3 @@ type PostVote struct {
 	VoteType  int    `json:"vote_type"` // 1 or -1
 	CreatedAt string `json:"created_at"`
 }
-
-// SentimentScore represents the aggregated sentiment for a stock from its top posts
-type SentimentScore struct {
-	StockUserID int
-	NetLikes    int // sum of (likes - dislikes) across top 10 posts
-}
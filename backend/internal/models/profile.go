@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// PublicProfile is what other users see about a user. Sections the user keeps private
+// are empty, with a flag saying so.
+type PublicProfile struct {
+	Username          string    `json:"username"`
+	Ticker            string    `json:"ticker"`
+	Bio               string    `json:"bio"`
+	CurrentSharePrice float64   `json:"current_share_price"`
+	Change24hPercent  float64   `json:"change_24h_percent"`
+	SharesOutstanding int       `json:"shares_outstanding"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	FollowerCount  int  `json:"follower_count"`
+	FollowingCount int  `json:"following_count"`
+	IsFollowing    bool `json:"is_following"`
+	IsMe           bool `json:"is_me"`
+
+	Achievements []UserAchievement `json:"achievements"`
+	TopHolders   []StockHolder     `json:"top_holders"`
+	RecentPosts  []StockPost       `json:"recent_posts"`
+
+	HoldingsHidden bool            `json:"holdings_hidden"`
+	Holdings       []PublicHolding `json:"holdings"`
+
+	// ValueHidden means the performance chart only carries returns, not portfolio values
+	ValueHidden bool               `json:"value_hidden"`
+	Performance []PerformancePoint `json:"performance"`
+}
+
+// StockHolder is one holder of a stock. Holders who hide their holdings are listed as
+// "Anonymous" without a user ID.
+type StockHolder struct {
+	UserID           int       `json:"user_id,omitempty"`
+	Username         string    `json:"username"`
+	Ticker           string    `json:"ticker,omitempty"`
+	NumShares        float64   `json:"num_shares"`
+	OwnershipPercent float64   `json:"ownership_percent"`
+	EntryPrice       float64   `json:"entry_price"`
+	OpenedAt         time.Time `json:"opened_at"`
+	HoldingDays      int       `json:"holding_days"`
+}
+
+// StockHolders is a page of a stock's shareholder registry, largest holders first.
+type StockHolders struct {
+	Ticker            string        `json:"ticker"`
+	SharesOutstanding int           `json:"shares_outstanding"`
+	Holders           []StockHolder `json:"holders"`
+	Ownership         Ownership     `json:"ownership"`
+}
+
+// Ownership summarizes how a stock's shares are spread. FloatShares are the shares not
+// held by anyone. ConcentrationHHI is the Herfindahl-Hirschman index of the holders'
+// percentages of shares outstanding: 10000 means one holder owns everything.
+type Ownership struct {
+	HolderCount      int     `json:"holder_count"`
+	SharesHeld       float64 `json:"shares_held"`
+	FloatShares      float64 `json:"float_shares"`
+	FloatPercent     float64 `json:"float_percent"`
+	TopHolderPercent float64 `json:"top_holder_percent"`
+	Top5Percent      float64 `json:"top5_percent"`
+	ConcentrationHHI float64 `json:"concentration_hhi"`
+}
+
+// PublicHolding is a stock someone holds, as shown on their public profile. NumShares and
+// Value are omitted when they hide their portfolio value.
+type PublicHolding struct {
+	Ticker            string  `json:"ticker"`
+	Username          string  `json:"username"`
+	NumShares         float64 `json:"num_shares,omitempty"`
+	CurrentPrice      float64 `json:"current_price"`
+	Value             float64 `json:"value,omitempty"`
+	ProfitLossPercent float64 `json:"profit_loss_percent"`
+}
+
+// PerformancePoint is one point of a public portfolio chart. ReturnPercent is measured from
+// the first point of the chart; Value is omitted when the user hides their portfolio value.
+type PerformancePoint struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Value         float64   `json:"value,omitempty"`
+	ReturnPercent float64   `json:"return_percent"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// RetentionTier is one step of the retention policy: rows older than AfterHours are kept
+// at no finer than Resolution.
+type RetentionTier struct {
+	Resolution string  `json:"resolution"`
+	AfterHours float64 `json:"after_hours"`
+}
+
+// RetentionRun records one compaction of a table to a resolution.
+type RetentionRun struct {
+	ID          int       `json:"id"`
+	Table       string    `json:"table"`
+	Resolution  string    `json:"resolution"`
+	Cutoff      time.Time `json:"cutoff"`
+	RowsDeleted int64     `json:"rows_deleted"`
+	DurationMs  int       `json:"duration_ms"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RetentionReport shows the retention policy, the rows reclaimed from each table so far,
+// each table's estimated current size and the most recent runs.
+type RetentionReport struct {
+	Tiers         []RetentionTier  `json:"tiers"`
+	RowsReclaimed map[string]int64 `json:"rows_reclaimed"`
+	EstimatedRows map[string]int64 `json:"estimated_rows"`
+	RecentRuns    []RetentionRun   `json:"recent_runs"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Search result types, also accepted by GET /api/search?type= to search just one of them
+const (
+	SearchTypeUser = "user"
+	SearchTypePost = "post"
+)
+
+// SearchResult is one match of a search. Exactly one of User and Post is set, matching Type.
+// Results are ordered by Rank, highest first.
+type SearchResult struct {
+	Type string      `json:"type"`
+	Rank float64     `json:"rank"`
+	User *SearchUser `json:"user,omitempty"`
+	Post *StockPost  `json:"post,omitempty"`
+}
+
+// SearchUser is a trader (and their stock) found by username, ticker or bio.
+type SearchUser struct {
+	ID                int     `json:"id"`
+	Username          string  `json:"username"`
+	Ticker            string  `json:"ticker"`
+	Bio               string  `json:"bio"`
+	CurrentSharePrice float64 `json:"current_share_price"`
+}
+
+type SearchPage struct {
+	Query      string         `json:"query"`
+	Results    []SearchResult `json:"results"`
+	NextOffset *int           `json:"next_offset,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type Season struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	FinalizedAt *time.Time `json:"finalized_at"`
+	Status      string     `json:"status"` // "upcoming", "active", "ended" (awaiting finalization) or "completed"
+}
+
+// SeasonStanding is a user's trading return over a season, measured between portfolio
+// snapshots less any bonuses and rewards credited in between.
+// StartValue and EndValue are omitted for users who hide their portfolio value.
+type SeasonStanding struct {
+	Rank          int     `json:"rank"`
+	UserID        int     `json:"user_id"`
+	Username      string  `json:"username"`
+	Ticker        string  `json:"ticker"`
+	StartValue    float64 `json:"start_value,omitempty"`
+	EndValue      float64 `json:"end_value,omitempty"`
+	ReturnPercent float64 `json:"return_percent"`
+	Reward        float64 `json:"reward"`
+	Badge         string  `json:"badge,omitempty"`
+}
+
+type SeasonLeaderboard struct {
+	Season    Season           `json:"season"`
+	Standings []SeasonStanding `json:"standings"`
+	MyRank    *SeasonStanding  `json:"my_rank"`
+}
+
+// SeasonBadge is a badge a user earned by placing in a season.
+type SeasonBadge struct {
+	SeasonID   int       `json:"season_id"`
+	SeasonName string    `json:"season_name"`
+	Rank       int       `json:"rank"`
+	Badge      string    `json:"badge"`
+	Reward     float64   `json:"reward"`
+	EarnedAt   time.Time `json:"earned_at"`
+}
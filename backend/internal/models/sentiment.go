@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Reasons a vote is left out of a stock's sentiment
+const (
+	DiscountOwnStock   = "own_stock"
+	DiscountVotingRing = "voting_ring"
+)
+
+// SentimentVote is a vote on a public post, as used to compute sentiment.
+type SentimentVote struct {
+	PostID        int
+	StockUserID   int
+	Ticker        string
+	VoterID       int
+	VoterUsername string
+	VoteType      int
+	VotedAt       time.Time
+	PostCreatedAt time.Time
+}
+
+// StockSentiment is a stock's sentiment over the sentiment window. RawNet is likes minus
+// dislikes; WeightedNet is what the market maker uses, after age decay and discounts.
+type StockSentiment struct {
+	Ticker          string  `json:"ticker"`
+	Votes           int     `json:"votes"`
+	RawNet          int     `json:"raw_net"`
+	WeightedNet     float64 `json:"weighted_net"`
+	DiscountedVotes int     `json:"discounted_votes"`
+}
+
+// DiscountedVote is a vote that didn't count toward sentiment, and why.
+type DiscountedVote struct {
+	PostID        int       `json:"post_id"`
+	Ticker        string    `json:"ticker"`
+	VoterUsername string    `json:"voter_username"`
+	VoteType      int       `json:"vote_type"`
+	VotedAt       time.Time `json:"voted_at"`
+	Reason        string    `json:"reason"`
+}
+
+// VotingRing is a group of users who repeatedly voted the same way on the same posts about
+// a stock within minutes of each other.
+type VotingRing struct {
+	Ticker      string   `json:"ticker"`
+	Members     []string `json:"members"`
+	SharedPosts int      `json:"shared_posts"`
+}
+
+// SentimentReport explains how the current sentiment was computed.
+type SentimentReport struct {
+	WindowHours     float64          `json:"window_hours"`
+	HalfLifeHours   float64          `json:"half_life_hours"`
+	Stocks          []StockSentiment `json:"stocks"`
+	Rings           []VotingRing     `json:"rings"`
+	DiscountedVotes []DiscountedVote `json:"discounted_votes"`
+}
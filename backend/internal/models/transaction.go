@@ -47,6 +47,7 @@ type StockDetail struct {
 	Volume24h         float64                  `json:"volume_24h"`
 	AllTimeHigh       float64                  `json:"all_time_high"`
 	AllTimeLow        float64                  `json:"all_time_low"`
+	Ownership         Ownership                `json:"ownership"`
 }
 
 type StockListItem struct {
@@ -55,15 +56,88 @@ type StockListItem struct {
 	Ticker            string         `json:"ticker"`
 	CurrentSharePrice float64        `json:"current_share_price"`
 	Change24hPercent  float64        `json:"change_24h_percent"`
+	Volume24h         float64        `json:"volume_24h"`
+	MarketCap         float64        `json:"market_cap"`
 	SparklineData     []float64      `json:"sparkline_data"`
 }
 
+// Stock list sort keys for GET /api/stocks?sort=
+const (
+	StockSortPrice     = "price"
+	StockSortChange24h = "change_24h"
+	StockSortVolume    = "volume"
+	StockSortMarketCap = "market_cap"
+	StockSortNewest    = "newest"
+)
+
+var StockSorts = []string{StockSortPrice, StockSortChange24h, StockSortVolume, StockSortMarketCap, StockSortNewest}
+
+// StockListQuery selects a page of the stock list. Nil filters don't apply; HeldBy and
+// WatchedBy, when non-zero, keep only stocks that user holds or watches.
+type StockListQuery struct {
+	Sort      string
+	Ascending bool
+	MinPrice  *float64
+	MaxPrice  *float64
+	MinVolume *float64
+	HeldBy    int
+	WatchedBy int
+	Limit     int
+	Cursor    string
+}
+
+// StockListCursor marks the last stock of a page: its value of the sort key and its ID,
+// which breaks ties.
+type StockListCursor struct {
+	Value float64
+	ID    int
+}
+
+type StockListPage struct {
+	Stocks     []StockListItem `json:"stocks"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Leaderboard categories, matching the LeaderboardData JSON keys.
+const (
+	LeaderboardMostValuable    = "most_valuable"
+	LeaderboardBiggestGainers  = "biggest_gainers"
+	LeaderboardBiggestLosers   = "biggest_losers"
+	LeaderboardRichestTraders  = "richest_traders"
+	LeaderboardBestPerformance = "best_performance"
+)
+
+var LeaderboardCategories = []string{
+	LeaderboardMostValuable,
+	LeaderboardBiggestGainers,
+	LeaderboardBiggestLosers,
+	LeaderboardRichestTraders,
+	LeaderboardBestPerformance,
+}
+
+// LeaderboardWindows are the lookbacks gainers and losers can be ranked over.
+var LeaderboardWindows = []string{"1h", "24h", "7d", "30d", "all"}
+
+// LeaderboardQuery selects a page of the leaderboard. With Category set only that category
+// is returned; otherwise every category's first Limit entries are.
+type LeaderboardQuery struct {
+	Window   string
+	Category string
+	Limit    int
+	Offset   int
+}
+
 type LeaderboardData struct {
 	MostValuable    []LeaderboardEntry `json:"most_valuable"`
 	BiggestGainers  []LeaderboardEntry `json:"biggest_gainers"`
 	BiggestLosers   []LeaderboardEntry `json:"biggest_losers"`
 	RichestTraders  []LeaderboardEntry `json:"richest_traders"`
 	BestPerformance []LeaderboardEntry `json:"best_performance"`
+
+	Window      string                       `json:"window"`
+	MyRanks     map[string]*LeaderboardEntry `json:"my_ranks"`
+	NextOffsets map[string]int               `json:"next_offsets"`
+	UpdatedAt   time.Time                    `json:"updated_at"`
 }
 
 type LeaderboardEntry struct {
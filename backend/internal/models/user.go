@@ -20,6 +20,8 @@ type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
 	Password  string `json:"password" binding:"required,min=6"`
 	FirstName string `json:"first_name" binding:"required,min=2,max=15"`
+	// Optional: pick a ticker instead of deriving it from the first name
+	Ticker string `json:"ticker,omitempty" binding:"omitempty,max=15"`
 }
 
 type LoginRequest struct {
@@ -40,10 +42,50 @@ type UserResponse struct {
 	CreatedAt         time.Time  `json:"created_at"`
 }
 
+type ChangeTickerRequest struct {
+	Ticker string `json:"ticker" binding:"required,min=2,max=15"`
+}
+
+type TickerAvailability struct {
+	Ticker      string   `json:"ticker"`
+	Available   bool     `json:"available"`
+	Suggestions []string `json:"suggestions"`
+}
+
 type UpdateProfileRequest struct {
 	Bio string `json:"bio" binding:"max=500"`
 }
 
+// PrivacySettings are a user's privacy preferences. They control what other users see about
+// them; the user's own portfolio and history are unaffected.
+//   - HideTrades keeps their trades out of public trade lists and feeds
+//   - AnonymizeTrades lists their trades as "Anonymous" instead (and keeps them out of feeds,
+//     which would reveal who made them)
+//   - HidePortfolioValue keeps their net worth off leaderboards, season standings, feeds and
+//     their public profile, which shows returns instead of values
+//   - HideHoldings keeps their holdings off their public profile and lists them as
+//     "Anonymous" among a stock's holders
+//
+// With either trade setting, stock owners are told "someone" traded their stock.
+type PrivacySettings struct {
+	HideTrades         bool `json:"hide_trades"`
+	AnonymizeTrades    bool `json:"anonymize_trades"`
+	HidePortfolioValue bool `json:"hide_portfolio_value"`
+	HideHoldings       bool `json:"hide_holdings"`
+}
+
+// AnonymousTrader is shown in place of the username of users who anonymize their trades
+// or hide their holdings.
+const AnonymousTrader = "Anonymous"
+
+// UpdatePrivacyRequest changes only the settings that are present.
+type UpdatePrivacyRequest struct {
+	HideTrades         *bool `json:"hide_trades"`
+	AnonymizeTrades    *bool `json:"anonymize_trades"`
+	HidePortfolioValue *bool `json:"hide_portfolio_value"`
+	HideHoldings       *bool `json:"hide_holdings"`
+}
+
 type PortfolioSnapshot struct {
 	ID          int       `json:"id"`
 	UserID      int       `json:"user_id"`
@@ -51,3 +93,22 @@ type PortfolioSnapshot struct {
 	GrubBalance float64   `json:"grub_balance"`
 	Timestamp   time.Time `json:"timestamp"`
 }
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"` // not needed for SSO-only accounts, which have none
+	Code     string `json:"code" binding:"required"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
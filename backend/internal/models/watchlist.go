@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+type WatchlistItem struct {
+	StockUserID       int       `json:"stock_user_id"`
+	Username          string    `json:"username"`
+	Ticker            string    `json:"ticker"`
+	CurrentSharePrice float64   `json:"current_share_price"`
+	Change24hPercent  float64   `json:"change_24h_percent"`
+	AddedAt           time.Time `json:"added_at"`
+}
+
+type AddToWatchlistRequest struct {
+	Ticker string `json:"ticker" binding:"required"`
+}
+
+// Price alert conditions.
+const (
+	AlertAbove  = "above"  // price rises to or above target_price
+	AlertBelow  = "below"  // price falls to or below target_price
+	AlertChange = "change" // price moves change_percent over window_minutes (negative for drops)
+)
+
+type PriceAlert struct {
+	ID              int        `json:"id"`
+	UserID          int        `json:"-"`
+	StockUserID     int        `json:"stock_user_id"`
+	StockTicker     string     `json:"stock_ticker"`
+	Condition       string     `json:"condition"`
+	TargetPrice     float64    `json:"target_price,omitempty"`
+	ChangePercent   float64    `json:"change_percent,omitempty"`
+	WindowMinutes   int        `json:"window_minutes,omitempty"`
+	OneShot         bool       `json:"one_shot"`
+	Enabled         bool       `json:"enabled"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type CreatePriceAlertRequest struct {
+	Ticker        string  `json:"ticker" binding:"required"`
+	Condition     string  `json:"condition" binding:"required"`
+	TargetPrice   float64 `json:"target_price"`
+	ChangePercent float64 `json:"change_percent"`
+	WindowMinutes int     `json:"window_minutes"`
+	OneShot       *bool   `json:"one_shot"` // defaults to true
+}
+
+type UpdatePriceAlertRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// PriceUpdate describes one stock's price moving, for alert evaluation.
+type PriceUpdate struct {
+	StockUserID int
+	OldPrice    float64
+	NewPrice    float64
+}
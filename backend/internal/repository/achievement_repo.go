@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"grub-exchange/internal/models"
+	"log"
 	"time"
 )
 
@@ -14,12 +15,32 @@ func NewAchievementRepo(db *sql.DB) *AchievementRepo {
 	return &AchievementRepo{db: db}
 }
 
-func (r *AchievementRepo) Award(userID int, achievementID string) error {
-	_, err := r.db.Exec(
+// Award grants an achievement and credits its Grub reward. It reports false without paying
+// anything if the user already had it, so concurrent evaluations can't double-pay.
+func (r *AchievementRepo) Award(userID int, achievementID string, reward float64) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
 		`INSERT INTO user_achievements (user_id, achievement_id, earned_at) VALUES ($1, $2, $3) ON CONFLICT (user_id, achievement_id) DO NOTHING`,
 		userID, achievementID, time.Now(),
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if reward > 0 {
+		if _, err := tx.Exec(`UPDATE balances SET grub_balance = grub_balance + $1 WHERE user_id = $2`, reward, userID); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
 }
 
 func (r *AchievementRepo) HasAchievement(userID int, achievementID string) (bool, error) {
@@ -31,6 +52,60 @@ func (r *AchievementRepo) HasAchievement(userID int, achievementID string) (bool
 	return count > 0, err
 }
 
+// GetEarnedAt maps each achievement the user has earned to when they earned it.
+func (r *AchievementRepo) GetEarnedAt(userID int) (map[string]time.Time, error) {
+	rows, err := r.db.Query(`SELECT achievement_id, earned_at FROM user_achievements WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	earned := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var earnedAt time.Time
+		if err := rows.Scan(&id, &earnedAt); err != nil {
+			return nil, err
+		}
+		earned[id] = earnedAt
+	}
+	return earned, nil
+}
+
+// GetEarnCounts returns how many active users have earned each achievement, along with
+// the number of active users, for rarity percentages.
+func (r *AchievementRepo) GetEarnCounts() (map[string]int, int, error) {
+	var totalUsers int
+	if err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND username != 'MARKET'`,
+	).Scan(&totalUsers); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(
+		`SELECT ua.achievement_id, COUNT(*)
+		 FROM user_achievements ua
+		 JOIN users u ON ua.user_id = u.id
+		 WHERE u.deleted_at IS NULL
+		 GROUP BY ua.achievement_id`,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, 0, err
+		}
+		counts[id] = count
+	}
+	return counts, totalUsers, nil
+}
+
 func (r *AchievementRepo) GetByUser(userID int) ([]models.UserAchievement, error) {
 	rows, err := r.db.Query(
 		`SELECT ua.id, ua.user_id, ua.achievement_id, a.name, a.description, a.icon, ua.earned_at
@@ -56,7 +131,11 @@ func (r *AchievementRepo) GetByUser(userID int) ([]models.UserAchievement, error
 }
 
 func (r *AchievementRepo) GetAll() ([]models.Achievement, error) {
-	rows, err := r.db.Query(`SELECT id, name, description, icon FROM achievements ORDER BY id`)
+	rows, err := r.db.Query(
+		`SELECT id, name, description, icon, COALESCE(event_type, ''), COALESCE(metric, 'event_count'),
+		        COALESCE(threshold, 1), COALESCE(window_hours, 0), COALESCE(reward, 0)
+		 FROM achievements ORDER BY id`,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +144,8 @@ func (r *AchievementRepo) GetAll() ([]models.Achievement, error) {
 	var achievements []models.Achievement
 	for rows.Next() {
 		var a models.Achievement
-		if err := rows.Scan(&a.ID, &a.Name, &a.Description, &a.Icon); err != nil {
+		if err := rows.Scan(&a.ID, &a.Name, &a.Description, &a.Icon, &a.EventType, &a.Metric,
+			&a.Threshold, &a.WindowHours, &a.Reward); err != nil {
 			return nil, err
 		}
 		achievements = append(achievements, a)
@@ -73,24 +153,60 @@ func (r *AchievementRepo) GetAll() ([]models.Achievement, error) {
 	return achievements, nil
 }
 
-// GetTradeCountToday returns the number of trades a user made today
-func (r *AchievementRepo) GetTradeCountToday(userID int) (int, error) {
+// RecordEvent stores an activity event for achievement evaluation.
+func (r *AchievementRepo) RecordEvent(userID int, eventType string, value float64) error {
+	_, err := r.db.Exec(
+		`INSERT INTO achievement_events (user_id, event_type, value) VALUES ($1, $2, $3)`,
+		userID, eventType, value,
+	)
+	return err
+}
+
+// AggregateEvents returns the count and summed value of a user's events of one type since a time.
+func (r *AchievementRepo) AggregateEvents(userID int, eventType string, since time.Time) (int, float64, error) {
 	var count int
+	var sum float64
 	err := r.db.QueryRow(
-		`SELECT COUNT(*) FROM transactions WHERE buyer_id = $1 AND timestamp >= CURRENT_DATE`,
-		userID,
-	).Scan(&count)
-	return count, err
+		`SELECT COUNT(*), COALESCE(SUM(value), 0) FROM achievement_events
+		 WHERE user_id = $1 AND event_type = $2 AND created_at >= $3`,
+		userID, eventType, since,
+	).Scan(&count, &sum)
+	return count, sum, err
+}
+
+// BackfillEvents seeds achievement_events from existing trades, posts and votes so progress
+// reflects activity from before the engine existed. Only runs if the table is empty.
+// Daily claims and dividends were never recorded per event, so they start from zero.
+func (r *AchievementRepo) BackfillEvents() {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM achievement_events`).Scan(&count); err != nil || count > 0 {
+		return
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO achievement_events (user_id, event_type, value, created_at)
+		SELECT buyer_id, 'trade', total_grub, timestamp FROM transactions WHERE transaction_type IN ('BUY', 'SELL')
+		UNION ALL
+		SELECT author_id, 'post', 1, created_at FROM stock_posts
+		UNION ALL
+		SELECT user_id, 'vote', 1, created_at FROM post_votes
+	`)
+	if err != nil {
+		log.Printf("Error backfilling achievement events: %v", err)
+		return
+	}
+	rows, _ := result.RowsAffected()
+	log.Printf("Backfilled %d achievement events from historical data", rows)
 }
 
-// GetOldestHoldingDays returns the age in days of the user's oldest holding
+// GetOldestHoldingDays returns the age in days of the user's oldest holding, counted from
+// when the position was last opened, as in the holder registry
 func (r *AchievementRepo) GetOldestHoldingDays(userID int) (int, error) {
 	var days sql.NullInt64
 	err := r.db.QueryRow(
-		`SELECT EXTRACT(DAY FROM NOW() - MIN(t.timestamp))::INTEGER
-		 FROM portfolios p
-		 JOIN transactions t ON t.buyer_id = p.owner_id AND t.stock_user_id = p.stock_user_id AND t.transaction_type = 'BUY'
-		 WHERE p.owner_id = $1 AND p.num_shares > 0`,
+		`SELECT EXTRACT(DAY FROM NOW() - MIN(opened_at))::INTEGER
+		 FROM portfolios
+		 WHERE owner_id = $1 AND num_shares > 0`,
 		userID,
 	).Scan(&days)
 	if err != nil || !days.Valid {
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"database/sql"
+	"grub-exchange/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// AlertRepo stores watchlists and price alerts.
+type AlertRepo struct {
+	db *sql.DB
+}
+
+func NewAlertRepo(db *sql.DB) *AlertRepo {
+	return &AlertRepo{db: db}
+}
+
+func (r *AlertRepo) AddToWatchlist(userID, stockUserID int) error {
+	_, err := r.db.Exec(
+		`INSERT INTO watchlists (user_id, stock_user_id) VALUES ($1, $2) ON CONFLICT (user_id, stock_user_id) DO NOTHING`,
+		userID, stockUserID,
+	)
+	return err
+}
+
+func (r *AlertRepo) RemoveFromWatchlist(userID, stockUserID int) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM watchlists WHERE user_id = $1 AND stock_user_id = $2`, userID, stockUserID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *AlertRepo) CountWatchlist(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM watchlists WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// GetWatchlist returns watched stocks, most recently added first. 24h change is filled in by the caller.
+func (r *AlertRepo) GetWatchlist(userID int) ([]models.WatchlistItem, error) {
+	rows, err := r.db.Query(
+		`SELECT u.id, u.username, u.ticker, u.current_share_price, w.created_at
+		 FROM watchlists w
+		 JOIN users u ON w.stock_user_id = u.id
+		 WHERE w.user_id = $1 AND u.deleted_at IS NULL
+		 ORDER BY w.created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.WatchlistItem
+	for rows.Next() {
+		var item models.WatchlistItem
+		if err := rows.Scan(&item.StockUserID, &item.Username, &item.Ticker, &item.CurrentSharePrice, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+const alertSelect = `SELECT a.id, a.user_id, a.stock_user_id, u.ticker, a.condition, a.target_price, a.change_percent,
+	        a.window_minutes, a.one_shot, a.enabled, a.last_triggered_at, a.created_at
+	 FROM price_alerts a
+	 JOIN users u ON a.stock_user_id = u.id`
+
+func scanAlerts(rows *sql.Rows) ([]models.PriceAlert, error) {
+	defer rows.Close()
+
+	var alerts []models.PriceAlert
+	for rows.Next() {
+		var a models.PriceAlert
+		var lastTriggered sql.NullTime
+		if err := rows.Scan(&a.ID, &a.UserID, &a.StockUserID, &a.StockTicker, &a.Condition, &a.TargetPrice,
+			&a.ChangePercent, &a.WindowMinutes, &a.OneShot, &a.Enabled, &lastTriggered, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if lastTriggered.Valid {
+			a.LastTriggeredAt = &lastTriggered.Time
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+func (r *AlertRepo) CreateAlert(a *models.PriceAlert) error {
+	return r.db.QueryRow(
+		`INSERT INTO price_alerts (user_id, stock_user_id, condition, target_price, change_percent, window_minutes, one_shot)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, enabled, created_at`,
+		a.UserID, a.StockUserID, a.Condition, a.TargetPrice, a.ChangePercent, a.WindowMinutes, a.OneShot,
+	).Scan(&a.ID, &a.Enabled, &a.CreatedAt)
+}
+
+func (r *AlertRepo) GetAlertsByUser(userID int) ([]models.PriceAlert, error) {
+	rows, err := r.db.Query(alertSelect+` WHERE a.user_id = $1 ORDER BY a.created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlerts(rows)
+}
+
+func (r *AlertRepo) CountAlerts(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM price_alerts WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// GetEnabledAlertsForStocks returns the enabled alerts on any of the given stocks.
+func (r *AlertRepo) GetEnabledAlertsForStocks(stockUserIDs []int) ([]models.PriceAlert, error) {
+	rows, err := r.db.Query(alertSelect+` WHERE a.enabled AND a.stock_user_id = ANY($1)`, pq.Array(stockUserIDs))
+	if err != nil {
+		return nil, err
+	}
+	return scanAlerts(rows)
+}
+
+// SetAlertEnabled turns an alert on or off. Re-enabling clears the cooldown.
+func (r *AlertRepo) SetAlertEnabled(alertID, userID int, enabled bool) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE price_alerts SET enabled = $1, last_triggered_at = CASE WHEN $1 THEN NULL ELSE last_triggered_at END
+		 WHERE id = $2 AND user_id = $3`,
+		enabled, alertID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *AlertRepo) DeleteAlert(alertID, userID int) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM price_alerts WHERE id = $1 AND user_id = $2`, alertID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// MarkTriggered records that an alert fired, disabling it if it's one-shot. It only succeeds
+// for an enabled alert, so concurrent price updates can't fire a one-shot alert twice.
+func (r *AlertRepo) MarkTriggered(alertID int) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE price_alerts SET last_triggered_at = NOW(), enabled = NOT one_shot
+		 WHERE id = $1 AND enabled`,
+		alertID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
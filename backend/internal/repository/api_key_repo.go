@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"database/sql"
+	"grub-exchange/internal/models"
+	"strings"
+	"time"
+)
+
+type APIKeyRepo struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepo(db *sql.DB) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+func (r *APIKeyRepo) Create(userID int, name, prefix, keyHash string, scopes []string, rateLimit int) (*models.APIKey, error) {
+	k := &models.APIKey{}
+	var scopeStr string
+	err := r.db.QueryRow(
+		`INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, rate_limit_per_minute, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, user_id, name, prefix, scopes, rate_limit_per_minute, last_used_at, revoked_at, created_at`,
+		userID, name, prefix, keyHash, strings.Join(scopes, ","), rateLimit, time.Now(),
+	).Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopeStr, &k.RateLimitPerMinute, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	k.Scopes = splitScopes(scopeStr)
+	return k, nil
+}
+
+// GetByUser returns all of a user's keys, including revoked ones, newest first.
+func (r *APIKeyRepo) GetByUser(userID int) ([]models.APIKey, error) {
+	rows, err := r.db.Query(
+		`SELECT id, user_id, name, prefix, scopes, rate_limit_per_minute, last_used_at, revoked_at, created_at
+		 FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		var scopeStr string
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopeStr, &k.RateLimitPerMinute,
+			&k.LastUsedAt, &k.RevokedAt, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		k.Scopes = splitScopes(scopeStr)
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepo) CountActiveByUser(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+	).Scan(&count)
+	return count, err
+}
+
+// GetOwnerByHash resolves an active (non-revoked) key to its owner, if the owner's account
+// is still open.
+func (r *APIKeyRepo) GetOwnerByHash(keyHash string) (*models.APIKeyOwner, error) {
+	o := &models.APIKeyOwner{}
+	var scopeStr string
+	err := r.db.QueryRow(
+		`SELECT k.id, k.user_id, u.username, k.scopes, k.rate_limit_per_minute
+		 FROM api_keys k
+		 JOIN users u ON k.user_id = u.id
+		 WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.deleted_at IS NULL`,
+		keyHash,
+	).Scan(&o.KeyID, &o.UserID, &o.Username, &scopeStr, &o.RateLimitPerMinute)
+	if err != nil {
+		return nil, err
+	}
+	o.Scopes = splitScopes(scopeStr)
+	return o, nil
+}
+
+// TouchLastUsed updates last_used_at, at most once a minute per key to avoid a write on every request.
+func (r *APIKeyRepo) TouchLastUsed(keyID int) error {
+	now := time.Now()
+	_, err := r.db.Exec(
+		`UPDATE api_keys SET last_used_at = $1
+		 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`,
+		now, keyID, now.Add(-time.Minute),
+	)
+	return err
+}
+
+// Revoke marks a key as revoked. Returns false if the key doesn't exist, isn't the user's, or was already revoked.
+func (r *APIKeyRepo) Revoke(keyID, userID int) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`,
+		time.Now(), keyID, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func splitScopes(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
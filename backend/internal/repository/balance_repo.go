@@ -33,6 +33,18 @@ func (r *BalanceRepo) GetByUserID(userID int) (*models.Balance, error) {
 	return balance, nil
 }
 
+// LockByUserID returns a user's balance and locks it until tx ends.
+func (r *BalanceRepo) LockByUserID(tx *sql.Tx, userID int) (*models.Balance, error) {
+	balance := &models.Balance{}
+	err := tx.QueryRow(
+		`SELECT user_id, grub_balance, last_daily_claim FROM balances WHERE user_id = $1 FOR UPDATE`, userID,
+	).Scan(&balance.UserID, &balance.GrubBalance, &balance.LastDailyClaim)
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
 func (r *BalanceRepo) UpdateBalance(tx *sql.Tx, userID int, amount float64) error {
 	_, err := tx.Exec(
 		`UPDATE balances SET grub_balance = grub_balance + $1 WHERE user_id = $2`,
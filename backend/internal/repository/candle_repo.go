@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"grub-exchange/internal/models"
+	"time"
+)
+
+// candleWatermark names the job_watermarks row recording how far price_candles is rolled up.
+const candleWatermark = "price_candles"
+
+// candleAggregateQuery aggregates price_history and transactions into candles of $3 seconds,
+// aligned to the Unix epoch, over the rows with timestamps in ($1, $2]. The %s placeholders
+// take extra conditions on price_history and transactions. Prices and trades are bucketed
+// separately, so every trade counts toward volume even if its interval has no recorded
+// price; such intervals take their prices from the trades' execution prices. Only buys and
+// sells are trades: delisting payouts aren't volume.
+const candleAggregateQuery = `
+	SELECT COALESCE(p.user_id, t.stock_user_id) AS user_id, COALESCE(p.bucket, t.bucket) AS bucket,
+	       COALESCE(p.open, t.open) AS open, COALESCE(p.high, t.high) AS high,
+	       COALESCE(p.low, t.low) AS low, COALESCE(p.close, t.close) AS close,
+	       COALESCE(t.volume, 0) AS volume, COALESCE(t.trades, 0) AS trades
+	FROM (
+	    SELECT user_id, to_timestamp(FLOOR(EXTRACT(EPOCH FROM timestamp) / $3) * $3) AS bucket,
+	           (ARRAY_AGG(price ORDER BY timestamp, id))[1] AS open,
+	           MAX(price) AS high, MIN(price) AS low,
+	           (ARRAY_AGG(price ORDER BY timestamp DESC, id DESC))[1] AS close
+	    FROM price_history
+	    WHERE timestamp > $1 AND timestamp <= $2%s
+	    GROUP BY 1, 2
+	) p
+	FULL JOIN (
+	    SELECT stock_user_id, to_timestamp(FLOOR(EXTRACT(EPOCH FROM timestamp) / $3) * $3) AS bucket,
+	           (ARRAY_AGG(price_per_share ORDER BY timestamp, id))[1] AS open,
+	           MAX(price_per_share) AS high, MIN(price_per_share) AS low,
+	           (ARRAY_AGG(price_per_share ORDER BY timestamp DESC, id DESC))[1] AS close,
+	           SUM(num_shares) AS volume, COUNT(*) AS trades
+	    FROM transactions
+	    WHERE timestamp > $1 AND timestamp <= $2 AND transaction_type IN ('BUY', 'SELL')%s
+	    GROUP BY 1, 2
+	) t ON t.stock_user_id = p.user_id AND t.bucket = p.bucket`
+
+type CandleRepo struct {
+	db *sql.DB
+}
+
+func NewCandleRepo(db *sql.DB) *CandleRepo {
+	return &CandleRepo{db: db}
+}
+
+// GetWatermark returns the time up to which price_candles is complete, or the zero time if
+// nothing has been rolled up yet.
+func (r *CandleRepo) GetWatermark() (time.Time, error) {
+	return getWatermark(r.db, candleWatermark)
+}
+
+// RollUp folds the price history and trades recorded after the watermark, up to upTo but
+// at most maxSpan past the watermark, into price_candles at every interval, and advances
+// the watermark. The watermark row is locked for the duration, so concurrent roll-ups
+// don't count rows twice. It returns the new watermark and whether anything was rolled up.
+func (r *CandleRepo) RollUp(intervals map[string]time.Duration, upTo time.Time, maxSpan time.Duration) (time.Time, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT INTO job_watermarks (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`,
+		candleWatermark,
+	); err != nil {
+		return time.Time{}, false, err
+	}
+	var watermark sql.NullTime
+	if err := tx.QueryRow(
+		`SELECT watermark FROM job_watermarks WHERE name = $1 FOR UPDATE`,
+		candleWatermark,
+	).Scan(&watermark); err != nil {
+		return time.Time{}, false, err
+	}
+
+	from := watermark.Time
+	if !watermark.Valid {
+		// First run: start just before the oldest recorded price
+		var oldest sql.NullTime
+		if err := tx.QueryRow(`SELECT MIN(timestamp) FROM price_history`).Scan(&oldest); err != nil {
+			return time.Time{}, false, err
+		}
+		if !oldest.Valid {
+			return time.Time{}, false, nil
+		}
+		from = oldest.Time.Add(-time.Microsecond)
+	}
+
+	to := upTo
+	if to.Sub(from) > maxSpan {
+		to = from.Add(maxSpan)
+	}
+	if !to.After(from) {
+		return from, false, nil
+	}
+
+	upsert := `INSERT INTO price_candles (stock_user_id, resolution, bucket_start, open, high, low, close, volume, trades)
+		SELECT c.user_id, $4::varchar, c.bucket, c.open, c.high, c.low, c.close, c.volume, c.trades
+		FROM (` + fmt.Sprintf(candleAggregateQuery, "", "") + `) c
+		ON CONFLICT (stock_user_id, resolution, bucket_start) DO UPDATE SET
+		    high = GREATEST(price_candles.high, EXCLUDED.high),
+		    low = LEAST(price_candles.low, EXCLUDED.low),
+		    close = EXCLUDED.close,
+		    volume = price_candles.volume + EXCLUDED.volume,
+		    trades = price_candles.trades + EXCLUDED.trades`
+	for name, interval := range intervals {
+		if _, err := tx.Exec(upsert, from, to, int(interval.Seconds()), name); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+
+	if err := setWatermark(tx, candleWatermark, to); err != nil {
+		return time.Time{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, false, err
+	}
+	return to, true, nil
+}
+
+// GetCandles returns a stock's rolled-up candles starting in [from, to), oldest first.
+func (r *CandleRepo) GetCandles(stockUserID int, interval string, from, to time.Time) ([]models.Candle, error) {
+	rows, err := r.db.Query(
+		`SELECT bucket_start, open, high, low, close, volume, trades
+		 FROM price_candles
+		 WHERE stock_user_id = $1 AND resolution = $2 AND bucket_start >= $3 AND bucket_start < $4
+		 ORDER BY bucket_start`,
+		stockUserID, interval, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candles []models.Candle
+	for rows.Next() {
+		var c models.Candle
+		if err := rows.Scan(&c.Time, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &c.Trades); err != nil {
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
+
+// AggregateCandles computes a stock's candles straight from price_history and transactions
+// for the rows with timestamps in (after, to], oldest first. Used for the stretch after the
+// watermark that hasn't been rolled up yet.
+func (r *CandleRepo) AggregateCandles(stockUserID int, interval time.Duration, after, to time.Time) ([]models.Candle, error) {
+	rows, err := r.db.Query(
+		fmt.Sprintf(candleAggregateQuery, " AND user_id = $4", " AND stock_user_id = $4")+` ORDER BY bucket`,
+		after, to, int(interval.Seconds()), stockUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candles []models.Candle
+	for rows.Next() {
+		var c models.Candle
+		var userID int
+		if err := rows.Scan(&userID, &c.Time, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &c.Trades); err != nil {
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"database/sql"
+	"grub-exchange/internal/models"
+)
+
+type FollowRepo struct {
+	db *sql.DB
+}
+
+func NewFollowRepo(db *sql.DB) *FollowRepo {
+	return &FollowRepo{db: db}
+}
+
+// Follow records that followerID follows followeeID. It reports false if they already did.
+func (r *FollowRepo) Follow(followerID, followeeID int) (bool, error) {
+	result, err := r.db.Exec(
+		`INSERT INTO follows (follower_id, followee_id) VALUES ($1, $2)
+		 ON CONFLICT (follower_id, followee_id) DO NOTHING`,
+		followerID, followeeID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *FollowRepo) Unfollow(followerID, followeeID int) (bool, error) {
+	result, err := r.db.Exec(
+		`DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2`,
+		followerID, followeeID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetCounts returns how many active users follow someone and how many they follow.
+func (r *FollowRepo) GetCounts(userID int) (followers, following int, err error) {
+	err = r.db.QueryRow(
+		`SELECT
+		     (SELECT COUNT(*) FROM follows f JOIN users u ON f.follower_id = u.id
+		      WHERE f.followee_id = $1 AND u.deleted_at IS NULL),
+		     (SELECT COUNT(*) FROM follows f JOIN users u ON f.followee_id = u.id
+		      WHERE f.follower_id = $1 AND u.deleted_at IS NULL)`,
+		userID,
+	).Scan(&followers, &following)
+	return followers, following, err
+}
+
+func (r *FollowRepo) IsFollowing(followerID, followeeID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2)`,
+		followerID, followeeID,
+	).Scan(&exists)
+	return exists, err
+}
+
+// GetFollowing returns the active users someone follows, most recently followed first.
+func (r *FollowRepo) GetFollowing(userID int) ([]models.FollowUser, error) {
+	return r.queryFollowUsers(
+		`SELECT u.id, u.username, u.ticker, u.current_share_price, f.created_at
+		 FROM follows f
+		 JOIN users u ON f.followee_id = u.id
+		 WHERE f.follower_id = $1 AND u.deleted_at IS NULL
+		 ORDER BY f.created_at DESC`,
+		userID,
+	)
+}
+
+// GetFollowers returns the active users following someone, most recent first.
+func (r *FollowRepo) GetFollowers(userID int) ([]models.FollowUser, error) {
+	return r.queryFollowUsers(
+		`SELECT u.id, u.username, u.ticker, u.current_share_price, f.created_at
+		 FROM follows f
+		 JOIN users u ON f.follower_id = u.id
+		 WHERE f.followee_id = $1 AND u.deleted_at IS NULL
+		 ORDER BY f.created_at DESC`,
+		userID,
+	)
+}
+
+func (r *FollowRepo) queryFollowUsers(query string, userID int) ([]models.FollowUser, error) {
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.FollowUser
+	for rows.Next() {
+		var u models.FollowUser
+		if err := rows.Scan(&u.UserID, &u.Username, &u.Ticker, &u.CurrentSharePrice, &u.FollowedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// feedQuery merges the followed users' activity into one stream with a common column layout:
+// kind, id, user_id, stock_ticker, three text and three numeric detail columns, created_at.
+// Big moves compare each completed day's last portfolio snapshot with the previous day's and
+// are stamped at the end of the day, so they don't shift while the day is still running.
+// Trades of users who hide or anonymize them, and big moves of users who hide their
+// portfolio value, are left out.
+const feedQuery = `WITH followed AS (
+	    SELECT followee_id AS user_id FROM follows WHERE follower_id = $1
+	)
+	SELECT e.kind, e.id, e.user_id, u.username, u.ticker, e.stock_ticker,
+	       e.text1, e.text2, e.text3, e.num1, e.num2, e.num3, e.created_at
+	FROM (
+	    SELECT 'trade' AS kind, t.id, t.buyer_id AS user_id, su.ticker AS stock_ticker,
+	           t.transaction_type AS text1, '' AS text2, '' AS text3,
+	           t.num_shares AS num1, t.price_per_share AS num2, t.total_grub AS num3, t.timestamp AS created_at
+	    FROM transactions t
+	    JOIN users su ON t.stock_user_id = su.id
+	    WHERE t.buyer_id IN (SELECT user_id FROM followed)
+	      AND t.transaction_type IN ('BUY', 'SELL')
+	      AND NOT EXISTS (SELECT 1 FROM privacy_settings ps
+	                      WHERE ps.user_id = t.buyer_id AND (ps.hide_trades OR ps.anonymize_trades))
+
+	    UNION ALL
+
+	    SELECT 'post', p.id, p.author_id, su.ticker, p.content, '', '', 0, 0, 0, p.created_at
+	    FROM stock_posts p
+	    JOIN users su ON p.stock_user_id = su.id
+	    WHERE p.author_id IN (SELECT user_id FROM followed) AND p.league_id IS NULL AND p.parent_id IS NULL
+	      AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+
+	    UNION ALL
+
+	    SELECT 'achievement', ua.id, ua.user_id, '', a.id, a.name, a.icon, 0, 0, 0, ua.earned_at
+	    FROM user_achievements ua
+	    JOIN achievements a ON ua.achievement_id = a.id
+	    WHERE ua.user_id IN (SELECT user_id FROM followed)
+
+	    UNION ALL
+
+	    SELECT 'big_move', c.id, c.user_id, '', '', '', '', c.prev_value, c.total_value,
+	           (c.total_value - c.prev_value) / c.prev_value * 100, c.day + INTERVAL '1 day'
+	    FROM (
+	        SELECT id, user_id, day, total_value,
+	               LAG(total_value) OVER (PARTITION BY user_id ORDER BY day) AS prev_value
+	        FROM (
+	            SELECT DISTINCT ON (user_id, date_trunc('day', timestamp))
+	                   id, user_id, date_trunc('day', timestamp) AS day, total_value
+	            FROM portfolio_snapshots
+	            WHERE user_id IN (SELECT user_id FROM followed)
+	              AND NOT EXISTS (SELECT 1 FROM privacy_settings ps
+	                              WHERE ps.user_id = portfolio_snapshots.user_id AND ps.hide_portfolio_value)
+	              AND timestamp >= date_trunc('day', NOW()) - INTERVAL '31 days'
+	              AND timestamp < date_trunc('day', NOW())
+	            ORDER BY user_id, date_trunc('day', timestamp), timestamp DESC
+	        ) closes
+	    ) c
+	    WHERE c.prev_value > 0 AND ABS(c.total_value - c.prev_value) / c.prev_value * 100 >= $2
+	) e
+	JOIN users u ON e.user_id = u.id
+	WHERE u.deleted_at IS NULL`
+
+// GetFeed returns up to limit feed items for the user's follows, newest first, starting
+// after the given cursor (or from the newest item if it's nil).
+func (r *FollowRepo) GetFeed(userID int, before *models.FeedCursor, limit int) ([]models.FeedItem, error) {
+	query := feedQuery
+	args := []interface{}{userID, models.BigMovePercent, limit}
+	if before != nil {
+		query += ` AND (e.created_at, e.kind, e.id) < ($4::timestamptz, $5::text, $6::integer)`
+		args = append(args, before.CreatedAt, before.Type, before.ID)
+	}
+	query += ` ORDER BY e.created_at DESC, e.kind DESC, e.id DESC LIMIT $3`
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.FeedItem
+	for rows.Next() {
+		var item models.FeedItem
+		var text1, text2, text3 string
+		var num1, num2, num3 float64
+		if err := rows.Scan(&item.Type, &item.ID, &item.UserID, &item.Username, &item.Ticker, &item.StockTicker,
+			&text1, &text2, &text3, &num1, &num2, &num3, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		switch item.Type {
+		case models.FeedTrade:
+			item.TransactionType = text1
+			item.NumShares, item.PricePerShare, item.TotalGrub = num1, num2, num3
+		case models.FeedPost:
+			item.Content = text1
+		case models.FeedAchievement:
+			item.AchievementID, item.AchievementName, item.AchievementIcon = text1, text2, text3
+		case models.FeedBigMove:
+			item.PreviousValue, item.Value, item.ChangePercent = num1, num2, num3
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"database/sql"
+	"grub-exchange/internal/models"
+)
+
+type LeagueRepo struct {
+	db *sql.DB
+}
+
+func NewLeagueRepo(db *sql.DB) *LeagueRepo {
+	return &LeagueRepo{db: db}
+}
+
+// Create makes a league and adds the owner as its first admin.
+func (r *LeagueRepo) Create(name, inviteCode string, ownerID int) (*models.League, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	league := &models.League{Name: name, InviteCode: inviteCode, OwnerID: ownerID, MemberCount: 1, MyRole: models.LeagueRoleAdmin}
+	err = tx.QueryRow(
+		`INSERT INTO leagues (name, invite_code, owner_id) VALUES ($1, $2, $3) RETURNING id, created_at`,
+		name, inviteCode, ownerID,
+	).Scan(&league.ID, &league.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO league_members (league_id, user_id, role) VALUES ($1, $2, $3)`,
+		league.ID, ownerID, models.LeagueRoleAdmin,
+	); err != nil {
+		return nil, err
+	}
+	return league, tx.Commit()
+}
+
+func (r *LeagueRepo) GetByID(leagueID int) (*models.League, error) {
+	league := &models.League{}
+	err := r.db.QueryRow(
+		`SELECT l.id, l.name, l.invite_code, l.owner_id, l.created_at,
+		        (SELECT COUNT(*) FROM league_members m WHERE m.league_id = l.id AND m.kicked_at IS NULL)
+		 FROM leagues l WHERE l.id = $1`,
+		leagueID,
+	).Scan(&league.ID, &league.Name, &league.InviteCode, &league.OwnerID, &league.CreatedAt, &league.MemberCount)
+	if err != nil {
+		return nil, err
+	}
+	return league, nil
+}
+
+func (r *LeagueRepo) GetIDByInviteCode(inviteCode string) (int, error) {
+	var id int
+	err := r.db.QueryRow(`SELECT id FROM leagues WHERE invite_code = $1`, inviteCode).Scan(&id)
+	return id, err
+}
+
+// GetForUser returns the leagues a user belongs to, with their role in each.
+func (r *LeagueRepo) GetForUser(userID int) ([]models.League, error) {
+	rows, err := r.db.Query(
+		`SELECT l.id, l.name, l.invite_code, l.owner_id, l.created_at, me.role,
+		        (SELECT COUNT(*) FROM league_members m WHERE m.league_id = l.id AND m.kicked_at IS NULL)
+		 FROM league_members me
+		 JOIN leagues l ON me.league_id = l.id
+		 WHERE me.user_id = $1 AND me.kicked_at IS NULL
+		 ORDER BY l.name`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leagues []models.League
+	for rows.Next() {
+		var l models.League
+		if err := rows.Scan(&l.ID, &l.Name, &l.InviteCode, &l.OwnerID, &l.CreatedAt, &l.MyRole, &l.MemberCount); err != nil {
+			return nil, err
+		}
+		leagues = append(leagues, l)
+	}
+	return leagues, nil
+}
+
+// GetMembership returns a user's role in a league and whether they were kicked.
+// It returns sql.ErrNoRows if they never joined.
+func (r *LeagueRepo) GetMembership(leagueID, userID int) (role string, kicked bool, err error) {
+	err = r.db.QueryRow(
+		`SELECT role, kicked_at IS NOT NULL FROM league_members WHERE league_id = $1 AND user_id = $2`,
+		leagueID, userID,
+	).Scan(&role, &kicked)
+	return role, kicked, err
+}
+
+func (r *LeagueRepo) GetMembers(leagueID int) ([]models.LeagueMember, error) {
+	rows, err := r.db.Query(
+		`SELECT m.user_id, u.username, u.ticker, m.role, m.joined_at
+		 FROM league_members m
+		 JOIN users u ON m.user_id = u.id
+		 WHERE m.league_id = $1 AND m.kicked_at IS NULL
+		 ORDER BY m.joined_at`,
+		leagueID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.LeagueMember
+	for rows.Next() {
+		var m models.LeagueMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Ticker, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// GetMemberIDs returns the set of current member IDs, for filtering leaderboards.
+func (r *LeagueRepo) GetMemberIDs(leagueID int) (map[int]bool, error) {
+	rows, err := r.db.Query(
+		`SELECT user_id FROM league_members WHERE league_id = $1 AND kicked_at IS NULL`,
+		leagueID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+func (r *LeagueRepo) AddMember(leagueID, userID int) error {
+	_, err := r.db.Exec(
+		`INSERT INTO league_members (league_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (league_id, user_id) DO NOTHING`,
+		leagueID, userID, models.LeagueRoleMember,
+	)
+	return err
+}
+
+// Kick removes a non-admin member and keeps them from rejoining.
+func (r *LeagueRepo) Kick(leagueID, userID int) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE league_members SET kicked_at = NOW()
+		 WHERE league_id = $1 AND user_id = $2 AND role = $3 AND kicked_at IS NULL`,
+		leagueID, userID, models.LeagueRoleMember,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Leave removes a member. If they were the last admin, the longest-standing member is promoted
+// and takes over ownership so the league is never left without an admin.
+func (r *LeagueRepo) Leave(tx *sql.Tx, leagueID, userID int) error {
+	if _, err := tx.Exec(
+		`DELETE FROM league_members WHERE league_id = $1 AND user_id = $2 AND kicked_at IS NULL`,
+		leagueID, userID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE league_members SET role = $2
+		 WHERE league_id = $1
+		   AND user_id = (SELECT user_id FROM league_members WHERE league_id = $1 AND kicked_at IS NULL ORDER BY joined_at LIMIT 1)
+		   AND NOT EXISTS (SELECT 1 FROM league_members WHERE league_id = $1 AND role = $2 AND kicked_at IS NULL)`,
+		leagueID, models.LeagueRoleAdmin,
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(
+		`UPDATE leagues SET owner_id = (
+		     SELECT user_id FROM league_members WHERE league_id = $1 AND role = $2 AND kicked_at IS NULL
+		     ORDER BY joined_at LIMIT 1)
+		 WHERE id = $1 AND owner_id = $3
+		   AND EXISTS (SELECT 1 FROM league_members WHERE league_id = $1 AND role = $2 AND kicked_at IS NULL)`,
+		leagueID, models.LeagueRoleAdmin, userID,
+	)
+	return err
+}
+
+// LeaveAll removes a user from every league they belong to, e.g. when their account closes.
+func (r *LeagueRepo) LeaveAll(tx *sql.Tx, userID int) error {
+	rows, err := tx.Query(`SELECT league_id FROM league_members WHERE user_id = $1 AND kicked_at IS NULL`, userID)
+	if err != nil {
+		return err
+	}
+	var leagueIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		leagueIDs = append(leagueIDs, id)
+	}
+	rows.Close()
+
+	for _, id := range leagueIDs {
+		if err := r.Leave(tx, id, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *LeagueRepo) SetInviteCode(leagueID int, inviteCode string) error {
+	_, err := r.db.Exec(`UPDATE leagues SET invite_code = $1 WHERE id = $2`, inviteCode, leagueID)
+	return err
+}
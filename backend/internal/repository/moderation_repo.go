@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"database/sql"
+	"grub-exchange/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type ModerationRepo struct {
+	db *sql.DB
+}
+
+func NewModerationRepo(db *sql.DB) *ModerationRepo {
+	return &ModerationRepo{db: db}
+}
+
+// CreateReport records a user's report of a post. It reports false if they already reported it.
+func (r *ModerationRepo) CreateReport(postID, reporterID int, reason, details string) (bool, error) {
+	result, err := r.db.Exec(
+		`INSERT INTO post_reports (post_id, reporter_id, reason, details) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (post_id, reporter_id) DO NOTHING`,
+		postID, reporterID, reason, details,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *ModerationRepo) CountOpenReports(postID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM post_reports WHERE post_id = $1 AND resolved_at IS NULL`, postID,
+	).Scan(&count)
+	return count, err
+}
+
+// GetQueue returns posts with open reports, most reported first.
+func (r *ModerationRepo) GetQueue(limit int) ([]models.ReportedPost, error) {
+	rows, err := r.db.Query(
+		`SELECT p.id, p.author_id, u.username, su.ticker, p.content, p.created_at, p.hidden_at IS NOT NULL,
+		        COUNT(*), array_agg(DISTINCT r.reason),
+		        array_remove(array_agg(r.details ORDER BY r.created_at), ''), MAX(r.created_at)
+		 FROM post_reports r
+		 JOIN stock_posts p ON r.post_id = p.id
+		 JOIN users u ON p.author_id = u.id
+		 JOIN users su ON p.stock_user_id = su.id
+		 WHERE r.resolved_at IS NULL AND p.deleted_at IS NULL
+		 GROUP BY p.id, u.username, su.ticker
+		 ORDER BY COUNT(*) DESC, MAX(r.created_at) DESC
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queue []models.ReportedPost
+	for rows.Next() {
+		var p models.ReportedPost
+		if err := rows.Scan(&p.PostID, &p.AuthorID, &p.AuthorUsername, &p.StockTicker, &p.Content, &p.CreatedAt,
+			&p.Hidden, &p.ReportCount, pq.Array(&p.Reasons), pq.Array(&p.Details), &p.LastReportedAt); err != nil {
+			return nil, err
+		}
+		queue = append(queue, p)
+	}
+	return queue, nil
+}
+
+// HidePost hides a post from everyone. It reports false if the post doesn't exist or is
+// already hidden or deleted.
+func (r *ModerationRepo) HidePost(tx *sql.Tx, postID int) (bool, error) {
+	result, err := tx.Exec(
+		`UPDATE stock_posts SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL AND deleted_at IS NULL`,
+		postID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// RestorePost makes a hidden post visible again. It reports false if it wasn't hidden.
+func (r *ModerationRepo) RestorePost(tx *sql.Tx, postID int) (bool, error) {
+	result, err := tx.Exec(
+		`UPDATE stock_posts SET hidden_at = NULL WHERE id = $1 AND hidden_at IS NOT NULL`,
+		postID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ResolveReports closes a post's open reports, taking it off the queue.
+func (r *ModerationRepo) ResolveReports(tx *sql.Tx, postID int) error {
+	_, err := tx.Exec(
+		`UPDATE post_reports SET resolved_at = NOW() WHERE post_id = $1 AND resolved_at IS NULL`,
+		postID,
+	)
+	return err
+}
+
+// SetBanned bans or unbans a user. It reports false if nothing changed.
+func (r *ModerationRepo) SetBanned(tx *sql.Tx, userID int, banned bool) (bool, error) {
+	query := `UPDATE users SET banned_at = NOW() WHERE id = $1 AND banned_at IS NULL`
+	if !banned {
+		query = `UPDATE users SET banned_at = NULL WHERE id = $1 AND banned_at IS NOT NULL`
+	}
+	result, err := tx.Exec(query, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *ModerationRepo) IsBanned(userID int) (bool, error) {
+	var banned bool
+	err := r.db.QueryRow(
+		`SELECT banned_at IS NOT NULL FROM users WHERE id = $1`, userID,
+	).Scan(&banned)
+	return banned, err
+}
+
+// LogAction adds an entry to the moderation log. actorID, postID and targetUserID may be nil.
+func (r *ModerationRepo) LogAction(tx *sql.Tx, actorID *int, action string, postID, targetUserID *int, note string) error {
+	_, err := tx.Exec(
+		`INSERT INTO moderation_log (actor_id, action, post_id, target_user_id, note)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		actorID, action, postID, targetUserID, note,
+	)
+	return err
+}
+
+// GetLog returns the most recent moderation actions, newest first.
+func (r *ModerationRepo) GetLog(limit int) ([]models.ModerationLogEntry, error) {
+	rows, err := r.db.Query(
+		`SELECT l.id, COALESCE(a.username, ''), l.action, l.post_id, COALESCE(t.username, ''), l.note, l.created_at
+		 FROM moderation_log l
+		 LEFT JOIN users a ON l.actor_id = a.id
+		 LEFT JOIN users t ON l.target_user_id = t.id
+		 ORDER BY l.created_at DESC, l.id DESC
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.ModerationLogEntry
+	for rows.Next() {
+		var e models.ModerationLogEntry
+		var postID sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.ActorUsername, &e.Action, &postID, &e.TargetUsername, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if postID.Valid {
+			id := int(postID.Int64)
+			e.PostID = &id
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (r *ModerationRepo) GetBannedWords() ([]models.BannedWord, error) {
+	rows, err := r.db.Query(`SELECT word, created_at FROM banned_words ORDER BY word`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var words []models.BannedWord
+	for rows.Next() {
+		var w models.BannedWord
+		if err := rows.Scan(&w.Word, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		words = append(words, w)
+	}
+	return words, nil
+}
+
+// AddBannedWord adds a word to the filter. It reports false if it was already there.
+func (r *ModerationRepo) AddBannedWord(tx *sql.Tx, word string, adminID int) (bool, error) {
+	result, err := tx.Exec(
+		`INSERT INTO banned_words (word, added_by) VALUES ($1, $2) ON CONFLICT (word) DO NOTHING`,
+		word, adminID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *ModerationRepo) RemoveBannedWord(tx *sql.Tx, word string) (bool, error) {
+	result, err := tx.Exec(`DELETE FROM banned_words WHERE word = $1`, word)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GrantAdmin makes the given users admins, returning how many weren't already. Closed
+// accounts are skipped.
+func (r *ModerationRepo) GrantAdmin(userIDs []int) (int64, error) {
+	result, err := r.db.Exec(
+		`UPDATE users SET is_admin = true
+		 WHERE id = ANY($1) AND deleted_at IS NULL AND NOT is_admin`,
+		pq.Array(userIDs),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -74,6 +74,44 @@ func (r *PortfolioRepo) DeleteHolding(tx *sql.Tx, ownerID, stockUserID int) erro
 	return err
 }
 
+// LockByStock returns every holding of a stock and locks them until tx ends, so concurrent
+// trades can't change them in the meantime.
+func (r *PortfolioRepo) LockByStock(tx *sql.Tx, stockUserID int) ([]models.Portfolio, error) {
+	return lockHoldings(tx,
+		`SELECT id, owner_id, stock_user_id, num_shares, avg_purchase_price
+		 FROM portfolios WHERE stock_user_id = $1 AND num_shares > 0
+		 ORDER BY num_shares DESC
+		 FOR UPDATE`, stockUserID,
+	)
+}
+
+// LockByOwner returns every holding of an owner and locks them until tx ends.
+func (r *PortfolioRepo) LockByOwner(tx *sql.Tx, ownerID int) ([]models.Portfolio, error) {
+	return lockHoldings(tx,
+		`SELECT id, owner_id, stock_user_id, num_shares, avg_purchase_price
+		 FROM portfolios WHERE owner_id = $1
+		 FOR UPDATE`, ownerID,
+	)
+}
+
+func lockHoldings(tx *sql.Tx, query string, id int) ([]models.Portfolio, error) {
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var portfolios []models.Portfolio
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.ID, &p.OwnerID, &p.StockUserID, &p.NumShares, &p.AvgPurchasePrice); err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, p)
+	}
+	return portfolios, rows.Err()
+}
+
 func (r *PortfolioRepo) GetAllHoldings() ([]models.Portfolio, error) {
 	rows, err := r.db.Query(
 		`SELECT id, owner_id, stock_user_id, num_shares, avg_purchase_price FROM portfolios`,
@@ -93,3 +131,98 @@ func (r *PortfolioRepo) GetAllHoldings() ([]models.Portfolio, error) {
 	}
 	return portfolios, nil
 }
+
+// GetOwnerIDsByStock returns the IDs of everyone currently holding shares of a stock.
+func (r *PortfolioRepo) GetOwnerIDsByStock(stockUserID int) ([]int, error) {
+	rows, err := r.db.Query(
+		`SELECT owner_id FROM portfolios WHERE stock_user_id = $1 AND num_shares > 0`, stockUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetByStock returns every holding of a stock.
+func (r *PortfolioRepo) GetByStock(stockUserID int) ([]models.Portfolio, error) {
+	rows, err := r.db.Query(
+		`SELECT id, owner_id, stock_user_id, num_shares, avg_purchase_price
+		 FROM portfolios WHERE stock_user_id = $1 AND num_shares > 0
+		 ORDER BY num_shares DESC`, stockUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var portfolios []models.Portfolio
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.ID, &p.OwnerID, &p.StockUserID, &p.NumShares, &p.AvgPurchasePrice); err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, p)
+	}
+	return portfolios, nil
+}
+
+// GetTopHolders returns a page of a stock's holders, largest first. Holders who hide their
+// holdings are anonymized; OwnershipPercent and HoldingDays are left for the caller.
+func (r *PortfolioRepo) GetTopHolders(stockUserID, limit, offset int) ([]models.StockHolder, error) {
+	rows, err := r.db.Query(
+		`SELECT p.owner_id, u.username, u.ticker, p.num_shares, p.avg_purchase_price, p.opened_at,
+		        COALESCE(ps.hide_holdings, false)
+		 FROM portfolios p
+		 JOIN users u ON p.owner_id = u.id
+		 LEFT JOIN privacy_settings ps ON ps.user_id = p.owner_id
+		 WHERE p.stock_user_id = $1 AND p.num_shares > 0
+		 ORDER BY p.num_shares DESC, p.opened_at ASC
+		 LIMIT $2 OFFSET $3`,
+		stockUserID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holders []models.StockHolder
+	for rows.Next() {
+		var h models.StockHolder
+		var hidden bool
+		if err := rows.Scan(&h.UserID, &h.Username, &h.Ticker, &h.NumShares, &h.EntryPrice, &h.OpenedAt, &hidden); err != nil {
+			return nil, err
+		}
+		if hidden {
+			h.UserID, h.Username, h.Ticker = 0, models.AnonymousTrader, ""
+		}
+		holders = append(holders, h)
+	}
+	return holders, nil
+}
+
+// GetOwnershipTotals returns a stock's holder count, total shares held, the share counts of
+// its largest and five largest holders, and the sum of squared share counts.
+func (r *PortfolioRepo) GetOwnershipTotals(stockUserID int) (holders int, held, top, top5, sumSquares float64, err error) {
+	err = r.db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(num_shares), 0), COALESCE(MAX(num_shares), 0),
+		        COALESCE(SUM(num_shares) FILTER (WHERE rank <= 5), 0),
+		        COALESCE(SUM(num_shares * num_shares), 0)
+		 FROM (
+		     SELECT num_shares, ROW_NUMBER() OVER (ORDER BY num_shares DESC) AS rank
+		     FROM portfolios
+		     WHERE stock_user_id = $1 AND num_shares > 0
+		 ) h`,
+		stockUserID,
+	).Scan(&holders, &held, &top, &top5, &sumSquares)
+	return holders, held, top, top5, sumSquares, err
+}
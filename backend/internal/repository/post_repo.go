@@ -2,7 +2,9 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"grub-exchange/internal/models"
+	"time"
 )
 
 type PostRepo struct {
@@ -13,30 +15,269 @@ func NewPostRepo(db *sql.DB) *PostRepo {
 	return &PostRepo{db: db}
 }
 
-func (r *PostRepo) Create(authorID, stockUserID int, content string) (*models.StockPost, error) {
-	var post models.StockPost
-	err := r.db.QueryRow(
-		`INSERT INTO stock_posts (author_id, stock_user_id, content)
-		 VALUES ($1, $2, $3)
+// Create adds a post about a stock. Posts with a leagueID are only visible within that league.
+// The post is cross-posted to any other stocks its entities have cashtags for.
+func (r *PostRepo) Create(authorID, stockUserID int, leagueID *int, content string, entities []models.PostEntity) (*models.StockPost, error) {
+	entitiesJSON, err := encodeEntities(entities)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	post := models.StockPost{Entities: entities}
+	err = tx.QueryRow(
+		`INSERT INTO stock_posts (author_id, stock_user_id, league_id, content, entities)
+		 VALUES ($1, $2, $3, $4, $5)
 		 RETURNING id, author_id, stock_user_id, content, likes, dislikes, created_at`,
-		authorID, stockUserID, content,
+		authorID, stockUserID, leagueID, content, entitiesJSON,
 	).Scan(&post.ID, &post.AuthorID, &post.StockUserID, &post.Content, &post.Likes, &post.Dislikes, &post.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if err := savePostStocks(tx, post.ID, stockUserID, entities); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	post.LeagueID = leagueID
 	return &post, nil
 }
 
-// GetByStock returns posts for a stock, with the requesting user's vote status.
+// CreateReply adds a reply to a post. Replies belong to the same stock and league as the
+// post they answer, so their votes count toward the stock's sentiment like any other post.
+func (r *PostRepo) CreateReply(authorID int, parent *models.StockPost, content string, entities []models.PostEntity) (*models.StockPost, error) {
+	entitiesJSON, err := encodeEntities(entities)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	post := models.StockPost{
+		AuthorID:    authorID,
+		StockTicker: parent.StockTicker,
+		StockUserID: parent.StockUserID,
+		LeagueID:    parent.LeagueID,
+		ParentID:    &parent.ID,
+		Depth:       parent.Depth + 1,
+		Content:     content,
+		Entities:    entities,
+	}
+	err = tx.QueryRow(
+		`INSERT INTO stock_posts (author_id, stock_user_id, league_id, parent_id, depth, content, entities)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		authorID, parent.StockUserID, parent.LeagueID, parent.ID, post.Depth, content, entitiesJSON,
+	).Scan(&post.ID, &post.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := savePostStocks(tx, post.ID, parent.StockUserID, entities); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE stock_posts SET reply_count = reply_count + 1 WHERE id = $1`, parent.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetByID returns a post with the requesting user's vote status, including deleted and
+// hidden posts.
+func (r *PostRepo) GetByID(postID, requestingUserID int) (*models.StockPost, error) {
+	var p models.StockPost
+	var parentID, leagueID sql.NullInt64
+	var editedAt sql.NullString
+	var entitiesJSON []byte
+	err := r.db.QueryRow(
+		`SELECT p.id, p.author_id, u.username, su.ticker, p.stock_user_id, p.league_id, p.content,
+		        p.likes, p.dislikes, p.created_at, COALESCE(v.vote_type, 0), p.parent_id, p.depth, p.reply_count,
+		        p.edited_at, p.deleted_at IS NOT NULL, p.hidden_at IS NOT NULL, p.entities
+		 FROM stock_posts p
+		 JOIN users u ON p.author_id = u.id
+		 JOIN users su ON p.stock_user_id = su.id
+		 LEFT JOIN post_votes v ON v.post_id = p.id AND v.user_id = $2
+		 WHERE p.id = $1`,
+		postID, requestingUserID,
+	).Scan(&p.ID, &p.AuthorID, &p.AuthorUsername, &p.StockTicker, &p.StockUserID, &leagueID, &p.Content,
+		&p.Likes, &p.Dislikes, &p.CreatedAt, &p.UserVote, &parentID, &p.Depth, &p.ReplyCount,
+		&editedAt, &p.Deleted, &p.Hidden, &entitiesJSON)
+	if err != nil {
+		return nil, err
+	}
+	if p.Entities, err = decodeEntities(entitiesJSON); err != nil {
+		return nil, err
+	}
+	if editedAt.Valid {
+		p.EditedAt = &editedAt.String
+	}
+	if leagueID.Valid {
+		id := int(leagueID.Int64)
+		p.LeagueID = &id
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		p.ParentID = &id
+	}
+	return &p, nil
+}
+
+// GetThread returns a post and up to limit of its replies at any depth, oldest first, with
+// the requesting user's vote status. The post itself comes first. Deleted and hidden posts
+// keep their place in the thread so their replies stay attached, but lose their content.
+func (r *PostRepo) GetThread(postID, requestingUserID, limit int) ([]models.StockPost, error) {
+	rows, err := r.db.Query(
+		`WITH RECURSIVE thread AS (
+		     SELECT id FROM stock_posts WHERE id = $1
+		     UNION ALL
+		     SELECT sp.id FROM stock_posts sp JOIN thread t ON sp.parent_id = t.id
+		 )
+		 SELECT p.id, p.author_id, u.username, su.ticker,
+		        CASE WHEN p.deleted_at IS NULL AND p.hidden_at IS NULL THEN p.content ELSE '' END, p.likes, p.dislikes, p.created_at,
+		        COALESCE(v.vote_type, 0), p.parent_id, p.depth, p.reply_count,
+		        p.edited_at, p.deleted_at IS NOT NULL, p.hidden_at IS NOT NULL,
+		        CASE WHEN p.deleted_at IS NULL AND p.hidden_at IS NULL THEN p.entities END
+		 FROM thread t
+		 JOIN stock_posts p ON p.id = t.id
+		 JOIN users u ON p.author_id = u.id
+		 JOIN users su ON p.stock_user_id = su.id
+		 LEFT JOIN post_votes v ON v.post_id = p.id AND v.user_id = $2
+		 ORDER BY (p.id = $1) DESC, p.created_at ASC, p.id ASC
+		 LIMIT $3`,
+		postID, requestingUserID, limit+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanPosts(rows)
+}
+
+// Edit replaces a post's content and entities, saving the previous content as a revision.
+// It reports false if the post doesn't exist or is deleted.
+func (r *PostRepo) Edit(postID int, content string, entities []models.PostEntity) (bool, error) {
+	entitiesJSON, err := encodeEntities(entities)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO post_revisions (post_id, content)
+		 SELECT id, content FROM stock_posts WHERE id = $1 AND deleted_at IS NULL
+		 FOR UPDATE`,
+		postID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	var stockUserID int
+	if err := tx.QueryRow(
+		`UPDATE stock_posts SET content = $1, entities = $2, edited_at = NOW() WHERE id = $3
+		 RETURNING stock_user_id`,
+		content, entitiesJSON, postID,
+	).Scan(&stockUserID); err != nil {
+		return false, err
+	}
+	if err := savePostStocks(tx, postID, stockUserID, entities); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Delete soft-deletes a post and takes it off its parent's reply count. It reports false
+// if the post doesn't exist or is already deleted.
+func (r *PostRepo) Delete(postID int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	err = tx.QueryRow(
+		`UPDATE stock_posts SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+		 RETURNING parent_id`,
+		postID,
+	).Scan(&parentID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if parentID.Valid {
+		if _, err := tx.Exec(
+			`UPDATE stock_posts SET reply_count = GREATEST(reply_count - 1, 0) WHERE id = $1`,
+			parentID.Int64,
+		); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// GetRevisions returns a post's earlier versions, newest first.
+func (r *PostRepo) GetRevisions(postID int) ([]models.PostRevision, error) {
+	rows, err := r.db.Query(
+		`SELECT id, content, created_at FROM post_revisions
+		 WHERE post_id = $1
+		 ORDER BY created_at DESC, id DESC`,
+		postID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.PostRevision
+	for rows.Next() {
+		var rev models.PostRevision
+		if err := rows.Scan(&rev.ID, &rev.Content, &rev.ReplacedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+// GetByStock returns posts about a stock, including posts cross-posted to it with a cashtag,
+// with the requesting user's vote status.
 func (r *PostRepo) GetByStock(stockUserID, requestingUserID, limit int) ([]models.StockPost, error) {
 	rows, err := r.db.Query(
 		`SELECT p.id, p.author_id, u.username, su.ticker, p.content, p.likes, p.dislikes, p.created_at,
-		        COALESCE(v.vote_type, 0)
+		        COALESCE(v.vote_type, 0), p.parent_id, p.depth, p.reply_count,
+		        p.edited_at, p.deleted_at IS NOT NULL, p.hidden_at IS NOT NULL, p.entities
 		 FROM stock_posts p
 		 JOIN users u ON p.author_id = u.id
 		 JOIN users su ON p.stock_user_id = su.id
 		 LEFT JOIN post_votes v ON v.post_id = p.id AND v.user_id = $3
-		 WHERE p.stock_user_id = $1
+		 WHERE (p.stock_user_id = $1 OR p.id IN (SELECT post_id FROM post_stocks WHERE stock_user_id = $1))
+		   AND p.league_id IS NULL AND p.parent_id IS NULL
+		   AND p.deleted_at IS NULL AND p.hidden_at IS NULL
 		 ORDER BY p.created_at DESC
 		 LIMIT $2`,
 		stockUserID, limit, requestingUserID,
@@ -44,25 +285,16 @@ func (r *PostRepo) GetByStock(stockUserID, requestingUserID, limit int) ([]model
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var posts []models.StockPost
-	for rows.Next() {
-		var p models.StockPost
-		if err := rows.Scan(&p.ID, &p.AuthorID, &p.AuthorUsername, &p.StockTicker,
-			&p.Content, &p.Likes, &p.Dislikes, &p.CreatedAt, &p.UserVote); err != nil {
-			return nil, err
-		}
-		posts = append(posts, p)
-	}
-	return posts, nil
+	return scanPosts(rows)
 }
 
-// Vote inserts or updates a vote, and updates the post's like/dislike counts atomically.
-func (r *PostRepo) Vote(postID, userID, voteType int) error {
+// Vote casts, toggles off or switches a vote, updating the post's like/dislike counts
+// atomically. It reports whether this is the user's first vote on the post ever, so
+// toggling a vote off and casting it again doesn't count as voting again.
+func (r *PostRepo) Vote(postID, userID, voteType int) (bool, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Get existing vote if any
@@ -72,6 +304,7 @@ func (r *PostRepo) Vote(postID, userID, voteType int) error {
 		postID, userID,
 	).Scan(&existingVote)
 
+	newVote := false
 	if err == sql.ErrNoRows {
 		// New vote
 		_, err = tx.Exec(
@@ -80,8 +313,20 @@ func (r *PostRepo) Vote(postID, userID, voteType int) error {
 		)
 		if err != nil {
 			tx.Rollback()
-			return err
+			return false, err
+		}
+		// post_votes forgets votes toggled off; post_voters remembers everyone who voted
+		var result sql.Result
+		result, err = tx.Exec(
+			`INSERT INTO post_voters (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			postID, userID,
+		)
+		if err != nil {
+			tx.Rollback()
+			return false, err
 		}
+		n, _ := result.RowsAffected()
+		newVote = n > 0
 		if voteType == 1 {
 			_, err = tx.Exec(`UPDATE stock_posts SET likes = likes + 1 WHERE id = $1`, postID)
 		} else {
@@ -89,13 +334,13 @@ func (r *PostRepo) Vote(postID, userID, voteType int) error {
 		}
 	} else if err != nil {
 		tx.Rollback()
-		return err
+		return false, err
 	} else if existingVote == voteType {
 		// Same vote — remove it (toggle off)
 		_, err = tx.Exec(`DELETE FROM post_votes WHERE post_id = $1 AND user_id = $2`, postID, userID)
 		if err != nil {
 			tx.Rollback()
-			return err
+			return false, err
 		}
 		if voteType == 1 {
 			_, err = tx.Exec(`UPDATE stock_posts SET likes = GREATEST(likes - 1, 0) WHERE id = $1`, postID)
@@ -107,7 +352,7 @@ func (r *PostRepo) Vote(postID, userID, voteType int) error {
 		_, err = tx.Exec(`UPDATE post_votes SET vote_type = $1 WHERE post_id = $2 AND user_id = $3`, voteType, postID, userID)
 		if err != nil {
 			tx.Rollback()
-			return err
+			return false, err
 		}
 		if voteType == 1 {
 			// Was dislike, now like
@@ -120,21 +365,53 @@ func (r *PostRepo) Vote(postID, userID, voteType int) error {
 
 	if err != nil {
 		tx.Rollback()
-		return err
+		return false, err
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return newVote, nil
+}
+
+// GetByLeague returns a league's posts, with the requesting user's vote status.
+func (r *PostRepo) GetByLeague(leagueID, requestingUserID, limit int) ([]models.StockPost, error) {
+	rows, err := r.db.Query(
+		`SELECT p.id, p.author_id, u.username, su.ticker, p.content, p.likes, p.dislikes, p.created_at,
+		        COALESCE(v.vote_type, 0), p.parent_id, p.depth, p.reply_count,
+		        p.edited_at, p.deleted_at IS NOT NULL, p.hidden_at IS NOT NULL, p.entities
+		 FROM stock_posts p
+		 JOIN users u ON p.author_id = u.id
+		 JOIN users su ON p.stock_user_id = su.id
+		 LEFT JOIN post_votes v ON v.post_id = p.id AND v.user_id = $3
+		 WHERE p.league_id = $1 AND p.parent_id IS NULL
+		   AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+		 ORDER BY p.created_at DESC
+		 LIMIT $2`,
+		leagueID, limit, requestingUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := scanPosts(rows)
+	for i := range posts {
+		posts[i].LeagueID = &leagueID
+	}
+	return posts, err
 }
 
 // GetRecent returns the most recent posts across all stocks.
 func (r *PostRepo) GetRecent(requestingUserID, limit int) ([]models.StockPost, error) {
 	rows, err := r.db.Query(
 		`SELECT p.id, p.author_id, u.username, su.ticker, p.content, p.likes, p.dislikes, p.created_at,
-		        COALESCE(v.vote_type, 0)
+		        COALESCE(v.vote_type, 0), p.parent_id, p.depth, p.reply_count,
+		        p.edited_at, p.deleted_at IS NOT NULL, p.hidden_at IS NOT NULL, p.entities
 		 FROM stock_posts p
 		 JOIN users u ON p.author_id = u.id
 		 JOIN users su ON p.stock_user_id = su.id
 		 LEFT JOIN post_votes v ON v.post_id = p.id AND v.user_id = $2
+		 WHERE p.league_id IS NULL AND p.parent_id IS NULL
+		   AND p.deleted_at IS NULL AND p.hidden_at IS NULL
 		 ORDER BY p.created_at DESC
 		 LIMIT $1`,
 		limit, requestingUserID,
@@ -142,13 +419,109 @@ func (r *PostRepo) GetRecent(requestingUserID, limit int) ([]models.StockPost, e
 	if err != nil {
 		return nil, err
 	}
+	return scanPosts(rows)
+}
+
+// GetByAuthor returns a user's public (non-league) posts, newest first, with the requesting
+// user's vote status.
+func (r *PostRepo) GetByAuthor(authorID, requestingUserID, limit int) ([]models.StockPost, error) {
+	rows, err := r.db.Query(
+		`SELECT p.id, p.author_id, u.username, su.ticker, p.content, p.likes, p.dislikes, p.created_at,
+		        COALESCE(v.vote_type, 0), p.parent_id, p.depth, p.reply_count,
+		        p.edited_at, p.deleted_at IS NOT NULL, p.hidden_at IS NOT NULL, p.entities
+		 FROM stock_posts p
+		 JOIN users u ON p.author_id = u.id
+		 JOIN users su ON p.stock_user_id = su.id
+		 LEFT JOIN post_votes v ON v.post_id = p.id AND v.user_id = $3
+		 WHERE p.author_id = $1 AND p.league_id IS NULL AND p.parent_id IS NULL
+		   AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+		 ORDER BY p.created_at DESC
+		 LIMIT $2`,
+		authorID, limit, requestingUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanPosts(rows)
+}
+
+// GetVotesSince returns the votes on public posts written since the given time, skipping
+// deleted and hidden posts. Used to compute stock sentiment.
+func (r *PostRepo) GetVotesSince(since time.Time) ([]models.SentimentVote, error) {
+	rows, err := r.db.Query(
+		`SELECT v.post_id, p.stock_user_id, su.ticker, v.user_id, u.username, v.vote_type, v.created_at, p.created_at
+		 FROM post_votes v
+		 JOIN stock_posts p ON v.post_id = p.id
+		 JOIN users su ON p.stock_user_id = su.id
+		 JOIN users u ON v.user_id = u.id
+		 WHERE p.created_at >= $1 AND p.league_id IS NULL
+		   AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+		 ORDER BY v.post_id, v.created_at`,
+		since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var votes []models.SentimentVote
+	for rows.Next() {
+		var v models.SentimentVote
+		if err := rows.Scan(&v.PostID, &v.StockUserID, &v.Ticker, &v.VoterID, &v.VoterUsername,
+			&v.VoteType, &v.VotedAt, &v.PostCreatedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, v)
+	}
+	return votes, nil
+}
+
+// Search finds public posts and replies whose content matches the query, best match first,
+// with the requesting user's vote status.
+func (r *PostRepo) Search(query string, requestingUserID, limit, offset int) ([]models.SearchResult, error) {
+	rows, err := r.db.Query(
+		`SELECT p.id, p.author_id, u.username, su.ticker, p.content, p.likes, p.dislikes, p.created_at,
+		        COALESCE(v.vote_type, 0), p.parent_id, p.depth, p.reply_count,
+		        p.edited_at, p.deleted_at IS NOT NULL, p.hidden_at IS NOT NULL, p.entities,
+		        ts_rank(p.search_vector, plainto_tsquery('english', $1)) AS rank
+		 FROM stock_posts p
+		 JOIN users u ON p.author_id = u.id
+		 JOIN users su ON p.stock_user_id = su.id
+		 LEFT JOIN post_votes v ON v.post_id = p.id AND v.user_id = $2
+		 WHERE p.search_vector @@ plainto_tsquery('english', $1)
+		   AND p.league_id IS NULL AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+		 ORDER BY rank DESC, p.created_at DESC, p.id DESC
+		 LIMIT $3 OFFSET $4`,
+		query, requestingUserID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		result := models.SearchResult{Type: models.SearchTypePost}
+		post, err := scanPost(rows, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+		result.Post = &post
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// scanPosts reads rows of the post listing columns: id, author, stock ticker, content, votes,
+// created_at, the requesting user's vote, parent_id, depth, reply_count, edited_at, whether
+// the post is deleted or hidden by moderators, and entities.
+func scanPosts(rows *sql.Rows) ([]models.StockPost, error) {
 	defer rows.Close()
 
 	var posts []models.StockPost
 	for rows.Next() {
-		var p models.StockPost
-		if err := rows.Scan(&p.ID, &p.AuthorID, &p.AuthorUsername, &p.StockTicker,
-			&p.Content, &p.Likes, &p.Dislikes, &p.CreatedAt, &p.UserVote); err != nil {
+		p, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
 		posts = append(posts, p)
@@ -156,52 +529,113 @@ func (r *PostRepo) GetRecent(requestingUserID, limit int) ([]models.StockPost, e
 	return posts, nil
 }
 
-// GetSentimentForStock returns the net sentiment (likes - dislikes) from the top 10
-// most-engaged posts for a stock. Used by the market maker.
-func (r *PostRepo) GetSentimentForStock(stockUserID int) (int, error) {
-	var netSentiment sql.NullInt64
-	err := r.db.QueryRow(
-		`SELECT SUM(likes - dislikes) FROM (
-			SELECT likes, dislikes FROM stock_posts
-			WHERE stock_user_id = $1
-			ORDER BY (likes + dislikes) DESC
-			LIMIT 10
-		) top_posts`,
-		stockUserID,
-	).Scan(&netSentiment)
+// scanPost reads one row of the post listing columns, followed by any extra columns.
+func scanPost(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.StockPost, error) {
+	var p models.StockPost
+	var parentID sql.NullInt64
+	var editedAt sql.NullString
+	var entitiesJSON []byte
+	dest := []interface{}{&p.ID, &p.AuthorID, &p.AuthorUsername, &p.StockTicker,
+		&p.Content, &p.Likes, &p.Dislikes, &p.CreatedAt, &p.UserVote,
+		&parentID, &p.Depth, &p.ReplyCount, &editedAt, &p.Deleted, &p.Hidden, &entitiesJSON}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return p, err
+	}
+	entities, err := decodeEntities(entitiesJSON)
 	if err != nil {
-		return 0, err
+		return p, err
+	}
+	p.Entities = entities
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		p.ParentID = &id
 	}
-	if netSentiment.Valid {
-		return int(netSentiment.Int64), nil
+	if editedAt.Valid {
+		p.EditedAt = &editedAt.String
 	}
-	return 0, nil
+	return p, nil
 }
 
-// GetAllSentiments returns sentiment scores for all stocks that have posts.
-// Used by the market maker to batch-load sentiments.
-func (r *PostRepo) GetAllSentiments() (map[int]int, error) {
+// GetUnparsed returns up to limit posts whose entities haven't been parsed yet, with just
+// their ID, stock and content.
+func (r *PostRepo) GetUnparsed(limit int) ([]models.StockPost, error) {
 	rows, err := r.db.Query(
-		`SELECT stock_user_id, SUM(net) FROM (
-			SELECT stock_user_id, (likes - dislikes) AS net,
-			       ROW_NUMBER() OVER (PARTITION BY stock_user_id ORDER BY (likes + dislikes) DESC) AS rn
-			FROM stock_posts
-		) ranked
-		WHERE rn <= 10
-		GROUP BY stock_user_id`,
+		`SELECT id, stock_user_id, content FROM stock_posts WHERE entities IS NULL ORDER BY id LIMIT $1`,
+		limit,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	sentiments := make(map[int]int)
+	var posts []models.StockPost
 	for rows.Next() {
-		var stockID, net int
-		if err := rows.Scan(&stockID, &net); err != nil {
+		var p models.StockPost
+		if err := rows.Scan(&p.ID, &p.StockUserID, &p.Content); err != nil {
 			return nil, err
 		}
-		sentiments[stockID] = net
+		posts = append(posts, p)
+	}
+	return posts, nil
+}
+
+// SetEntities stores a post's parsed entities and cross-posts it to their cashtags' stocks.
+func (r *PostRepo) SetEntities(postID, stockUserID int, entities []models.PostEntity) error {
+	entitiesJSON, err := encodeEntities(entities)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE stock_posts SET entities = $1 WHERE id = $2`, entitiesJSON, postID); err != nil {
+		return err
+	}
+	if err := savePostStocks(tx, postID, stockUserID, entities); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// savePostStocks replaces the stocks a post is cross-posted to with those its cashtags
+// name, other than the stock it's about.
+func savePostStocks(tx *sql.Tx, postID, stockUserID int, entities []models.PostEntity) error {
+	if _, err := tx.Exec(`DELETE FROM post_stocks WHERE post_id = $1`, postID); err != nil {
+		return err
+	}
+	for _, e := range entities {
+		if e.Type != models.EntityCashtag || e.UserID == stockUserID {
+			continue
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO post_stocks (post_id, stock_user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			postID, e.UserID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeEntities(entities []models.PostEntity) ([]byte, error) {
+	if entities == nil {
+		entities = []models.PostEntity{}
+	}
+	return json.Marshal(entities)
+}
+
+// decodeEntities reads a post's entities column. Posts not parsed yet have none.
+func decodeEntities(data []byte) ([]models.PostEntity, error) {
+	entities := []models.PostEntity{}
+	if len(data) == 0 {
+		return entities, nil
+	}
+	if err := json.Unmarshal(data, &entities); err != nil {
+		return nil, err
 	}
-	return sentiments, nil
+	return entities, nil
 }
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"grub-exchange/internal/models"
+)
+
+type PrivacyRepo struct {
+	db *sql.DB
+}
+
+func NewPrivacyRepo(db *sql.DB) *PrivacyRepo {
+	return &PrivacyRepo{db: db}
+}
+
+// Get returns a user's privacy settings, or the defaults if they never changed them.
+func (r *PrivacyRepo) Get(userID int) (*models.PrivacySettings, error) {
+	settings := &models.PrivacySettings{}
+	err := r.db.QueryRow(
+		`SELECT hide_trades, anonymize_trades, hide_portfolio_value, hide_holdings
+		 FROM privacy_settings WHERE user_id = $1`,
+		userID,
+	).Scan(&settings.HideTrades, &settings.AnonymizeTrades, &settings.HidePortfolioValue, &settings.HideHoldings)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (r *PrivacyRepo) Save(userID int, settings *models.PrivacySettings) error {
+	_, err := r.db.Exec(
+		`INSERT INTO privacy_settings (user_id, hide_trades, anonymize_trades, hide_portfolio_value, hide_holdings, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 ON CONFLICT (user_id) DO UPDATE SET
+		     hide_trades = EXCLUDED.hide_trades,
+		     anonymize_trades = EXCLUDED.anonymize_trades,
+		     hide_portfolio_value = EXCLUDED.hide_portfolio_value,
+		     hide_holdings = EXCLUDED.hide_holdings,
+		     updated_at = NOW()`,
+		userID, settings.HideTrades, settings.AnonymizeTrades, settings.HidePortfolioValue, settings.HideHoldings,
+	)
+	return err
+}
+
+// GetPortfolioValueHidden returns the set of users who hide their portfolio value.
+func (r *PrivacyRepo) GetPortfolioValueHidden() (map[int]bool, error) {
+	rows, err := r.db.Query(`SELECT user_id FROM privacy_settings WHERE hide_portfolio_value`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hidden := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		hidden[id] = true
+	}
+	return hidden, nil
+}
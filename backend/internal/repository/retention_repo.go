@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"grub-exchange/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// retentionQueries delete the rows of a table with timestamps in [$1, $2) that aren't worth
+// keeping at a resolution of $3 seconds. In each bucket, price_history keeps its first, last,
+// highest and lowest price, so the price at every bucket boundary and the all-time range
+// survive; portfolio_snapshots keeps its first and last snapshot.
+var retentionQueries = map[string]string{
+	"price_history": `
+		DELETE FROM price_history WHERE id IN (
+		    SELECT id FROM (
+		        SELECT id,
+		               ROW_NUMBER() OVER (w ORDER BY timestamp, id) AS first_n,
+		               ROW_NUMBER() OVER (w ORDER BY timestamp DESC, id DESC) AS last_n,
+		               ROW_NUMBER() OVER (w ORDER BY price DESC, id) AS high_n,
+		               ROW_NUMBER() OVER (w ORDER BY price, id) AS low_n
+		        FROM price_history
+		        WHERE timestamp >= $1 AND timestamp < $2
+		        WINDOW w AS (PARTITION BY user_id, FLOOR(EXTRACT(EPOCH FROM timestamp) / $3))
+		    ) ranked
+		    WHERE first_n > 1 AND last_n > 1 AND high_n > 1 AND low_n > 1
+		)`,
+	"portfolio_snapshots": `
+		DELETE FROM portfolio_snapshots WHERE id IN (
+		    SELECT id FROM (
+		        SELECT id,
+		               ROW_NUMBER() OVER (w ORDER BY timestamp, id) AS first_n,
+		               ROW_NUMBER() OVER (w ORDER BY timestamp DESC, id DESC) AS last_n
+		        FROM portfolio_snapshots
+		        WHERE timestamp >= $1 AND timestamp < $2
+		        WINDOW w AS (PARTITION BY user_id, FLOOR(EXTRACT(EPOCH FROM timestamp) / $3))
+		    ) ranked
+		    WHERE first_n > 1 AND last_n > 1
+		)`,
+}
+
+type RetentionRepo struct {
+	db *sql.DB
+}
+
+func NewRetentionRepo(db *sql.DB) *RetentionRepo {
+	return &RetentionRepo{db: db}
+}
+
+func (r *RetentionRepo) GetWatermark(name string) (time.Time, error) {
+	return getWatermark(r.db, name)
+}
+
+// GetOldest returns the timestamp of a table's oldest row, or the zero time if it's empty.
+func (r *RetentionRepo) GetOldest(table string) (time.Time, error) {
+	if _, ok := retentionQueries[table]; !ok {
+		return time.Time{}, fmt.Errorf("no retention policy for table %q", table)
+	}
+	var oldest sql.NullTime
+	if err := r.db.QueryRow(`SELECT MIN(timestamp) FROM ` + table).Scan(&oldest); err != nil {
+		return time.Time{}, err
+	}
+	return oldest.Time, nil
+}
+
+// Compact thins out a table's rows with timestamps in [from, to) to the given resolution and
+// advances the named watermark to to. Compacting is idempotent, so a range compacted twice
+// loses nothing more. It returns the number of rows deleted.
+func (r *RetentionRepo) Compact(table string, from, to time.Time, resolution time.Duration, watermarkName string) (int64, error) {
+	query, ok := retentionQueries[table]
+	if !ok {
+		return 0, fmt.Errorf("no retention policy for table %q", table)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, from, to, int(resolution.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	deleted, _ := result.RowsAffected()
+	if err := setWatermark(tx, watermarkName, to); err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+func (r *RetentionRepo) RecordRun(run *models.RetentionRun) error {
+	return r.db.QueryRow(
+		`INSERT INTO retention_runs (table_name, resolution, cutoff, rows_deleted, duration_ms)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, created_at`,
+		run.Table, run.Resolution, run.Cutoff, run.RowsDeleted, run.DurationMs,
+	).Scan(&run.ID, &run.CreatedAt)
+}
+
+// GetRecentRuns returns the latest recorded runs, newest first.
+func (r *RetentionRepo) GetRecentRuns(limit int) ([]models.RetentionRun, error) {
+	rows, err := r.db.Query(
+		`SELECT id, table_name, resolution, cutoff, rows_deleted, duration_ms, created_at
+		 FROM retention_runs
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.RetentionRun
+	for rows.Next() {
+		var run models.RetentionRun
+		if err := rows.Scan(&run.ID, &run.Table, &run.Resolution, &run.Cutoff, &run.RowsDeleted,
+			&run.DurationMs, &run.CreatedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// GetRowsReclaimed returns the total rows deleted from each table by all runs.
+func (r *RetentionRepo) GetRowsReclaimed() (map[string]int64, error) {
+	rows, err := r.db.Query(`SELECT table_name, SUM(rows_deleted) FROM retention_runs GROUP BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reclaimed := make(map[string]int64)
+	for rows.Next() {
+		var table string
+		var n int64
+		if err := rows.Scan(&table, &n); err != nil {
+			return nil, err
+		}
+		reclaimed[table] = n
+	}
+	return reclaimed, nil
+}
+
+// GetEstimatedRows returns the planner's row count estimate for each table, which is much
+// cheaper than counting large tables.
+func (r *RetentionRepo) GetEstimatedRows(tables []string) (map[string]int64, error) {
+	rows, err := r.db.Query(
+		`SELECT relname, GREATEST(reltuples, 0)::bigint FROM pg_class
+		 WHERE relkind = 'r' AND relname = ANY($1) AND relnamespace = 'public'::regnamespace`,
+		pq.Array(tables),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	estimates := make(map[string]int64)
+	for rows.Next() {
+		var table string
+		var n int64
+		if err := rows.Scan(&table, &n); err != nil {
+			return nil, err
+		}
+		estimates[table] = n
+	}
+	return estimates, nil
+}
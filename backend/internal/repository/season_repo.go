@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"database/sql"
+	"grub-exchange/internal/models"
+	"time"
+)
+
+type SeasonRepo struct {
+	db *sql.DB
+}
+
+func NewSeasonRepo(db *sql.DB) *SeasonRepo {
+	return &SeasonRepo{db: db}
+}
+
+const seasonColumns = `id, name, starts_at, ends_at, finalized_at`
+
+func scanSeason(row interface{ Scan(...interface{}) error }) (*models.Season, error) {
+	s := &models.Season{}
+	var finalizedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.Name, &s.StartsAt, &s.EndsAt, &finalizedAt); err != nil {
+		return nil, err
+	}
+	switch {
+	case finalizedAt.Valid:
+		s.FinalizedAt = &finalizedAt.Time
+		s.Status = "completed"
+	case time.Now().Before(s.StartsAt):
+		s.Status = "upcoming"
+	case time.Now().Before(s.EndsAt):
+		s.Status = "active"
+	default:
+		s.Status = "ended"
+	}
+	return s, nil
+}
+
+func (r *SeasonRepo) Create(name string, startsAt, endsAt time.Time) (*models.Season, error) {
+	return scanSeason(r.db.QueryRow(
+		`INSERT INTO seasons (name, starts_at, ends_at) VALUES ($1, $2, $3) RETURNING `+seasonColumns,
+		name, startsAt, endsAt,
+	))
+}
+
+func (r *SeasonRepo) GetByID(id int) (*models.Season, error) {
+	return scanSeason(r.db.QueryRow(`SELECT `+seasonColumns+` FROM seasons WHERE id = $1`, id))
+}
+
+// GetLatest returns the season with the latest start, or nil if there are none.
+func (r *SeasonRepo) GetLatest() (*models.Season, error) {
+	season, err := scanSeason(r.db.QueryRow(`SELECT ` + seasonColumns + ` FROM seasons ORDER BY starts_at DESC LIMIT 1`))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return season, err
+}
+
+func (r *SeasonRepo) GetAll() ([]models.Season, error) {
+	rows, err := r.db.Query(`SELECT ` + seasonColumns + ` FROM seasons ORDER BY starts_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []models.Season
+	for rows.Next() {
+		s, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, *s)
+	}
+	return seasons, nil
+}
+
+// GetDueForFinalization returns seasons that have ended but whose standings aren't archived yet.
+func (r *SeasonRepo) GetDueForFinalization() ([]models.Season, error) {
+	rows, err := r.db.Query(
+		`SELECT ` + seasonColumns + ` FROM seasons WHERE finalized_at IS NULL AND ends_at <= NOW() ORDER BY ends_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seasons []models.Season
+	for rows.Next() {
+		s, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, *s)
+	}
+	return seasons, nil
+}
+
+func (r *SeasonRepo) Count() (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM seasons`).Scan(&count)
+	return count, err
+}
+
+// ComputeStandings measures every active user's portfolio value at the start and end of a
+// window from portfolio_snapshots. The start value is the last snapshot at or before startsAt,
+// or the first one after it for users who joined mid-season. Grub credited for anything other
+// than trading between the two snapshots (daily bonuses, achievement rewards and season
+// prizes) doesn't count toward the return. Results are unranked.
+func (r *SeasonRepo) ComputeStandings(startsAt, endsAt time.Time) ([]models.SeasonStanding, error) {
+	rows, err := r.db.Query(
+		`SELECT u.id, u.username, u.ticker, start_snap.total_value, last_in.total_value,
+		        COALESCE(bonuses.total, 0) + COALESCE(rewards.total, 0) + COALESCE(prizes.total, 0)
+		 FROM users u
+		 LEFT JOIN LATERAL (
+		     SELECT total_value, timestamp FROM portfolio_snapshots
+		     WHERE user_id = u.id AND timestamp <= $1 ORDER BY timestamp DESC LIMIT 1
+		 ) before_start ON true
+		 LEFT JOIN LATERAL (
+		     SELECT total_value, timestamp FROM portfolio_snapshots
+		     WHERE user_id = u.id AND timestamp > $1 AND timestamp <= $2 ORDER BY timestamp ASC LIMIT 1
+		 ) first_in ON true
+		 CROSS JOIN LATERAL (
+		     SELECT COALESCE(before_start.total_value, first_in.total_value) AS total_value,
+		            COALESCE(before_start.timestamp, first_in.timestamp) AS timestamp
+		 ) start_snap
+		 JOIN LATERAL (
+		     SELECT total_value, timestamp FROM portfolio_snapshots
+		     WHERE user_id = u.id AND timestamp <= $2 ORDER BY timestamp DESC LIMIT 1
+		 ) last_in ON true
+		 LEFT JOIN LATERAL (
+		     SELECT SUM(value) AS total FROM achievement_events
+		     WHERE user_id = u.id AND event_type = $3
+		       AND created_at > start_snap.timestamp AND created_at <= last_in.timestamp
+		 ) bonuses ON true
+		 LEFT JOIN LATERAL (
+		     SELECT SUM(a.reward) AS total FROM user_achievements ua
+		     JOIN achievements a ON a.id = ua.achievement_id
+		     WHERE ua.user_id = u.id
+		       AND ua.earned_at > start_snap.timestamp AND ua.earned_at <= last_in.timestamp
+		 ) rewards ON true
+		 LEFT JOIN LATERAL (
+		     SELECT SUM(ss.reward) AS total FROM season_standings ss
+		     JOIN seasons s ON s.id = ss.season_id
+		     WHERE ss.user_id = u.id
+		       AND s.finalized_at > start_snap.timestamp AND s.finalized_at <= last_in.timestamp
+		 ) prizes ON true
+		 WHERE u.deleted_at IS NULL AND u.username != 'MARKET'
+		   AND start_snap.total_value > 0`,
+		startsAt, endsAt, models.EventDailyClaim,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var standings []models.SeasonStanding
+	for rows.Next() {
+		var s models.SeasonStanding
+		var credits float64
+		if err := rows.Scan(&s.UserID, &s.Username, &s.Ticker, &s.StartValue, &s.EndValue, &credits); err != nil {
+			return nil, err
+		}
+		s.ReturnPercent = (s.EndValue - credits - s.StartValue) / s.StartValue * 100
+		standings = append(standings, s)
+	}
+	return standings, nil
+}
+
+func (r *SeasonRepo) SaveStanding(tx *sql.Tx, seasonID int, s models.SeasonStanding) error {
+	_, err := tx.Exec(
+		`INSERT INTO season_standings (season_id, user_id, rank, start_value, end_value, return_percent, reward, badge)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (season_id, user_id) DO NOTHING`,
+		seasonID, s.UserID, s.Rank, s.StartValue, s.EndValue, s.ReturnPercent, s.Reward, s.Badge,
+	)
+	return err
+}
+
+// MarkFinalized flags a season as finalized. It reports false if another run already did,
+// so the caller can roll back instead of paying rewards twice.
+func (r *SeasonRepo) MarkFinalized(tx *sql.Tx, seasonID int) (bool, error) {
+	result, err := tx.Exec(
+		`UPDATE seasons SET finalized_at = NOW() WHERE id = $1 AND finalized_at IS NULL`,
+		seasonID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetStandings returns a finalized season's archived standings, best first.
+func (r *SeasonRepo) GetStandings(seasonID int) ([]models.SeasonStanding, error) {
+	rows, err := r.db.Query(
+		`SELECT ss.rank, ss.user_id, u.username, u.ticker, ss.start_value, ss.end_value,
+		        ss.return_percent, ss.reward, ss.badge
+		 FROM season_standings ss
+		 JOIN users u ON ss.user_id = u.id
+		 WHERE ss.season_id = $1
+		 ORDER BY ss.rank`,
+		seasonID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var standings []models.SeasonStanding
+	for rows.Next() {
+		var s models.SeasonStanding
+		if err := rows.Scan(&s.Rank, &s.UserID, &s.Username, &s.Ticker, &s.StartValue, &s.EndValue,
+			&s.ReturnPercent, &s.Reward, &s.Badge); err != nil {
+			return nil, err
+		}
+		standings = append(standings, s)
+	}
+	return standings, nil
+}
+
+// GetBadges returns the season badges a user has earned, newest first.
+func (r *SeasonRepo) GetBadges(userID int) ([]models.SeasonBadge, error) {
+	rows, err := r.db.Query(
+		`SELECT s.id, s.name, ss.rank, ss.badge, ss.reward, s.finalized_at
+		 FROM season_standings ss
+		 JOIN seasons s ON ss.season_id = s.id
+		 WHERE ss.user_id = $1 AND ss.badge != ''
+		 ORDER BY s.ends_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var badges []models.SeasonBadge
+	for rows.Next() {
+		var b models.SeasonBadge
+		if err := rows.Scan(&b.SeasonID, &b.SeasonName, &b.Rank, &b.Badge, &b.Reward, &b.EarnedAt); err != nil {
+			return nil, err
+		}
+		badges = append(badges, b)
+	}
+	return badges, nil
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"grub-exchange/internal/models"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type TransactionRepo struct {
@@ -32,6 +34,12 @@ func (r *TransactionRepo) CreateNoTx(t *models.Transaction) error {
 	return err
 }
 
+// publicTraderName is the trader's name as other users see it, for queries that join the
+// trader as u1 and their privacy_settings as ps. Users who hide their trades are filtered out
+// by those queries instead.
+const publicTraderName = `CASE WHEN COALESCE(ps.anonymize_trades, false) THEN '` + models.AnonymousTrader + `' ELSE u1.username END`
+
+// GetByUser returns a user's own trades, newest first.
 func (r *TransactionRepo) GetByUser(userID int, limit int) ([]models.TransactionWithDetails, error) {
 	rows, err := r.db.Query(
 		`SELECT t.id, u1.username, u2.ticker, t.transaction_type, t.num_shares, t.price_per_share, t.total_grub, t.timestamp
@@ -59,13 +67,15 @@ func (r *TransactionRepo) GetByUser(userID int, limit int) ([]models.Transaction
 	return txns, nil
 }
 
+// GetByStock returns a stock's recent public trades, respecting the traders' privacy settings.
 func (r *TransactionRepo) GetByStock(stockUserID int, limit int) ([]models.TransactionWithDetails, error) {
 	rows, err := r.db.Query(
-		`SELECT t.id, u1.username, u2.ticker, t.transaction_type, t.num_shares, t.price_per_share, t.total_grub, t.timestamp
+		`SELECT t.id, `+publicTraderName+`, u2.ticker, t.transaction_type, t.num_shares, t.price_per_share, t.total_grub, t.timestamp
 		 FROM transactions t
 		 JOIN users u1 ON t.buyer_id = u1.id
 		 JOIN users u2 ON t.stock_user_id = u2.id
-		 WHERE t.stock_user_id = $1
+		 LEFT JOIN privacy_settings ps ON ps.user_id = t.buyer_id
+		 WHERE t.stock_user_id = $1 AND NOT COALESCE(ps.hide_trades, false)
 		 ORDER BY t.timestamp DESC LIMIT $2`,
 		stockUserID, limit,
 	)
@@ -86,12 +96,16 @@ func (r *TransactionRepo) GetByStock(stockUserID int, limit int) ([]models.Trans
 	return txns, nil
 }
 
+// GetRecent returns the latest public trades across the market, respecting the traders'
+// privacy settings.
 func (r *TransactionRepo) GetRecent(limit int) ([]models.TransactionWithDetails, error) {
 	rows, err := r.db.Query(
-		`SELECT t.id, u1.username, u2.ticker, t.transaction_type, t.num_shares, t.price_per_share, t.total_grub, t.timestamp
+		`SELECT t.id, `+publicTraderName+`, u2.ticker, t.transaction_type, t.num_shares, t.price_per_share, t.total_grub, t.timestamp
 		 FROM transactions t
 		 JOIN users u1 ON t.buyer_id = u1.id
 		 JOIN users u2 ON t.stock_user_id = u2.id
+		 LEFT JOIN privacy_settings ps ON ps.user_id = t.buyer_id
+		 WHERE NOT COALESCE(ps.hide_trades, false)
 		 ORDER BY t.timestamp DESC LIMIT $1`,
 		limit,
 	)
@@ -243,3 +257,62 @@ func (r *TransactionRepo) GetPricesAtBatch(at time.Time) (map[int]float64, error
 	}
 	return prices, nil
 }
+
+// GetPricesAtFor returns the price of each of the given stocks at a given time. Stocks with
+// no price history before then are left out.
+func (r *TransactionRepo) GetPricesAtFor(userIDs []int, at time.Time) (map[int]float64, error) {
+	rows, err := r.db.Query(
+		`SELECT DISTINCT ON (user_id) user_id, price
+		 FROM price_history
+		 WHERE user_id = ANY($1) AND timestamp <= $2
+		 ORDER BY user_id, timestamp DESC`,
+		pq.Array(userIDs), at,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[int]float64)
+	for rows.Next() {
+		var uid int
+		var price float64
+		if err := rows.Scan(&uid, &price); err != nil {
+			return nil, err
+		}
+		prices[uid] = price
+	}
+	return prices, nil
+}
+
+// GetBucketCloses splits the time since a given moment into buckets of equal length and
+// returns, for each of the given stocks, the last price recorded in each bucket that has
+// one, keyed by bucket index. Prices after the last bucket count toward it.
+func (r *TransactionRepo) GetBucketCloses(userIDs []int, since time.Time, bucket time.Duration, buckets int) (map[int]map[int]float64, error) {
+	rows, err := r.db.Query(
+		`SELECT DISTINCT ON (user_id, bucket) user_id,
+		        LEAST(FLOOR(EXTRACT(EPOCH FROM timestamp - $2) / $3)::integer, $4 - 1) AS bucket, price
+		 FROM price_history
+		 WHERE user_id = ANY($1) AND timestamp > $2
+		 ORDER BY user_id, bucket, timestamp DESC`,
+		pq.Array(userIDs), since, bucket.Seconds(), buckets,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	closes := make(map[int]map[int]float64)
+	for rows.Next() {
+		var uid, i int
+		var price float64
+		if err := rows.Scan(&uid, &i, &price); err != nil {
+			return nil, err
+		}
+		if closes[uid] == nil {
+			closes[uid] = make(map[int]float64)
+		}
+		closes[uid][i] = price
+	}
+	return closes, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+type TwoFactorRepo struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepo(db *sql.DB) *TwoFactorRepo {
+	return &TwoFactorRepo{db: db}
+}
+
+// Get returns the user's TOTP secret and whether 2FA is enabled.
+// Returns sql.ErrNoRows if the user never started enrollment.
+func (r *TwoFactorRepo) Get(userID int) (secret string, enabled bool, lastStep int64, err error) {
+	err = r.db.QueryRow(
+		`SELECT secret, enabled, last_used_step FROM user_two_factor WHERE user_id = $1`, userID,
+	).Scan(&secret, &enabled, &lastStep)
+	return
+}
+
+func (r *TwoFactorRepo) IsEnabled(userID int) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRow(
+		`SELECT enabled FROM user_two_factor WHERE user_id = $1`, userID,
+	).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// SavePendingSecret stores a fresh secret for enrollment. Fails silently (no rows) if 2FA is
+// already enabled, so an active secret can't be replaced without disabling first.
+func (r *TwoFactorRepo) SavePendingSecret(userID int, secret string) error {
+	_, err := r.db.Exec(
+		`INSERT INTO user_two_factor (user_id, secret, enabled, last_used_step, created_at)
+		 VALUES ($1, $2, false, 0, $3)
+		 ON CONFLICT (user_id) DO UPDATE SET secret = $2, last_used_step = 0, created_at = $3
+		 WHERE user_two_factor.enabled = false`,
+		userID, secret, time.Now(),
+	)
+	return err
+}
+
+// Enable turns on 2FA and replaces the user's recovery codes in one transaction.
+func (r *TwoFactorRepo) Enable(userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`UPDATE user_two_factor SET enabled = true, enabled_at = $1, last_used_step = $2 WHERE user_id = $3`,
+		time.Now(), step, userID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range recoveryCodeHashes {
+		if _, err := tx.Exec(
+			`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *TwoFactorRepo) Disable(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_two_factor WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkStepUsed records the last accepted time step. Returns false if the step was
+// already used (or an older one), which means the code is being replayed.
+func (r *TwoFactorRepo) MarkStepUsed(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE user_two_factor SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`,
+		step, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetLockedUntil returns when the user's 2FA lockout ends, or the zero time if it isn't locked.
+func (r *TwoFactorRepo) GetLockedUntil(userID int) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRow(
+		`SELECT locked_until FROM user_two_factor WHERE user_id = $1`, userID,
+	).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return lockedUntil.Time, err
+}
+
+// RecordFailure counts a wrong code. The maxAttempts-th failure in a row locks 2FA until
+// lockedUntil and starts the count over. Returns whether this failure caused a lockout.
+func (r *TwoFactorRepo) RecordFailure(userID, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	var locked bool
+	err := r.db.QueryRow(
+		`UPDATE user_two_factor SET
+		     failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		     locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $3 ELSE locked_until END
+		 WHERE user_id = $1
+		 RETURNING failed_attempts = 0`,
+		userID, maxAttempts, lockedUntil,
+	).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return locked, err
+}
+
+// ResetFailures clears the wrong-code count after a code is accepted.
+func (r *TwoFactorRepo) ResetFailures(userID int) error {
+	_, err := r.db.Exec(
+		`UPDATE user_two_factor SET failed_attempts = 0 WHERE user_id = $1 AND failed_attempts > 0`, userID,
+	)
+	return err
+}
+
+// UseRecoveryCode consumes a recovery code. Returns false if it doesn't exist or was already used.
+func (r *TwoFactorRepo) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE recovery_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`,
+		time.Now(), userID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *TwoFactorRepo) CountUnusedRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID,
+	).Scan(&count)
+	return count, err
+}
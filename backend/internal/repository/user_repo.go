@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
 	"grub-exchange/internal/models"
 	"time"
 )
@@ -51,27 +52,41 @@ func (r *UserRepo) GetByID(id int) (*models.User, error) {
 	))
 }
 
+// LockByID returns a user and locks their row until tx ends, so their stock's price can't
+// change in the meantime.
+func (r *UserRepo) LockByID(tx *sql.Tx, id int) (*models.User, error) {
+	return r.scanUser(tx.QueryRow(
+		`SELECT `+userSelectCols+` FROM users WHERE id = $1 FOR UPDATE`, id,
+	))
+}
+
 func (r *UserRepo) GetByEmail(email string) (*models.User, error) {
 	return r.scanUser(r.db.QueryRow(
 		`SELECT `+userSelectCols+` FROM users WHERE email = $1`, email,
 	))
 }
 
+// GetByTicker resolves a current ticker, falling back to old tickers from ticker_aliases
+// so links and posts that use a previous ticker still find the user.
 func (r *UserRepo) GetByTicker(ticker string) (*models.User, error) {
 	return r.scanUser(r.db.QueryRow(
-		`SELECT `+userSelectCols+` FROM users WHERE ticker = $1`, ticker,
+		`SELECT `+userSelectCols+` FROM users
+		 WHERE id = COALESCE(
+			 (SELECT id FROM users WHERE ticker = $1),
+			 (SELECT user_id FROM ticker_aliases WHERE old_ticker = $1)
+		 ) AND deleted_at IS NULL`, ticker,
 	))
 }
 
 func (r *UserRepo) GetByUsername(username string) (*models.User, error) {
 	return r.scanUser(r.db.QueryRow(
-		`SELECT `+userSelectCols+` FROM users WHERE username = $1`, username,
+		`SELECT `+userSelectCols+` FROM users WHERE username = $1 AND deleted_at IS NULL`, username,
 	))
 }
 
 func (r *UserRepo) GetAll() ([]models.User, error) {
 	rows, err := r.db.Query(
-		`SELECT `+userSelectCols+` FROM users WHERE username != 'MARKET' ORDER BY current_share_price DESC`,
+		`SELECT `+userSelectCols+` FROM users WHERE username != 'MARKET' AND deleted_at IS NULL ORDER BY current_share_price DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -128,6 +143,71 @@ func (r *UserRepo) UpdateLastLogin(userID int) error {
 	return err
 }
 
+// IsActive reports whether the user exists and hasn't closed their account.
+func (r *UserRepo) IsActive(userID int) (bool, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT COUNT(*) FROM users WHERE id = $1 AND deleted_at IS NULL`, userID,
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (r *UserRepo) IsAdmin(userID int) (bool, error) {
+	var isAdmin bool
+	err := r.db.QueryRow(
+		`SELECT is_admin FROM users WHERE id = $1 AND deleted_at IS NULL`, userID,
+	).Scan(&isAdmin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return isAdmin, err
+}
+
+// AnonymizeClosedAccount scrubs a closing user's PII and credentials. The users row itself
+// stays (with a placeholder name and ticker) so transactions, posts and votes that
+// reference it remain valid for everyone else.
+func (r *UserRepo) AnonymizeClosedAccount(tx *sql.Tx, userID int, finalPrice float64) error {
+	// Notifications others received still name the user; strip that first, while the old name is known
+	_, err := tx.Exec(
+		`UPDATE notifications n SET actor_username = '', message = REPLACE(n.message, u.username, 'A former user')
+		 FROM users u
+		 WHERE u.id = $1 AND n.actor_username = u.username`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	placeholder := fmt.Sprintf("deleted_%d", userID)
+	_, err = tx.Exec(
+		`UPDATE users SET username = $1, email = $2, password_hash = '', ticker = $3, bio = '',
+		        current_share_price = $4, deleted_at = $5
+		 WHERE id = $6`,
+		placeholder, placeholder+"@deleted.invalid", fmt.Sprintf("DELISTED%d", userID),
+		finalPrice, time.Now(), userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	cleanup := []string{
+		`DELETE FROM user_two_factor WHERE user_id = $1`,
+		`DELETE FROM recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM ticker_aliases WHERE user_id = $1`,
+		`DELETE FROM notifications WHERE user_id = $1`,
+		`DELETE FROM portfolio_snapshots WHERE user_id = $1`,
+		`UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+		`UPDATE balances SET grub_balance = 0 WHERE user_id = $1`,
+	}
+	for _, q := range cleanup {
+		if _, err := tx.Exec(q, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *UserRepo) ExistsByEmail(email string) (bool, error) {
 	var count int
 	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE email = $1`, email).Scan(&count)
@@ -140,16 +220,82 @@ func (r *UserRepo) ExistsByUsername(username string) (bool, error) {
 	return count > 0, err
 }
 
+// ExistsByTicker reports whether a ticker is in use, either as a current ticker or as an alias.
 func (r *UserRepo) ExistsByTicker(ticker string) (bool, error) {
 	var count int
-	err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE ticker = $1`, ticker).Scan(&count)
+	err := r.db.QueryRow(
+		`SELECT (SELECT COUNT(*) FROM users WHERE ticker = $1) + (SELECT COUNT(*) FROM ticker_aliases WHERE old_ticker = $1)`,
+		ticker,
+	).Scan(&count)
 	return count > 0, err
 }
 
+// TickerAvailableFor reports whether userID may take ticker: it must be unused,
+// or be one of the user's own old tickers.
+func (r *UserRepo) TickerAvailableFor(ticker string, userID int) (bool, error) {
+	var count int
+	err := r.db.QueryRow(
+		`SELECT (SELECT COUNT(*) FROM users WHERE ticker = $1) +
+		        (SELECT COUNT(*) FROM ticker_aliases WHERE old_ticker = $1 AND user_id != $2)`,
+		ticker, userID,
+	).Scan(&count)
+	return count == 0, err
+}
+
+func (r *UserRepo) GetTickerChangedAt(userID int) (*time.Time, error) {
+	var changedAt *time.Time
+	err := r.db.QueryRow(`SELECT ticker_changed_at FROM users WHERE id = $1`, userID).Scan(&changedAt)
+	return changedAt, err
+}
+
+// ChangeTicker moves a user to a new ticker and records the old one as an alias.
+func (r *UserRepo) ChangeTicker(tx *sql.Tx, userID int, oldTicker, newTicker string) error {
+	// Reclaiming one of your own old tickers: it stops being an alias
+	if _, err := tx.Exec(
+		`DELETE FROM ticker_aliases WHERE old_ticker = $1 AND user_id = $2`, newTicker, userID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO ticker_aliases (old_ticker, user_id, changed_at) VALUES ($1, $2, $3)`,
+		oldTicker, userID, time.Now(),
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(
+		`UPDATE users SET ticker = $1, ticker_changed_at = $2 WHERE id = $3`,
+		newTicker, time.Now(), userID,
+	)
+	return err
+}
+
+// GetAliases returns a user's previous tickers, most recent first.
+func (r *UserRepo) GetAliases(userID int) ([]string, error) {
+	rows, err := r.db.Query(
+		`SELECT old_ticker FROM ticker_aliases WHERE user_id = $1 ORDER BY changed_at DESC`, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aliases []string
+	for rows.Next() {
+		var a string
+		if err := rows.Scan(&a); err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, nil
+}
+
 func (r *UserRepo) GetStocksNotTradedSince(since time.Time) ([]models.User, error) {
 	rows, err := r.db.Query(
 		`SELECT `+userSelectCols+` FROM users u
-		 WHERE u.id NOT IN (
+		 WHERE u.deleted_at IS NULL AND u.id NOT IN (
 			 SELECT DISTINCT stock_user_id FROM transactions WHERE timestamp > $1
 		 )`, since,
 	)
@@ -206,3 +352,181 @@ func (r *UserRepo) GetPortfolioSnapshots(userID int, since time.Time) ([]models.
 	}
 	return snapshots, nil
 }
+
+// GetHourlyPortfolioSnapshots returns the last snapshot of each hour since the given time,
+// for charts that don't need minute-level detail.
+func (r *UserRepo) GetHourlyPortfolioSnapshots(userID int, since time.Time) ([]models.PortfolioSnapshot, error) {
+	rows, err := r.db.Query(
+		`SELECT DISTINCT ON (date_trunc('hour', timestamp)) id, user_id, total_value, grub_balance, timestamp
+		 FROM portfolio_snapshots
+		 WHERE user_id = $1 AND timestamp > $2
+		 ORDER BY date_trunc('hour', timestamp) ASC, timestamp DESC`,
+		userID, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.PortfolioSnapshot
+	for rows.Next() {
+		var s models.PortfolioSnapshot
+		if err := rows.Scan(&s.ID, &s.UserID, &s.TotalValue, &s.GrubBalance, &s.Timestamp); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, nil
+}
+
+// GetByIdentity returns the user linked to an external (OIDC) identity.
+func (r *UserRepo) GetByIdentity(issuer, subject string) (*models.User, error) {
+	return r.scanUser(r.db.QueryRow(
+		`SELECT `+userSelectCols+` FROM users
+		 WHERE id = (SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2)`,
+		issuer, subject,
+	))
+}
+
+// LinkIdentity attaches an external identity to a user, or refreshes its last login if already linked.
+func (r *UserRepo) LinkIdentity(userID int, issuer, subject, email string) error {
+	_, err := r.db.Exec(
+		`INSERT INTO user_identities (user_id, issuer, subject, email, last_login_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (issuer, subject) DO UPDATE SET email = $4, last_login_at = $5`,
+		userID, issuer, subject, email, time.Now(),
+	)
+	return err
+}
+
+// Search finds active traders by username, ticker or bio, best match first. Tickers equal to
+// or starting with tickerPrefix rank above other matches; an empty tickerPrefix disables
+// ticker prefix matching.
+func (r *UserRepo) Search(query, tickerPrefix string, limit, offset int) ([]models.SearchResult, error) {
+	rows, err := r.db.Query(
+		`SELECT id, username, ticker, COALESCE(bio, ''), current_share_price,
+		        CASE WHEN ticker = $2 THEN 2 WHEN $2 <> '' AND ticker LIKE $2 || '%' THEN 1 ELSE 0 END
+		        + GREATEST(ts_rank(search_vector, plainto_tsquery('simple', $1)),
+		                   ts_rank(search_vector, plainto_tsquery('english', $1))) AS rank
+		 FROM users
+		 WHERE deleted_at IS NULL AND username != 'MARKET'
+		   AND (($2 <> '' AND ticker LIKE $2 || '%')
+		        OR search_vector @@ plainto_tsquery('simple', $1)
+		        OR search_vector @@ plainto_tsquery('english', $1))
+		 ORDER BY rank DESC, id
+		 LIMIT $3 OFFSET $4`,
+		query, tickerPrefix, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		u := &models.SearchUser{}
+		result := models.SearchResult{Type: models.SearchTypeUser, User: u}
+		if err := rows.Scan(&u.ID, &u.Username, &u.Ticker, &u.Bio, &u.CurrentSharePrice, &result.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// stockListQuery computes each listed stock's price, 24h change, 24h volume (shares traded)
+// and market cap. Stocks with no price from 24 hours ago are compared against the 10 GRUB
+// listing price, like TransactionRepo.GetPriceAt.
+const stockListQuery = `
+	WITH stocks AS (
+	    SELECT u.id, u.username, u.ticker, u.current_share_price AS price,
+	           CASE WHEN COALESCE(p.price, 10.0) > 0
+	                THEN (u.current_share_price - COALESCE(p.price, 10.0)) / COALESCE(p.price, 10.0) * 100
+	                ELSE 0 END AS change_24h,
+	           COALESCE(v.volume, 0) AS volume,
+	           u.current_share_price * u.shares_outstanding AS market_cap
+	    FROM users u
+	    LEFT JOIN LATERAL (
+	        SELECT price FROM price_history
+	        WHERE user_id = u.id AND timestamp <= $1
+	        ORDER BY timestamp DESC LIMIT 1
+	    ) p ON TRUE
+	    LEFT JOIN LATERAL (
+	        SELECT SUM(num_shares) AS volume FROM transactions
+	        WHERE stock_user_id = u.id AND timestamp > $1
+	    ) v ON TRUE
+	    WHERE u.username != 'MARKET' AND u.deleted_at IS NULL
+	)
+	SELECT s.id, s.username, s.ticker, s.price, s.change_24h, s.volume, s.market_cap
+	FROM stocks s
+	WHERE TRUE`
+
+// stockSortColumns maps stock list sort keys to columns of stockListQuery. Newest sorts by
+// ID, which follows signup order.
+var stockSortColumns = map[string]string{
+	models.StockSortPrice:     "s.price",
+	models.StockSortChange24h: "s.change_24h",
+	models.StockSortVolume:    "s.volume",
+	models.StockSortMarketCap: "s.market_cap",
+	models.StockSortNewest:    "s.id",
+}
+
+// ListStocks returns up to limit stocks matching the query's filters in its sort order,
+// starting after the given cursor (or from the start if it's nil). Sparklines aren't filled.
+func (r *UserRepo) ListStocks(q models.StockListQuery, after *models.StockListCursor, limit int) ([]models.StockListItem, error) {
+	column, ok := stockSortColumns[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown stock sort %q", q.Sort)
+	}
+
+	query := stockListQuery
+	args := []interface{}{time.Now().Add(-24 * time.Hour)}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.MinPrice != nil {
+		query += ` AND s.price >= ` + arg(*q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		query += ` AND s.price <= ` + arg(*q.MaxPrice)
+	}
+	if q.MinVolume != nil {
+		query += ` AND s.volume >= ` + arg(*q.MinVolume)
+	}
+	if q.HeldBy != 0 {
+		query += ` AND EXISTS (SELECT 1 FROM portfolios WHERE owner_id = ` + arg(q.HeldBy) +
+			` AND stock_user_id = s.id AND num_shares > 0)`
+	}
+	if q.WatchedBy != 0 {
+		query += ` AND EXISTS (SELECT 1 FROM watchlists WHERE user_id = ` + arg(q.WatchedBy) +
+			` AND stock_user_id = s.id)`
+	}
+
+	direction, compare := "DESC", "<"
+	if q.Ascending {
+		direction, compare = "ASC", ">"
+	}
+	if after != nil {
+		query += fmt.Sprintf(` AND (%s, s.id) %s (%s::double precision, %s::integer)`,
+			column, compare, arg(after.Value), arg(after.ID))
+	}
+	query += fmt.Sprintf(` ORDER BY %s %s, s.id %s LIMIT %s`, column, direction, direction, arg(limit))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stocks []models.StockListItem
+	for rows.Next() {
+		var s models.StockListItem
+		if err := rows.Scan(&s.ID, &s.Username, &s.Ticker, &s.CurrentSharePrice,
+			&s.Change24hPercent, &s.Volume24h, &s.MarketCap); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, s)
+	}
+	return stocks, nil
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// getWatermark reads how far the named background job has processed, or the zero time if
+// it hasn't run yet.
+func getWatermark(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, name string) (time.Time, error) {
+	var watermark sql.NullTime
+	err := q.QueryRow(`SELECT watermark FROM job_watermarks WHERE name = $1`, name).Scan(&watermark)
+	if err != nil && err != sql.ErrNoRows {
+		return time.Time{}, err
+	}
+	return watermark.Time, nil
+}
+
+// setWatermark records how far the named background job has processed.
+func setWatermark(tx *sql.Tx, name string, watermark time.Time) error {
+	_, err := tx.Exec(
+		`INSERT INTO job_watermarks (name, watermark, updated_at) VALUES ($1, $2, NOW())
+		 ON CONFLICT (name) DO UPDATE SET watermark = EXCLUDED.watermark, updated_at = NOW()`,
+		name, watermark,
+	)
+	return err
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"log"
+	"math"
+	"time"
+)
+
+// DelistingPriceWindow is the lookback for the time-weighted average price paid to holders.
+const DelistingPriceWindow = 7 * 24 * time.Hour
+
+// AccountService handles closing accounts. Closing delists the user's stock:
+//   - holders are paid the 7-day time-weighted average price for every share. Payouts are
+//     issued by the exchange, the same source that funds dividends and daily bonuses, so they
+//     don't depend on the closing user's balance. Each one is recorded as a DELIST transaction
+//   - the user's own holdings are sold at normal execution prices so other stocks' prices react
+//   - the user's remaining Grub is forfeited, and their PII and credentials are scrubbed
+//
+// Transactions, posts and votes are kept, pointing at the anonymized user row.
+type AccountService struct {
+	db            *sql.DB
+	authSvc       *AuthService
+	userRepo      *repository.UserRepo
+	balanceRepo   *repository.BalanceRepo
+	portfolioRepo *repository.PortfolioRepo
+	txnRepo       *repository.TransactionRepo
+	notifRepo     *repository.NotificationRepo
+	leagueRepo    *repository.LeagueRepo
+	alertSvc      *AlertService
+}
+
+func NewAccountService(
+	db *sql.DB,
+	authSvc *AuthService,
+	userRepo *repository.UserRepo,
+	balanceRepo *repository.BalanceRepo,
+	portfolioRepo *repository.PortfolioRepo,
+	txnRepo *repository.TransactionRepo,
+	notifRepo *repository.NotificationRepo,
+	leagueRepo *repository.LeagueRepo,
+	alertSvc *AlertService,
+) *AccountService {
+	return &AccountService{
+		db:            db,
+		authSvc:       authSvc,
+		userRepo:      userRepo,
+		balanceRepo:   balanceRepo,
+		portfolioRepo: portfolioRepo,
+		txnRepo:       txnRepo,
+		notifRepo:     notifRepo,
+		leagueRepo:    leagueRepo,
+		alertSvc:      alertSvc,
+	}
+}
+
+// QuoteClosure previews the payouts and liquidations closing the account would trigger.
+func (s *AccountService) QuoteClosure(userID int) (*models.ClosureQuote, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is written; the transaction only holds the locks quoteClosure takes
+	defer tx.Rollback()
+
+	return s.quoteClosure(tx, userID)
+}
+
+// quoteClosure computes the closure quote, locking every row it reads the shares, prices
+// and balance from until tx ends. CloseAccount pays out this quote in the same transaction,
+// so trades can't change the holdings or prices between quoting and paying.
+func (s *AccountService) quoteClosure(tx *sql.Tx, userID int) (*models.ClosureQuote, error) {
+	user, err := s.userRepo.LockByID(tx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	now := time.Now()
+	from := now.Add(-DelistingPriceWindow)
+	startPrice, err := s.txnRepo.GetPriceAt(user.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.txnRepo.GetPriceHistory(user.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	payoutPrice := TimeWeightedAveragePrice(startPrice, history, from, now)
+
+	quote := &models.ClosureQuote{
+		Ticker:        user.Ticker,
+		PayoutPrice:   payoutPrice,
+		PayoutWindow:  "7d",
+		HolderPayouts: []models.ClosureHolderPayout{},
+		Liquidations:  []models.ClosureLiquidation{},
+	}
+
+	holders, err := s.portfolioRepo.LockByStock(tx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holders {
+		holder, err := s.userRepo.GetByID(h.OwnerID)
+		if err != nil {
+			continue
+		}
+		payout := math.Round(h.NumShares*payoutPrice*100) / 100
+		quote.HolderPayouts = append(quote.HolderPayouts, models.ClosureHolderPayout{
+			UserID:    h.OwnerID,
+			Username:  holder.Username,
+			NumShares: h.NumShares,
+			Payout:    payout,
+		})
+		quote.TotalPayout += payout
+	}
+
+	holdings, err := s.portfolioRepo.LockByOwner(tx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range holdings {
+		stockUser, err := s.userRepo.LockByID(tx, h.StockUserID)
+		if err != nil {
+			continue
+		}
+		_, execPrice := CalculateTradeExecution(stockUser.CurrentSharePrice, -h.NumShares, float64(stockUser.SharesOutstanding))
+		proceeds := math.Round(h.NumShares*execPrice*100) / 100
+		quote.Liquidations = append(quote.Liquidations, models.ClosureLiquidation{
+			StockUserID: stockUser.ID,
+			Ticker:      stockUser.Ticker,
+			NumShares:   h.NumShares,
+			Proceeds:    proceeds,
+		})
+		quote.GrubForfeited += proceeds
+	}
+
+	balance, err := s.balanceRepo.LockByUserID(tx, user.ID)
+	if err == nil {
+		quote.GrubForfeited += balance.GrubBalance
+	}
+
+	return quote, nil
+}
+
+// CloseAccount delists the user's stock and anonymizes the account in a single transaction.
+func (s *AccountService) CloseAccount(userID int, req *models.CloseAccountRequest) (*models.ClosureQuote, error) {
+	if req.Confirm != "DELETE" {
+		return nil, errors.New(`type DELETE to confirm closing your account`)
+	}
+	if err := s.authSvc.Reauthenticate(userID, req.Password, req.Code); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Username == "MARKET" {
+		return nil, errors.New("the market account cannot be closed")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	quote, err := s.quoteClosure(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Pay out every holder of the delisted stock
+	for _, p := range quote.HolderPayouts {
+		if err := s.balanceRepo.UpdateBalance(tx, p.UserID, p.Payout); err != nil {
+			return nil, err
+		}
+		if err := s.portfolioRepo.DeleteHolding(tx, p.UserID, userID); err != nil {
+			return nil, err
+		}
+		txn := &models.Transaction{
+			BuyerID:         p.UserID,
+			StockUserID:     userID,
+			TransactionType: "DELIST",
+			NumShares:       p.NumShares,
+			PricePerShare:   quote.PayoutPrice,
+			TotalGrub:       p.Payout,
+		}
+		if err := s.txnRepo.Create(tx, txn); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Sell the closing user's own holdings
+	var priceUpdates []models.PriceUpdate
+	for _, l := range quote.Liquidations {
+		// Already locked by quoteClosure, so the price is the one the quote used
+		stockUser, err := s.userRepo.LockByID(tx, l.StockUserID)
+		if err != nil {
+			return nil, err
+		}
+		newPrice, execPrice := CalculateTradeExecution(stockUser.CurrentSharePrice, -l.NumShares, float64(stockUser.SharesOutstanding))
+
+		if err := s.portfolioRepo.DeleteHolding(tx, userID, l.StockUserID); err != nil {
+			return nil, err
+		}
+		if err := s.userRepo.UpdateSharePrice(tx, l.StockUserID, newPrice); err != nil {
+			return nil, err
+		}
+		txn := &models.Transaction{
+			BuyerID:         userID,
+			StockUserID:     l.StockUserID,
+			TransactionType: "SELL",
+			NumShares:       l.NumShares,
+			PricePerShare:   execPrice,
+			TotalGrub:       math.Round(l.NumShares*execPrice*100) / 100,
+		}
+		if err := s.txnRepo.Create(tx, txn); err != nil {
+			return nil, err
+		}
+		if err := s.txnRepo.RecordPriceHistory(tx, l.StockUserID, newPrice); err != nil {
+			return nil, err
+		}
+		priceUpdates = append(priceUpdates, models.PriceUpdate{StockUserID: l.StockUserID, OldPrice: stockUser.CurrentSharePrice, NewPrice: newPrice})
+	}
+
+	// 3. Freeze the stock at the payout price and scrub the account
+	if err := s.txnRepo.RecordPriceHistory(tx, userID, quote.PayoutPrice); err != nil {
+		return nil, err
+	}
+	if err := s.leagueRepo.LeaveAll(tx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.AnonymizeClosedAccount(tx, userID, quote.PayoutPrice); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if s.alertSvc != nil {
+		s.alertSvc.OnPriceUpdates(priceUpdates)
+	}
+
+	log.Printf("Account %d closed: %d holders paid %.2f Grub", userID, len(quote.HolderPayouts), quote.TotalPayout)
+
+	if s.notifRepo != nil {
+		for _, p := range quote.HolderPayouts {
+			msg := fmt.Sprintf("%s was delisted. You received %.2f Grub for your %.2f shares.", quote.Ticker, p.Payout, p.NumShares)
+			_ = s.notifRepo.Create(p.UserID, "delisted", msg, "", quote.Ticker, p.NumShares)
+		}
+	}
+
+	return quote, nil
+}
@@ -1,16 +1,23 @@
 package services
 
 import (
+	"fmt"
 	"grub-exchange/internal/models"
 	"grub-exchange/internal/repository"
 	"log"
+	"math"
+	"time"
 )
 
+// AchievementService evaluates the declarative rules in the achievements table. Actions emit
+// events through RecordEvent; rules aggregate those events (optionally over a trailing window)
+// or measure the user's current state, and unlock once they reach their threshold.
 type AchievementService struct {
 	achievementRepo *repository.AchievementRepo
 	balanceRepo     *repository.BalanceRepo
 	portfolioRepo   *repository.PortfolioRepo
 	userRepo        *repository.UserRepo
+	notifRepo       *repository.NotificationRepo
 }
 
 func NewAchievementService(
@@ -18,122 +25,200 @@ func NewAchievementService(
 	balanceRepo *repository.BalanceRepo,
 	portfolioRepo *repository.PortfolioRepo,
 	userRepo *repository.UserRepo,
+	notifRepo *repository.NotificationRepo,
 ) *AchievementService {
 	return &AchievementService{
 		achievementRepo: achievementRepo,
 		balanceRepo:     balanceRepo,
 		portfolioRepo:   portfolioRepo,
 		userRepo:        userRepo,
+		notifRepo:       notifRepo,
 	}
 }
 
-// CheckAfterTrade checks and awards trade-related achievements
-func (s *AchievementService) CheckAfterTrade(userID int) []models.UserAchievement {
-	var newlyEarned []models.UserAchievement
-
-	// First Trade
-	if earned := s.checkFirstTrade(userID); earned != nil {
-		newlyEarned = append(newlyEarned, *earned)
-	}
-
-	// Day Trader (10 trades in one day)
-	if earned := s.checkDayTrader(userID); earned != nil {
-		newlyEarned = append(newlyEarned, *earned)
-	}
-
-	// Whale (portfolio worth 10,000+ Grub)
-	if earned := s.checkWhale(userID); earned != nil {
-		newlyEarned = append(newlyEarned, *earned)
+// RecordEvent stores an activity event and evaluates the achievements it can affect: rules on
+// that event type plus the state-based rules. It returns any newly earned achievements.
+func (s *AchievementService) RecordEvent(userID int, eventType string, value float64) []models.UserAchievement {
+	if err := s.achievementRepo.RecordEvent(userID, eventType, value); err != nil {
+		log.Printf("Error recording %s event for user %d: %v", eventType, userID, err)
+		return nil
 	}
+	return s.evaluate(userID, func(a models.Achievement) bool {
+		return a.EventType == eventType || !isEventMetric(a.Metric)
+	})
+}
 
-	return newlyEarned
+// EvaluateAll checks every achievement for a user. It runs periodically so rules that
+// progress without any action (holding days, portfolio value) still unlock.
+func (s *AchievementService) EvaluateAll(userID int) []models.UserAchievement {
+	return s.evaluate(userID, func(models.Achievement) bool { return true })
 }
 
-// CheckDiamondHands checks if a user has held a stock for 30+ days (called periodically)
-func (s *AchievementService) CheckDiamondHands(userID int) *models.UserAchievement {
-	has, _ := s.achievementRepo.HasAchievement(userID, "diamond_hands")
-	if has {
-		return nil
+// GetStatuses returns every achievement with the user's earned state, its rarity across
+// users and the user's progress toward it. Earned achievements show as complete.
+func (s *AchievementService) GetStatuses(userID int) ([]models.AchievementStatus, error) {
+	defs, err := s.GetAllAchievements()
+	if err != nil {
+		return nil, err
 	}
-
-	days, err := s.achievementRepo.GetOldestHoldingDays(userID)
-	if err != nil || days < 30 {
-		return nil
+	earned, err := s.achievementRepo.GetEarnedAt(userID)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := s.achievementRepo.Award(userID, "diamond_hands"); err != nil {
-		log.Printf("Error awarding diamond_hands to user %d: %v", userID, err)
-		return nil
+	earnCounts, totalUsers, err := s.achievementRepo.GetEarnCounts()
+	if err != nil {
+		return nil, err
 	}
 
-	return &models.UserAchievement{
-		UserID:        userID,
-		AchievementID: "diamond_hands",
-		Name:          "Diamond Hands",
-		Description:   "Hold a stock for 30+ days",
-		Icon:          "💎",
+	cache := make(map[string]float64)
+	statuses := make([]models.AchievementStatus, 0, len(defs))
+	for _, a := range defs {
+		status := models.AchievementStatus{Achievement: a, Target: a.Threshold}
+		if totalUsers > 0 {
+			status.RarityPercent = math.Round(float64(earnCounts[a.ID])/float64(totalUsers)*1000) / 10
+		}
+
+		if earnedAt, ok := earned[a.ID]; ok {
+			status.Earned = true
+			status.EarnedAt = &earnedAt
+			status.Current = a.Threshold
+		} else {
+			current, err := s.measure(userID, a, cache)
+			if err != nil {
+				return nil, err
+			}
+			status.Current = math.Min(math.Round(current*100)/100, a.Threshold)
+		}
+		statuses = append(statuses, status)
 	}
+	return statuses, nil
 }
 
-func (s *AchievementService) checkFirstTrade(userID int) *models.UserAchievement {
-	has, _ := s.achievementRepo.HasAchievement(userID, "first_trade")
-	if has {
+func (s *AchievementService) evaluate(userID int, applies func(models.Achievement) bool) []models.UserAchievement {
+	defs, err := s.achievementRepo.GetAll()
+	if err != nil {
+		log.Printf("Error loading achievements: %v", err)
 		return nil
 	}
-
-	if err := s.achievementRepo.Award(userID, "first_trade"); err != nil {
-		log.Printf("Error awarding first_trade to user %d: %v", userID, err)
+	earned, err := s.achievementRepo.GetEarnedAt(userID)
+	if err != nil {
+		log.Printf("Error loading achievements for user %d: %v", userID, err)
 		return nil
 	}
 
-	return &models.UserAchievement{
-		UserID:        userID,
-		AchievementID: "first_trade",
-		Name:          "First Trade",
-		Description:   "Execute your first buy or sell trade",
-		Icon:          "🎯",
+	var newlyEarned []models.UserAchievement
+	cache := make(map[string]float64)
+	for _, a := range defs {
+		if _, ok := earned[a.ID]; ok || !applies(a) {
+			continue
+		}
+
+		current, err := s.measure(userID, a, cache)
+		if err != nil {
+			log.Printf("Error measuring %s for user %d: %v", a.ID, userID, err)
+			continue
+		}
+		if current < a.Threshold {
+			continue
+		}
+
+		awarded, err := s.achievementRepo.Award(userID, a.ID, a.Reward)
+		if err != nil {
+			log.Printf("Error awarding %s to user %d: %v", a.ID, userID, err)
+			continue
+		}
+		if !awarded {
+			continue
+		}
+
+		newlyEarned = append(newlyEarned, models.UserAchievement{
+			UserID:        userID,
+			AchievementID: a.ID,
+			Name:          a.Name,
+			Description:   a.Description,
+			Icon:          a.Icon,
+			EarnedAt:      time.Now(),
+		})
+		s.notifyUnlock(userID, a)
 	}
+	return newlyEarned
 }
 
-func (s *AchievementService) checkDayTrader(userID int) *models.UserAchievement {
-	has, _ := s.achievementRepo.HasAchievement(userID, "day_trader")
-	if has {
-		return nil
+func (s *AchievementService) notifyUnlock(userID int, a models.Achievement) {
+	if s.notifRepo == nil {
+		return
 	}
-
-	count, err := s.achievementRepo.GetTradeCountToday(userID)
-	if err != nil || count < 10 {
-		return nil
+	msg := fmt.Sprintf("Achievement unlocked: %s %s!", a.Icon, a.Name)
+	if a.Reward > 0 {
+		msg = fmt.Sprintf("Achievement unlocked: %s %s! You earned %.2f Grub.", a.Icon, a.Name, a.Reward)
 	}
+	_ = s.notifRepo.Create(userID, "achievement", msg, "", "", 0)
+}
 
-	if err := s.achievementRepo.Award(userID, "day_trader"); err != nil {
-		log.Printf("Error awarding day_trader to user %d: %v", userID, err)
-		return nil
+// measure returns the user's current value of an achievement's metric. State metrics are
+// cached per evaluation since several rules can share one (e.g. Whale and Leviathan).
+func (s *AchievementService) measure(userID int, a models.Achievement, cache map[string]float64) (float64, error) {
+	if isEventMetric(a.Metric) {
+		var since time.Time
+		if a.WindowHours > 0 {
+			since = time.Now().Add(-time.Duration(a.WindowHours) * time.Hour)
+		}
+		count, sum, err := s.achievementRepo.AggregateEvents(userID, a.EventType, since)
+		if err != nil {
+			return 0, err
+		}
+		if a.Metric == models.MetricEventSum {
+			return sum, nil
+		}
+		return float64(count), nil
 	}
 
-	return &models.UserAchievement{
-		UserID:        userID,
-		AchievementID: "day_trader",
-		Name:          "Day Trader",
-		Description:   "Execute 10 trades in a single day",
-		Icon:          "⚡",
+	if v, ok := cache[a.Metric]; ok {
+		return v, nil
 	}
-}
 
-func (s *AchievementService) checkWhale(userID int) *models.UserAchievement {
-	has, _ := s.achievementRepo.HasAchievement(userID, "whale")
-	if has {
-		return nil
+	var v float64
+	switch a.Metric {
+	case models.MetricPortfolioValue:
+		value, err := s.portfolioValue(userID)
+		if err != nil {
+			return 0, err
+		}
+		v = value
+	case models.MetricHoldingDays:
+		days, err := s.achievementRepo.GetOldestHoldingDays(userID)
+		if err != nil {
+			return 0, err
+		}
+		v = float64(days)
+	case models.MetricDistinctHoldings:
+		holdings, err := s.portfolioRepo.GetByOwner(userID)
+		if err != nil {
+			return 0, err
+		}
+		for _, h := range holdings {
+			if h.NumShares > 0 {
+				v++
+			}
+		}
+	default:
+		return 0, fmt.Errorf("unknown achievement metric %q", a.Metric)
 	}
 
+	cache[a.Metric] = v
+	return v, nil
+}
+
+// portfolioValue is the user's Grub balance plus the market value of their holdings.
+func (s *AchievementService) portfolioValue(userID int) (float64, error) {
 	balance, err := s.balanceRepo.GetByUserID(userID)
 	if err != nil {
-		return nil
+		return 0, err
 	}
 
 	holdings, err := s.portfolioRepo.GetByOwner(userID)
 	if err != nil {
-		return nil
+		return 0, err
 	}
 
 	totalValue := balance.GrubBalance
@@ -144,23 +229,11 @@ func (s *AchievementService) checkWhale(userID int) *models.UserAchievement {
 		}
 		totalValue += h.NumShares * stockUser.CurrentSharePrice
 	}
+	return totalValue, nil
+}
 
-	if totalValue < 10000 {
-		return nil
-	}
-
-	if err := s.achievementRepo.Award(userID, "whale"); err != nil {
-		log.Printf("Error awarding whale to user %d: %v", userID, err)
-		return nil
-	}
-
-	return &models.UserAchievement{
-		UserID:        userID,
-		AchievementID: "whale",
-		Name:          "Whale",
-		Description:   "Portfolio worth 10,000+ Grub",
-		Icon:          "🐋",
-	}
+func isEventMetric(metric string) bool {
+	return metric == models.MetricEventCount || metric == models.MetricEventSum
 }
 
 func (s *AchievementService) GetUserAchievements(userID int) ([]models.UserAchievement, error) {
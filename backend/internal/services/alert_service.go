@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	maxWatchlistItems = 100
+	maxPriceAlerts    = 50
+
+	// Repeating alerts stay quiet this long after firing so a price hovering around the
+	// target doesn't send a notification every tick.
+	alertCooldown = time.Hour
+
+	maxAlertWindowMinutes = 30 * 24 * 60
+)
+
+// AlertService manages watchlists and price alerts, and checks alerts whenever prices move.
+type AlertService struct {
+	alertRepo *repository.AlertRepo
+	userRepo  *repository.UserRepo
+	txnRepo   *repository.TransactionRepo
+	notifRepo *repository.NotificationRepo
+}
+
+func NewAlertService(
+	alertRepo *repository.AlertRepo,
+	userRepo *repository.UserRepo,
+	txnRepo *repository.TransactionRepo,
+	notifRepo *repository.NotificationRepo,
+) *AlertService {
+	return &AlertService{
+		alertRepo: alertRepo,
+		userRepo:  userRepo,
+		txnRepo:   txnRepo,
+		notifRepo: notifRepo,
+	}
+}
+
+func (s *AlertService) GetWatchlist(userID int) ([]models.WatchlistItem, error) {
+	items, err := s.alertRepo.GetWatchlist(userID)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []models.WatchlistItem{}
+	}
+
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	for i := range items {
+		price24hAgo, _ := s.txnRepo.GetPriceAt(items[i].StockUserID, dayAgo)
+		if price24hAgo > 0 {
+			items[i].Change24hPercent = ((items[i].CurrentSharePrice - price24hAgo) / price24hAgo) * 100
+		}
+	}
+	return items, nil
+}
+
+func (s *AlertService) AddToWatchlist(userID int, ticker string) error {
+	stockUser, err := s.userRepo.GetByTicker(ticker)
+	if err != nil {
+		return errors.New("stock not found")
+	}
+
+	count, err := s.alertRepo.CountWatchlist(userID)
+	if err != nil {
+		return err
+	}
+	if count >= maxWatchlistItems {
+		return fmt.Errorf("watchlist is limited to %d stocks", maxWatchlistItems)
+	}
+	return s.alertRepo.AddToWatchlist(userID, stockUser.ID)
+}
+
+func (s *AlertService) RemoveFromWatchlist(userID int, ticker string) error {
+	stockUser, err := s.userRepo.GetByTicker(ticker)
+	if err != nil {
+		return errors.New("stock not found")
+	}
+
+	removed, err := s.alertRepo.RemoveFromWatchlist(userID, stockUser.ID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New("stock is not on your watchlist")
+	}
+	return nil
+}
+
+func (s *AlertService) GetAlerts(userID int) ([]models.PriceAlert, error) {
+	alerts, err := s.alertRepo.GetAlertsByUser(userID)
+	if alerts == nil {
+		alerts = []models.PriceAlert{}
+	}
+	return alerts, err
+}
+
+func (s *AlertService) CreateAlert(userID int, req *models.CreatePriceAlertRequest) (*models.PriceAlert, error) {
+	stockUser, err := s.userRepo.GetByTicker(req.Ticker)
+	if err != nil {
+		return nil, errors.New("stock not found")
+	}
+
+	alert := &models.PriceAlert{
+		UserID:      userID,
+		StockUserID: stockUser.ID,
+		StockTicker: stockUser.Ticker,
+		Condition:   strings.ToLower(req.Condition),
+		OneShot:     req.OneShot == nil || *req.OneShot,
+	}
+
+	switch alert.Condition {
+	case models.AlertAbove, models.AlertBelow:
+		if req.TargetPrice <= 0 {
+			return nil, errors.New("target_price must be positive")
+		}
+		alert.TargetPrice = req.TargetPrice
+	case models.AlertChange:
+		if req.ChangePercent == 0 {
+			return nil, errors.New("change_percent must be non-zero")
+		}
+		if req.WindowMinutes <= 0 || req.WindowMinutes > maxAlertWindowMinutes {
+			return nil, fmt.Errorf("window_minutes must be between 1 and %d", maxAlertWindowMinutes)
+		}
+		alert.ChangePercent = req.ChangePercent
+		alert.WindowMinutes = req.WindowMinutes
+	default:
+		return nil, errors.New("condition must be above, below or change")
+	}
+
+	count, err := s.alertRepo.CountAlerts(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxPriceAlerts {
+		return nil, fmt.Errorf("you can have at most %d price alerts", maxPriceAlerts)
+	}
+
+	if err := s.alertRepo.CreateAlert(alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+func (s *AlertService) SetAlertEnabled(userID, alertID int, enabled bool) error {
+	updated, err := s.alertRepo.SetAlertEnabled(alertID, userID, enabled)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.New("alert not found")
+	}
+	return nil
+}
+
+func (s *AlertService) DeleteAlert(userID, alertID int) error {
+	deleted, err := s.alertRepo.DeleteAlert(alertID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("alert not found")
+	}
+	return nil
+}
+
+// OnPriceUpdates checks the enabled alerts on the stocks whose prices just moved and fires
+// the ones whose condition the move satisfied. Repeating alerts fire when the price crosses
+// into their condition; one-shot alerts also fire if it already held, then disable themselves.
+func (s *AlertService) OnPriceUpdates(updates []models.PriceUpdate) {
+	if len(updates) == 0 {
+		return
+	}
+
+	byStock := make(map[int]models.PriceUpdate, len(updates))
+	stockIDs := make([]int, 0, len(updates))
+	for _, u := range updates {
+		if prev, ok := byStock[u.StockUserID]; ok {
+			// Several moves in one batch: evaluate the net move
+			u.OldPrice = prev.OldPrice
+		} else {
+			stockIDs = append(stockIDs, u.StockUserID)
+		}
+		byStock[u.StockUserID] = u
+	}
+
+	alerts, err := s.alertRepo.GetEnabledAlertsForStocks(stockIDs)
+	if err != nil {
+		log.Printf("Error loading price alerts: %v", err)
+		return
+	}
+
+	now := time.Now()
+	baselines := make(map[[2]int]float64) // (stock, window minutes) -> price at window start
+	for _, a := range alerts {
+		if !a.OneShot && a.LastTriggeredAt != nil && now.Sub(*a.LastTriggeredAt) < alertCooldown {
+			continue
+		}
+		update := byStock[a.StockUserID]
+
+		var baseline float64
+		if a.Condition == models.AlertChange {
+			key := [2]int{a.StockUserID, a.WindowMinutes}
+			var ok bool
+			if baseline, ok = baselines[key]; !ok {
+				baseline, err = s.txnRepo.GetPriceAt(a.StockUserID, now.Add(-time.Duration(a.WindowMinutes)*time.Minute))
+				if err != nil {
+					log.Printf("Error loading baseline price for alert %d: %v", a.ID, err)
+					continue
+				}
+				baselines[key] = baseline
+			}
+		}
+
+		if !alertConditionMet(a, update.NewPrice, baseline) {
+			continue
+		}
+		if !a.OneShot && alertConditionMet(a, update.OldPrice, baseline) {
+			continue
+		}
+
+		fired, err := s.alertRepo.MarkTriggered(a.ID)
+		if err != nil {
+			log.Printf("Error marking price alert %d triggered: %v", a.ID, err)
+			continue
+		}
+		if fired && s.notifRepo != nil {
+			_ = s.notifRepo.Create(a.UserID, "price_alert", alertMessage(a, update.NewPrice, baseline), "", a.StockTicker, 0)
+		}
+	}
+}
+
+// alertConditionMet reports whether price satisfies an alert. baseline is the price at the
+// start of a change alert's window.
+func alertConditionMet(a models.PriceAlert, price, baseline float64) bool {
+	switch a.Condition {
+	case models.AlertAbove:
+		return price >= a.TargetPrice
+	case models.AlertBelow:
+		return price <= a.TargetPrice
+	case models.AlertChange:
+		if baseline <= 0 {
+			return false
+		}
+		change := (price - baseline) / baseline * 100
+		if a.ChangePercent > 0 {
+			return change >= a.ChangePercent
+		}
+		return change <= a.ChangePercent
+	}
+	return false
+}
+
+func alertMessage(a models.PriceAlert, price, baseline float64) string {
+	switch a.Condition {
+	case models.AlertAbove:
+		return fmt.Sprintf("%s rose to %.2f Grub, above your %.2f alert", a.StockTicker, price, a.TargetPrice)
+	case models.AlertBelow:
+		return fmt.Sprintf("%s fell to %.2f Grub, below your %.2f alert", a.StockTicker, price, a.TargetPrice)
+	}
+	change := (price - baseline) / baseline * 100
+	return fmt.Sprintf("%s moved %+.1f%% in the last %s (now %.2f Grub)", a.StockTicker, change, formatAlertWindow(a.WindowMinutes), price)
+}
+
+func formatAlertWindow(minutes int) string {
+	switch {
+	case minutes%(24*60) == 0:
+		return fmt.Sprintf("%dd", minutes/(24*60))
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
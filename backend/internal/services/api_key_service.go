@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"grub-exchange/internal/utils"
+	"strings"
+)
+
+const (
+	maxActiveAPIKeys       = 10
+	defaultAPIKeyRateLimit = 60  // requests per minute
+	maxAPIKeyRateLimit     = 600 // requests per minute
+)
+
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepo
+}
+
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepo) *APIKeyService {
+	return &APIKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+// CreateKey issues a new key and returns it alongside the plaintext key, which is never stored.
+func (s *APIKeyService) CreateKey(userID int, req *models.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rateLimit := req.RateLimitPerMinute
+	if rateLimit == 0 {
+		rateLimit = defaultAPIKeyRateLimit
+	}
+	if rateLimit < 1 || rateLimit > maxAPIKeyRateLimit {
+		return nil, "", errors.New("rate_limit_per_minute must be between 1 and 600")
+	}
+
+	active, err := s.apiKeyRepo.CountActiveByUser(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if active >= maxActiveAPIKeys {
+		return nil, "", errors.New("too many active API keys, revoke one first")
+	}
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	created, err := s.apiKeyRepo.Create(userID, strings.TrimSpace(req.Name), prefix, utils.HashAPIKey(key), scopes, rateLimit)
+	if err != nil {
+		return nil, "", err
+	}
+	return created, key, nil
+}
+
+func (s *APIKeyService) ListKeys(userID int) ([]models.APIKey, error) {
+	keys, err := s.apiKeyRepo.GetByUser(userID)
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	return keys, err
+}
+
+func (s *APIKeyService) RevokeKey(userID, keyID int) error {
+	revoked, err := s.apiKeyRepo.Revoke(keyID, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("API key not found")
+	}
+	return nil
+}
+
+// normalizeScopes validates and de-duplicates the requested scopes, keeping a stable order.
+func normalizeScopes(requested []string) ([]string, error) {
+	want := make(map[string]bool)
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		valid := false
+		for _, v := range models.ValidScopes {
+			if scope == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, errors.New("invalid scope: " + scope + " (allowed: read, trade, post)")
+		}
+		want[scope] = true
+	}
+
+	var scopes []string
+	for _, v := range models.ValidScopes {
+		if want[v] {
+			scopes = append(scopes, v)
+		}
+	}
+	return scopes, nil
+}
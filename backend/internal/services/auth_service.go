@@ -3,7 +3,9 @@ package services
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"grub-exchange/internal/models"
+	"grub-exchange/internal/oidc"
 	"grub-exchange/internal/repository"
 	"grub-exchange/internal/utils"
 	"strings"
@@ -11,16 +13,28 @@ import (
 )
 
 type AuthService struct {
-	db          *sql.DB
-	userRepo    *repository.UserRepo
-	balanceRepo *repository.BalanceRepo
-	txnRepo     *repository.TransactionRepo
+	db            *sql.DB
+	userRepo      *repository.UserRepo
+	balanceRepo   *repository.BalanceRepo
+	txnRepo       *repository.TransactionRepo
+	twoFactorRepo *repository.TwoFactorRepo
+	tickerSvc     *TickerService
 }
 
-func NewAuthService(db *sql.DB, userRepo *repository.UserRepo, balanceRepo *repository.BalanceRepo, txnRepo *repository.TransactionRepo) *AuthService {
-	return &AuthService{db: db, userRepo: userRepo, balanceRepo: balanceRepo, txnRepo: txnRepo}
+func NewAuthService(db *sql.DB, userRepo *repository.UserRepo, balanceRepo *repository.BalanceRepo, txnRepo *repository.TransactionRepo, twoFactorRepo *repository.TwoFactorRepo, tickerSvc *TickerService) *AuthService {
+	return &AuthService{db: db, userRepo: userRepo, balanceRepo: balanceRepo, txnRepo: txnRepo, twoFactorRepo: twoFactorRepo, tickerSvc: tickerSvc}
 }
 
+const (
+	recoveryCodeCount = 10
+
+	// After this many wrong second-factor codes in a row, 2FA is locked for twoFactorLockout.
+	// The lockout outlasts a login challenge, so the challenge being guessed at is dead by
+	// the time it lifts.
+	maxTwoFactorAttempts = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
 func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserResponse, string, error) {
 	exists, err := s.userRepo.ExistsByEmail(req.Email)
 	if err != nil {
@@ -38,39 +52,70 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserRespons
 		return nil, "", errors.New("username already taken")
 	}
 
+	// A ticker derived from the first name is auto-suffixed on collision (the second
+	// "Alex" becomes ALEX2); an explicitly chosen one fails with suggestions instead.
+	chosen := req.Ticker != ""
 	ticker := utils.SanitizeTicker(req.FirstName)
+	if chosen {
+		ticker = utils.SanitizeTicker(req.Ticker)
+	}
 	if !utils.ValidateTicker(ticker) {
+		if chosen {
+			return nil, "", errors.New("ticker must be 2-15 letters, optionally followed by digits")
+		}
 		return nil, "", errors.New("invalid first name for ticker")
 	}
 
-	exists, err = s.userRepo.ExistsByTicker(ticker)
+	ticker, err = s.tickerSvc.Resolve(ticker, !chosen)
 	if err != nil {
 		return nil, "", err
 	}
-	if exists {
-		return nil, "", errors.New("ticker already exists, try a different name")
-	}
 
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// Atomic registration: create user, balance, and initial price history in one transaction
-	tx, err := s.db.Begin()
+	userID, err := s.createUser(req.Username, strings.ToLower(req.Email), hashedPassword, ticker)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := utils.GenerateToken(userID, req.Username)
 	if err != nil {
 		return nil, "", err
 	}
+
+	resp := &models.UserResponse{
+		ID:                userID,
+		Username:          req.Username,
+		Email:             strings.ToLower(req.Email),
+		Ticker:            ticker,
+		Bio:               "",
+		CurrentSharePrice: 10.0,
+		SharesOutstanding: 1000,
+		GrubBalance:       100.0,
+	}
+
+	return resp, token, nil
+}
+
+// createUser atomically creates the user, their starting balance and initial price history.
+func (s *AuthService) createUser(username, email, passwordHash, ticker string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
 	defer tx.Rollback()
 
 	var userID int64
 	err = tx.QueryRow(
 		`INSERT INTO users (username, email, password_hash, ticker, bio, current_share_price, shares_outstanding, created_at)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
-		req.Username, strings.ToLower(req.Email), hashedPassword, ticker, "", 10.0, 1000, time.Now(),
+		username, email, passwordHash, ticker, "", 10.0, 1000, time.Now(),
 	).Scan(&userID)
 	if err != nil {
-		return nil, "", err
+		return 0, err
 	}
 
 	_, err = tx.Exec(
@@ -78,7 +123,7 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserRespons
 		userID, 100.0,
 	)
 	if err != nil {
-		return nil, "", err
+		return 0, err
 	}
 
 	_, err = tx.Exec(
@@ -86,42 +131,203 @@ func (s *AuthService) Register(req *models.RegisterRequest) (*models.UserRespons
 		userID, 10.0, time.Now(),
 	)
 	if err != nil {
-		return nil, "", err
+		return 0, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, "", err
+		return 0, err
+	}
+	return int(userID), nil
+}
+
+// Login checks credentials. If the user has 2FA enabled, no session is issued: the
+// returned challenge token must be exchanged for a session via CompleteTwoFactorLogin.
+func (s *AuthService) Login(req *models.LoginRequest) (*models.UserResponse, string, string, error) {
+	u, err := s.userRepo.GetByEmail(strings.ToLower(req.Email))
+	if err != nil {
+		return nil, "", "", errors.New("invalid email or password")
+	}
+
+	if !utils.CheckPassword(u.PasswordHash, req.Password) {
+		return nil, "", "", errors.New("invalid email or password")
 	}
 
-	token, err := utils.GenerateToken(int(userID), req.Username)
+	enabled, err := s.twoFactorRepo.IsEnabled(u.ID)
 	if err != nil {
+		return nil, "", "", err
+	}
+	if enabled {
+		challenge, err := utils.GenerateChallengeToken(u.ID, u.Username)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return nil, "", challenge, nil
+	}
+
+	user, token, err := s.issueSession(u)
+	return user, token, "", err
+}
+
+// CompleteTwoFactorLogin exchanges a challenge token plus a TOTP or recovery code for a session.
+func (s *AuthService) CompleteTwoFactorLogin(req *models.TwoFactorLoginRequest) (*models.UserResponse, string, error) {
+	claims, err := utils.ValidateChallengeToken(req.ChallengeToken, utils.PurposeTwoFactor)
+	if err != nil {
+		return nil, "", errors.New("login challenge expired, please sign in again")
+	}
+
+	if err := s.verifySecondFactor(claims.UserID, req.Code); err != nil {
 		return nil, "", err
 	}
 
-	resp := &models.UserResponse{
-		ID:                int(userID),
-		Username:          req.Username,
-		Email:             strings.ToLower(req.Email),
-		Ticker:            ticker,
-		Bio:               "",
-		CurrentSharePrice: 10.0,
-		SharesOutstanding: 1000,
-		GrubBalance:       100.0,
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, "", errors.New("user not found")
 	}
 
-	return resp, token, nil
+	return s.issueSession(user)
 }
 
-func (s *AuthService) Login(req *models.LoginRequest) (*models.UserResponse, string, error) {
-	user, err := s.userRepo.GetByEmail(strings.ToLower(req.Email))
+// LoginWithOIDC signs in a user from a verified OIDC identity. Lookup order:
+//  1. an identity already linked to a user
+//  2. an existing user with the same email, if the IdP says the email is verified (then linked)
+//  3. otherwise a new account is registered with a derived username and ticker
+//
+// Like Login, it returns a challenge token instead of a session when 2FA is enabled.
+func (s *AuthService) LoginWithOIDC(identity *oidc.Identity) (*models.UserResponse, string, string, error) {
+	if identity.Subject == "" {
+		return nil, "", "", errors.New("identity provider did not return a subject")
+	}
+
+	user, err := s.userRepo.GetByIdentity(identity.Issuer, identity.Subject)
+	if err == sql.ErrNoRows {
+		user, err = s.linkOrRegisterOIDC(identity)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if err := s.userRepo.LinkIdentity(user.ID, identity.Issuer, identity.Subject, identity.Email); err != nil {
+		return nil, "", "", err
+	}
+
+	enabled, err := s.twoFactorRepo.IsEnabled(user.ID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if enabled {
+		challenge, err := utils.GenerateChallengeToken(user.ID, user.Username)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return nil, "", challenge, nil
+	}
+
+	resp, token, err := s.issueSession(user)
+	return resp, token, "", err
+}
+
+func (s *AuthService) linkOrRegisterOIDC(identity *oidc.Identity) (*models.User, error) {
+	if identity.Email == "" {
+		return nil, errors.New("identity provider did not share an email address")
+	}
+
+	existing, err := s.userRepo.GetByEmail(identity.Email)
+	if err == nil {
+		// Only link by email when the IdP vouches for it, otherwise anyone could
+		// claim an existing account by registering that address with the IdP.
+		if !identity.EmailVerified {
+			return nil, errors.New("an account with this email exists; verify your email with your identity provider to link it")
+		}
+		return existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	username, err := s.availableUsername(oidcUsernameCandidate(identity))
+	if err != nil {
+		return nil, err
+	}
+
+	ticker, err := s.tickerSvc.Resolve(oidcTickerCandidate(identity, username), true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Empty password hash: SSO-only accounts can't sign in with a password
+	userID, err := s.createUser(username, identity.Email, "", ticker)
 	if err != nil {
-		return nil, "", errors.New("invalid email or password")
+		return nil, err
 	}
+	return s.userRepo.GetByID(userID)
+}
 
-	if !utils.CheckPassword(user.PasswordHash, req.Password) {
-		return nil, "", errors.New("invalid email or password")
+// oidcUsernameCandidate picks a base username from the IdP claims, reduced to the
+// characters ValidateUsername accepts.
+func oidcUsernameCandidate(identity *oidc.Identity) string {
+	raw := identity.PreferredUsername
+	if raw == "" {
+		raw = strings.SplitN(identity.Email, "@", 2)[0]
 	}
 
+	var b strings.Builder
+	for _, r := range raw {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	username := b.String()
+	if len(username) > 16 {
+		username = username[:16]
+	}
+	for len(username) < 3 {
+		username += "_"
+	}
+	return username
+}
+
+// oidcTickerCandidate derives the ticker the same way registration does: from the first name.
+func oidcTickerCandidate(identity *oidc.Identity, username string) string {
+	firstName := identity.GivenName
+	if firstName == "" {
+		firstName = strings.SplitN(strings.TrimSpace(identity.Name), " ", 2)[0]
+	}
+
+	ticker := utils.SanitizeTicker(firstName)
+	if !utils.ValidateTicker(ticker) {
+		// Fall back to the letters of the username
+		var b strings.Builder
+		for _, r := range username {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+				b.WriteRune(r)
+			}
+		}
+		ticker = utils.SanitizeTicker(b.String())
+	}
+	if len(ticker) > 13 {
+		ticker = ticker[:13]
+	}
+	return ticker
+}
+
+// availableUsername returns base, or base with a numeric suffix if it's taken.
+func (s *AuthService) availableUsername(base string) (string, error) {
+	candidate := base
+	for i := 2; i < 1000; i++ {
+		exists, err := s.userRepo.ExistsByUsername(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists && utils.ValidateUsername(candidate) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", errors.New("could not find an available username")
+}
+
+// issueSession records the login and returns the user payload with a fresh session JWT.
+func (s *AuthService) issueSession(user *models.User) (*models.UserResponse, string, error) {
 	if err := s.userRepo.UpdateLastLogin(user.ID); err != nil {
 		return nil, "", err
 	}
@@ -152,6 +358,162 @@ func (s *AuthService) Login(req *models.LoginRequest) (*models.UserResponse, str
 	return resp, token, nil
 }
 
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code. Wrong
+// codes count toward a temporary lockout, so codes can't be guessed by brute force.
+func (s *AuthService) verifySecondFactor(userID int, code string) error {
+	secret, enabled, _, err := s.twoFactorRepo.Get(userID)
+	if err != nil || !enabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	lockedUntil, err := s.twoFactorRepo.GetLockedUntil(userID)
+	if err != nil {
+		return err
+	}
+	if time.Now().Before(lockedUntil) {
+		return errors.New("too many invalid codes, try again later")
+	}
+
+	if step, ok := utils.ValidateTOTP(secret, code, time.Now()); ok {
+		fresh, err := s.twoFactorRepo.MarkStepUsed(userID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errors.New("code already used, wait for the next one")
+		}
+		return s.twoFactorRepo.ResetFailures(userID)
+	}
+
+	used, err := s.twoFactorRepo.UseRecoveryCode(userID, utils.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		locked, err := s.twoFactorRepo.RecordFailure(userID, maxTwoFactorAttempts, time.Now().Add(twoFactorLockout))
+		if err != nil {
+			return err
+		}
+		if locked {
+			return errors.New("too many invalid codes, try again later")
+		}
+		return errors.New("invalid verification code")
+	}
+	return s.twoFactorRepo.ResetFailures(userID)
+}
+
+// Reauthenticate confirms a sensitive action: the password (unless the account is
+// SSO-only and has none) and, when 2FA is enabled, a second-factor code. An SSO-only
+// account without 2FA has nothing to prove itself with beyond the session, so it's
+// refused until 2FA is enabled.
+func (s *AuthService) Reauthenticate(userID int, password, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if user.PasswordHash != "" && !utils.CheckPassword(user.PasswordHash, password) {
+		return errors.New("invalid password")
+	}
+
+	enabled, err := s.twoFactorRepo.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		if code == "" {
+			return errors.New("two-factor code required")
+		}
+		return s.verifySecondFactor(userID, code)
+	}
+	if user.PasswordHash == "" {
+		return errors.New("enable two-factor authentication to confirm this action")
+	}
+	return nil
+}
+
+// BeginTwoFactorEnrollment generates a new secret for the user. 2FA stays off until
+// the user proves they can generate codes via ConfirmTwoFactorEnrollment.
+func (s *AuthService) BeginTwoFactorEnrollment(userID int) (*models.TwoFactorEnrollment, error) {
+	enabled, err := s.twoFactorRepo.IsEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SavePendingSecret(userID, secret); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPProvisioningURI(secret, user.Email),
+	}, nil
+}
+
+// ConfirmTwoFactorEnrollment verifies the first code from the authenticator app, enables 2FA
+// and returns the plaintext recovery codes. They are only ever shown this once.
+func (s *AuthService) ConfirmTwoFactorEnrollment(userID int, code string) ([]string, error) {
+	secret, enabled, _, err := s.twoFactorRepo.Get(userID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("start enrollment first")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, errors.New("invalid verification code")
+	}
+
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = utils.HashRecoveryCode(c)
+	}
+
+	if err := s.twoFactorRepo.Enable(userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor requires the password (unless the account is SSO-only) and a valid
+// second factor.
+func (s *AuthService) DisableTwoFactor(userID int, req *models.DisableTwoFactorRequest) error {
+	if err := s.Reauthenticate(userID, req.Password, req.Code); err != nil {
+		return err
+	}
+	return s.twoFactorRepo.Disable(userID)
+}
+
+// GetTwoFactorStatus reports whether 2FA is on and how many recovery codes remain.
+func (s *AuthService) GetTwoFactorStatus(userID int) (bool, int, error) {
+	enabled, err := s.twoFactorRepo.IsEnabled(userID)
+	if err != nil || !enabled {
+		return false, 0, err
+	}
+	remaining, err := s.twoFactorRepo.CountUnusedRecoveryCodes(userID)
+	return true, remaining, err
+}
+
 func (s *AuthService) GetMe(userID int) (*models.UserResponse, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"log"
+	"math"
+	"time"
+)
+
+const (
+	// Rows are rolled up once they're this old, so trades still committing with an earlier
+	// timestamp aren't skipped
+	candleRollupLag = 30 * time.Second
+	// Each roll-up transaction covers at most this much history, keeping the first backfill
+	// from holding one huge transaction
+	candleRollupSpan = 24 * time.Hour
+
+	defaultCandleCount = 200
+	maxCandleCount     = 1000
+)
+
+// ErrInvalidCandleRange is returned for an unknown interval, a from after to, or a range
+// spanning too many candles.
+var ErrInvalidCandleRange = errors.New("invalid candle range")
+
+// CandleService serves OHLCV candles. Completed history comes from the price_candles rollup,
+// which RollUp maintains incrementally; the few seconds after its watermark are aggregated
+// from the raw tables on each request.
+type CandleService struct {
+	userRepo   *repository.UserRepo
+	candleRepo *repository.CandleRepo
+}
+
+func NewCandleService(userRepo *repository.UserRepo, candleRepo *repository.CandleRepo) *CandleService {
+	return &CandleService{userRepo: userRepo, candleRepo: candleRepo}
+}
+
+// RollUp folds new price history and trades into the candle rollup, catching up in steps
+// of candleRollupSpan.
+func (s *CandleService) RollUp() {
+	upTo := time.Now().Add(-candleRollupLag)
+	for {
+		watermark, rolled, err := s.candleRepo.RollUp(models.CandleIntervals, upTo, candleRollupSpan)
+		if err != nil {
+			log.Printf("Error rolling up price candles: %v", err)
+			return
+		}
+		if !rolled || !watermark.Before(upTo) {
+			return
+		}
+	}
+}
+
+// GetCandles returns a stock's candles at the given interval for candles starting in
+// [from, to). A zero to means now and a zero from means defaultCandleCount intervals before to.
+func (s *CandleService) GetCandles(ticker, interval string, from, to time.Time) (*models.StockCandles, error) {
+	from, to, err := candleRange(interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByTicker(ticker)
+	if err != nil {
+		return nil, err
+	}
+	candles, err := s.loadCandles(user.ID, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StockCandles{
+		Ticker:   user.Ticker,
+		Interval: interval,
+		From:     from,
+		To:       to,
+		Candles:  candles,
+	}, nil
+}
+
+// candleRange validates a candle request's interval and range, filling in the defaults and
+// aligning from to the interval.
+func candleRange(interval string, from, to time.Time) (time.Time, time.Time, error) {
+	size, ok := models.CandleIntervals[interval]
+	if !ok {
+		return from, to, fmt.Errorf("%w: interval must be one of 1m, 5m, 1h, 1d", ErrInvalidCandleRange)
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultCandleCount * size)
+	}
+	from = from.Truncate(size)
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("%w: from must be before to", ErrInvalidCandleRange)
+	}
+	if to.Sub(from) > maxCandleCount*size {
+		return from, to, fmt.Errorf("%w: at most %d candles per request", ErrInvalidCandleRange, maxCandleCount)
+	}
+	return from, to, nil
+}
+
+// loadCandles returns a stock's candles starting in [from, to), combining the rollup with
+// the rows after its watermark. interval must be one of models.CandleIntervals.
+func (s *CandleService) loadCandles(stockUserID int, interval string, from, to time.Time) ([]models.Candle, error) {
+	watermark, err := s.candleRepo.GetWatermark()
+	if err != nil {
+		return nil, err
+	}
+	rolledUp, err := s.candleRepo.GetCandles(stockUserID, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := rolledUp
+	if watermark.Before(to) {
+		after := watermark
+		if after.Before(from) {
+			after = from.Add(-time.Microsecond)
+		}
+		// The raw rows end just before to, so candles starting at or after to are impossible
+		recent, err := s.candleRepo.AggregateCandles(stockUserID, models.CandleIntervals[interval], after, to.Add(-time.Microsecond))
+		if err != nil {
+			return nil, err
+		}
+		candles = mergeCandles(rolledUp, recent)
+	}
+	if candles == nil {
+		candles = []models.Candle{}
+	}
+	return candles, nil
+}
+
+// mergeCandles appends candles aggregated after the watermark to the rolled-up ones. The
+// candle straddling the watermark appears in both halves and is combined.
+func mergeCandles(rolledUp, recent []models.Candle) []models.Candle {
+	if len(rolledUp) == 0 || len(recent) == 0 {
+		return append(rolledUp, recent...)
+	}
+	last := &rolledUp[len(rolledUp)-1]
+	if !last.Time.Equal(recent[0].Time) {
+		return append(rolledUp, recent...)
+	}
+
+	first := recent[0]
+	last.High = math.Max(last.High, first.High)
+	last.Low = math.Min(last.Low, first.Low)
+	last.Close = first.Close
+	last.Volume += first.Volume
+	last.Trades += first.Trades
+	return append(rolledUp, recent[1:]...)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"grub-exchange/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCandleRange(t *testing.T) {
+	to := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval string
+		from, to time.Time
+		wantFrom time.Time
+	}{
+		{"from is truncated to the interval", "1h", to.Add(-90 * time.Minute), to, to.Add(-2 * time.Hour)},
+		{"aligned from is kept", "5m", to.Add(-time.Hour), to, to.Add(-time.Hour)},
+		{"zero from is defaultCandleCount intervals back", "1m", time.Time{}, to, to.Add(-defaultCandleCount * time.Minute)},
+		{"exactly maxCandleCount candles", "1m", to.Add(-maxCandleCount * time.Minute), to, to.Add(-maxCandleCount * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, gotTo, err := candleRange(tt.interval, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("candleRange() error = %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !gotTo.Equal(tt.to) {
+				t.Errorf("candleRange() = [%v, %v), want [%v, %v)", from, gotTo, tt.wantFrom, tt.to)
+			}
+		})
+	}
+}
+
+func TestCandleRangeRejects(t *testing.T) {
+	to := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval string
+		from, to time.Time
+	}{
+		{"unknown interval", "2h", to.Add(-time.Hour), to},
+		{"from after to", "1m", to.Add(time.Minute), to},
+		{"from equal to to", "1m", to, to},
+		{"more than maxCandleCount candles", "1m", to.Add(-(maxCandleCount + 1) * time.Minute), to},
+		// from is exactly maxCandleCount hours back until it is truncated to the hour
+		{"truncation pushes past maxCandleCount", "1h", to.Add(30*time.Minute - maxCandleCount*time.Hour), to.Add(30 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := candleRange(tt.interval, tt.from, tt.to); !errors.Is(err, ErrInvalidCandleRange) {
+				t.Errorf("candleRange() error = %v, want ErrInvalidCandleRange", err)
+			}
+		})
+	}
+}
+
+func TestMergeCandles(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2026, 3, 1, 12, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name             string
+		rolledUp, recent []models.Candle
+		want             []models.Candle
+	}{
+		{
+			name: "both halves empty",
+			want: nil,
+		},
+		{
+			name:     "nothing after the watermark",
+			rolledUp: []models.Candle{{Time: at(0), Open: 10, High: 11, Low: 9, Close: 10, Volume: 5, Trades: 2}},
+			want:     []models.Candle{{Time: at(0), Open: 10, High: 11, Low: 9, Close: 10, Volume: 5, Trades: 2}},
+		},
+		{
+			name:   "nothing rolled up",
+			recent: []models.Candle{{Time: at(1), Open: 10, High: 11, Low: 9, Close: 10, Volume: 5, Trades: 2}},
+			want:   []models.Candle{{Time: at(1), Open: 10, High: 11, Low: 9, Close: 10, Volume: 5, Trades: 2}},
+		},
+		{
+			name: "separate buckets are appended",
+			rolledUp: []models.Candle{
+				{Time: at(0), Open: 10, High: 11, Low: 9, Close: 10, Volume: 5, Trades: 2},
+			},
+			recent: []models.Candle{
+				{Time: at(1), Open: 10, High: 12, Low: 10, Close: 12, Volume: 3, Trades: 1},
+			},
+			want: []models.Candle{
+				{Time: at(0), Open: 10, High: 11, Low: 9, Close: 10, Volume: 5, Trades: 2},
+				{Time: at(1), Open: 10, High: 12, Low: 10, Close: 12, Volume: 3, Trades: 1},
+			},
+		},
+		{
+			// The rolled-up open is kept, the extremes widen, the recent close wins and
+			// volume and trades add up
+			name: "a straddling bucket is combined",
+			rolledUp: []models.Candle{
+				{Time: at(0), Open: 9, High: 10, Low: 9, Close: 10, Volume: 1, Trades: 1},
+				{Time: at(1), Open: 10, High: 11, Low: 9, Close: 10, Volume: 5, Trades: 2},
+			},
+			recent: []models.Candle{
+				{Time: at(1), Open: 10, High: 12, Low: 8, Close: 11, Volume: 3, Trades: 4},
+				{Time: at(2), Open: 11, High: 11, Low: 11, Close: 11, Volume: 1, Trades: 1},
+			},
+			want: []models.Candle{
+				{Time: at(0), Open: 9, High: 10, Low: 9, Close: 10, Volume: 1, Trades: 1},
+				{Time: at(1), Open: 10, High: 12, Low: 8, Close: 11, Volume: 8, Trades: 6},
+				{Time: at(2), Open: 11, High: 11, Low: 11, Close: 11, Volume: 1, Trades: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeCandles(tt.rolledUp, tt.recent); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeCandles() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a page cursor that wasn't issued by the same listing.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// FeedService manages the follow graph and builds each user's activity feed from the
+// trades, public posts, achievements and big portfolio moves of the people they follow.
+type FeedService struct {
+	followRepo *repository.FollowRepo
+	userRepo   *repository.UserRepo
+	notifRepo  *repository.NotificationRepo
+}
+
+func NewFeedService(
+	followRepo *repository.FollowRepo,
+	userRepo *repository.UserRepo,
+	notifRepo *repository.NotificationRepo,
+) *FeedService {
+	return &FeedService{
+		followRepo: followRepo,
+		userRepo:   userRepo,
+		notifRepo:  notifRepo,
+	}
+}
+
+func (s *FeedService) Follow(userID int, username string) error {
+	target, err := s.userRepo.GetByUsername(username)
+	if err != nil || target.Username == "MARKET" {
+		return errors.New("user not found")
+	}
+	if target.ID == userID {
+		return errors.New("you can't follow yourself")
+	}
+
+	followed, err := s.followRepo.Follow(userID, target.ID)
+	if err != nil {
+		return err
+	}
+
+	if followed && s.notifRepo != nil {
+		follower, _ := s.userRepo.GetByID(userID)
+		if follower != nil {
+			msg := fmt.Sprintf("%s started following you", follower.Username)
+			_ = s.notifRepo.Create(target.ID, "follow", msg, follower.Username, "", 0)
+		}
+	}
+	return nil
+}
+
+func (s *FeedService) Unfollow(userID int, username string) error {
+	target, err := s.userRepo.GetByUsername(username)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	unfollowed, err := s.followRepo.Unfollow(userID, target.ID)
+	if err != nil {
+		return err
+	}
+	if !unfollowed {
+		return errors.New("you don't follow this user")
+	}
+	return nil
+}
+
+func (s *FeedService) GetFollowing(userID int) ([]models.FollowUser, error) {
+	users, err := s.followRepo.GetFollowing(userID)
+	if users == nil {
+		users = []models.FollowUser{}
+	}
+	return users, err
+}
+
+func (s *FeedService) GetFollowers(userID int) ([]models.FollowUser, error) {
+	users, err := s.followRepo.GetFollowers(userID)
+	if users == nil {
+		users = []models.FollowUser{}
+	}
+	return users, err
+}
+
+// GetFeed returns a page of the user's feed, newest first. cursor is the NextCursor of the
+// previous page, or empty for the first page.
+func (s *FeedService) GetFeed(userID int, cursor string, limit int) (*models.FeedPage, error) {
+	var before *models.FeedCursor
+	if cursor != "" {
+		c, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		before = c
+	}
+
+	// Fetch one extra item to learn whether there's another page
+	items, err := s.followRepo.GetFeed(userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.FeedPage{Items: []models.FeedItem{}}
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		page.NextCursor = encodeFeedCursor(models.FeedCursor{CreatedAt: last.CreatedAt, Type: last.Type, ID: last.ID})
+	}
+	if items != nil {
+		page.Items = items
+	}
+	return page, nil
+}
+
+// Feed cursors are opaque to clients: base64 of "unixnano:type:id".
+func encodeFeedCursor(c models.FeedCursor) string {
+	raw := fmt.Sprintf("%d:%s:%d", c.CreatedAt.UnixNano(), c.Type, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (*models.FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &models.FeedCursor{CreatedAt: time.Unix(0, nanos), Type: parts[1], ID: id}, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"grub-exchange/internal/indicators"
+	"grub-exchange/internal/models"
+	"math"
+	"time"
+)
+
+const (
+	// Bollinger bands are this many standard deviations from the middle band
+	bollingerWidth = 2
+	// Candles are loaded for this many times the longest window before from, so the
+	// indicators are defined from the first candle and the EMA has settled. Intervals
+	// without a recorded price have no candle, so more than one window's worth is needed.
+	indicatorWarmup = 3
+)
+
+// GetIndicators returns technical indicators for a stock's candles starting in [from, to),
+// computed from the candles' closes. from and to default as in GetCandles; the candles
+// before from that the windows look back over don't count toward the candle limit.
+func (s *CandleService) GetIndicators(ticker, interval string, from, to time.Time, windows models.IndicatorWindows) (*models.StockIndicators, error) {
+	from, to, err := candleRange(interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByTicker(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	longest := 0
+	for _, w := range []int{windows.SMA, windows.EMA, windows.RSI + 1, windows.Bollinger, windows.Volatility + 1} {
+		if w > longest {
+			longest = w
+		}
+	}
+	warmup := time.Duration(indicatorWarmup*longest) * models.CandleIntervals[interval]
+	candles, err := s.loadCandles(user.ID, interval, from.Add(-warmup), to)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make([]time.Time, len(candles))
+	closes := make([]float64, len(candles))
+	start := len(candles)
+	for i, c := range candles {
+		times[i] = c.Time
+		closes[i] = c.Close
+		if start == len(candles) && !c.Time.Before(from) {
+			start = i
+		}
+	}
+
+	// VWAP is anchored at from, weighting each candle's typical price by its volume
+	var typical, volumes []float64
+	for _, c := range candles[start:] {
+		typical = append(typical, (c.High+c.Low+c.Close)/3)
+		volumes = append(volumes, c.Volume)
+	}
+
+	middle, upper, lower := indicators.Bollinger(closes, windows.Bollinger, bollingerWidth)
+	bollinger := []models.BollingerPoint{}
+	for i := start; i < len(candles); i++ {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		bollinger = append(bollinger, models.BollingerPoint{
+			Time:   times[i],
+			Middle: middle[i],
+			Upper:  upper[i],
+			Lower:  lower[i],
+		})
+	}
+
+	return &models.StockIndicators{
+		Ticker:     user.Ticker,
+		Interval:   interval,
+		From:       from,
+		To:         to,
+		Windows:    windows,
+		SMA:        indicatorPoints(times, indicators.SMA(closes, windows.SMA), start),
+		EMA:        indicatorPoints(times, indicators.EMA(closes, windows.EMA), start),
+		RSI:        indicatorPoints(times, indicators.RSI(closes, windows.RSI), start),
+		Bollinger:  bollinger,
+		VWAP:       indicatorPoints(times[start:], indicators.VWAP(typical, volumes), 0),
+		Volatility: indicatorPoints(times, indicators.Volatility(closes, windows.Volatility), start),
+	}, nil
+}
+
+// indicatorPoints pairs the values from start on with their candle times, leaving out the
+// points where the indicator isn't defined.
+func indicatorPoints(times []time.Time, values []float64, start int) []models.IndicatorPoint {
+	points := []models.IndicatorPoint{}
+	for i := start; i < len(values); i++ {
+		if math.IsNaN(values[i]) {
+			continue
+		}
+		points = append(points, models.IndicatorPoint{Time: times[i], Value: values[i]})
+	}
+	return points
+}
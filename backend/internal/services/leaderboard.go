@@ -0,0 +1,364 @@
+package services
+
+import (
+	"errors"
+	"grub-exchange/internal/models"
+	"log"
+	"sort"
+	"time"
+)
+
+// initialSharePrice is what every stock lists at; it's the "all" window baseline.
+const initialSharePrice = 10.0
+
+// rankedLeaderboard holds every ranked entry of each category for one window.
+type rankedLeaderboard map[string][]models.LeaderboardEntry
+
+// RefreshLeaderboards recomputes the ranked leaderboards for every window and swaps them into
+// the cache. It runs on the scheduler so requests only page through precomputed lists.
+func (s *MarketService) RefreshLeaderboards() {
+	boards, err := s.computeLeaderboards()
+	if err != nil {
+		log.Printf("Error refreshing leaderboards: %v", err)
+		return
+	}
+
+	s.leaderboardMu.Lock()
+	s.leaderboards = boards
+	s.leaderboardsAt = time.Now()
+	s.leaderboardMu.Unlock()
+}
+
+// cachedLeaderboards returns the cached leaderboards, computing them first if the
+// scheduler hasn't run yet.
+func (s *MarketService) cachedLeaderboards() (map[string]rankedLeaderboard, time.Time, error) {
+	s.leaderboardMu.RLock()
+	boards, at := s.leaderboards, s.leaderboardsAt
+	s.leaderboardMu.RUnlock()
+	if boards != nil {
+		return boards, at, nil
+	}
+
+	s.RefreshLeaderboards()
+
+	s.leaderboardMu.RLock()
+	defer s.leaderboardMu.RUnlock()
+	if s.leaderboards == nil {
+		return nil, time.Time{}, errors.New("leaderboard is unavailable")
+	}
+	return s.leaderboards, s.leaderboardsAt, nil
+}
+
+// GetLeaderboard returns a page of the cached leaderboard along with the requesting user's
+// own rank in every category, even when it falls outside the page.
+func (s *MarketService) GetLeaderboard(userID int, q models.LeaderboardQuery) (*models.LeaderboardData, error) {
+	boards, updatedAt, err := s.cachedLeaderboards()
+	if err != nil {
+		return nil, err
+	}
+	board, ok := boards[q.Window]
+	if !ok {
+		return nil, errors.New("invalid window")
+	}
+	return pageLeaderboard(board, q, userID, updatedAt), nil
+}
+
+// GetLeaderboardAmong is GetLeaderboard restricted to the given users (e.g. a league's
+// members) and re-ranked among them.
+func (s *MarketService) GetLeaderboardAmong(userID int, q models.LeaderboardQuery, userIDs map[int]bool) (*models.LeaderboardData, error) {
+	boards, updatedAt, err := s.cachedLeaderboards()
+	if err != nil {
+		return nil, err
+	}
+	board, ok := boards[q.Window]
+	if !ok {
+		return nil, errors.New("invalid window")
+	}
+
+	filtered := make(rankedLeaderboard, len(board))
+	for category, entries := range board {
+		var kept []models.LeaderboardEntry
+		for _, e := range entries {
+			if userIDs[e.UserID] {
+				e.Rank = len(kept) + 1
+				kept = append(kept, e)
+			}
+		}
+		filtered[category] = kept
+	}
+	return pageLeaderboard(filtered, q, userID, updatedAt), nil
+}
+
+// pageLeaderboard slices the requested page out of fully ranked lists. Offsets index the
+// cached ranking, so a refresh between pages can shift entries across a page boundary.
+func pageLeaderboard(board rankedLeaderboard, q models.LeaderboardQuery, userID int, updatedAt time.Time) *models.LeaderboardData {
+	data := &models.LeaderboardData{
+		Window:      q.Window,
+		MyRanks:     make(map[string]*models.LeaderboardEntry),
+		NextOffsets: make(map[string]int),
+		UpdatedAt:   updatedAt,
+	}
+
+	for _, category := range models.LeaderboardCategories {
+		entries := board[category]
+
+		data.MyRanks[category] = nil
+		for i := range entries {
+			if entries[i].UserID == userID {
+				mine := entries[i]
+				data.MyRanks[category] = &mine
+				break
+			}
+		}
+
+		if q.Category != "" && q.Category != category {
+			continue
+		}
+
+		page := []models.LeaderboardEntry{}
+		if q.Offset < len(entries) {
+			end := q.Offset + q.Limit
+			if end > len(entries) {
+				end = len(entries)
+			}
+			page = entries[q.Offset:end]
+			if end < len(entries) {
+				data.NextOffsets[category] = end
+			}
+		}
+
+		switch category {
+		case models.LeaderboardMostValuable:
+			data.MostValuable = page
+		case models.LeaderboardBiggestGainers:
+			data.BiggestGainers = page
+		case models.LeaderboardBiggestLosers:
+			data.BiggestLosers = page
+		case models.LeaderboardRichestTraders:
+			data.RichestTraders = page
+		case models.LeaderboardBestPerformance:
+			data.BestPerformance = page
+		}
+	}
+	return data
+}
+
+// windowStart returns when a leaderboard window begins, or the zero time for "all".
+func windowStart(window string, now time.Time) time.Time {
+	switch window {
+	case "1h":
+		return now.Add(-time.Hour)
+	case "24h":
+		return now.Add(-24 * time.Hour)
+	case "7d":
+		return now.Add(-7 * 24 * time.Hour)
+	case "30d":
+		return now.Add(-30 * 24 * time.Hour)
+	}
+	return time.Time{}
+}
+
+// computeLeaderboards ranks every user in every category. Only gainers and losers depend on
+// the window; the other categories reflect current state and are shared between windows.
+func (s *MarketService) computeLeaderboards() (map[string]rankedLeaderboard, error) {
+	// --- Batch-load all data upfront to avoid N+1 queries ---
+	users, err := s.userRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// Build user lookup map by ID
+	userMap := make(map[int]models.User, len(users))
+	for _, u := range users {
+		userMap[u.ID] = u
+	}
+
+	// Batch: all balances (single query)
+	allBalances, _ := s.balanceRepo.GetAllBalances()
+	balanceMap := make(map[int]float64, len(allBalances))
+	for _, b := range allBalances {
+		balanceMap[b.UserID] = b.GrubBalance
+	}
+
+	// Batch: all holdings (single query)
+	allHoldings, _ := s.portfolioRepo.GetAllHoldings()
+	// Group holdings by owner
+	holdingsByOwner := make(map[int][]models.Portfolio)
+	for _, h := range allHoldings {
+		holdingsByOwner[h.OwnerID] = append(holdingsByOwner[h.OwnerID], h)
+	}
+
+	// Users who hide their portfolio value are left off the richest traders board
+	hiddenValues, err := s.privacyRepo.GetPortfolioValueHidden()
+	if err != nil {
+		return nil, err
+	}
+
+	mostValuable := rankMostValuable(users)
+	richest := rankRichest(users, userMap, balanceMap, holdingsByOwner, hiddenValues)
+	performance := rankPerformance(users, userMap, holdingsByOwner)
+
+	now := time.Now()
+	boards := make(map[string]rankedLeaderboard, len(models.LeaderboardWindows))
+	for _, window := range models.LeaderboardWindows {
+		var startPrices map[int]float64
+		if start := windowStart(window, now); !start.IsZero() {
+			// Batch: all prices at the window start (single query)
+			startPrices, err = s.txnRepo.GetPricesAtBatch(start)
+			if err != nil {
+				return nil, err
+			}
+		}
+		gainers, losers := rankPriceChanges(users, startPrices)
+
+		boards[window] = rankedLeaderboard{
+			models.LeaderboardMostValuable:    mostValuable,
+			models.LeaderboardBiggestGainers:  gainers,
+			models.LeaderboardBiggestLosers:   losers,
+			models.LeaderboardRichestTraders:  richest,
+			models.LeaderboardBestPerformance: performance,
+		}
+	}
+	return boards, nil
+}
+
+// --- 1. Most Valuable Stocks ---
+func rankMostValuable(users []models.User) []models.LeaderboardEntry {
+	sortedByPrice := make([]models.User, len(users))
+	copy(sortedByPrice, users)
+	sort.Slice(sortedByPrice, func(i, j int) bool {
+		return sortedByPrice[i].CurrentSharePrice > sortedByPrice[j].CurrentSharePrice
+	})
+
+	entries := make([]models.LeaderboardEntry, 0, len(sortedByPrice))
+	for i, u := range sortedByPrice {
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:     i + 1,
+			UserID:   u.ID,
+			Username: u.Username,
+			Ticker:   u.Ticker,
+			Value:    u.CurrentSharePrice,
+		})
+	}
+	return entries
+}
+
+// --- 2. Biggest Gainers and Losers over the window ---
+// startPrices holds each stock's price at the window start; stocks listed since then (and
+// every stock, for the "all" window) are measured from the listing price.
+func rankPriceChanges(users []models.User, startPrices map[int]float64) (gainers, losers []models.LeaderboardEntry) {
+	type userChange struct {
+		user   models.User
+		change float64
+	}
+	changes := make([]userChange, 0, len(users))
+	for _, u := range users {
+		startPrice := initialSharePrice
+		if p, ok := startPrices[u.ID]; ok {
+			startPrice = p
+		}
+		changePercent := 0.0
+		if startPrice > 0 {
+			changePercent = ((u.CurrentSharePrice - startPrice) / startPrice) * 100
+		}
+		changes = append(changes, userChange{user: u, change: changePercent})
+	}
+
+	toEntries := func() []models.LeaderboardEntry {
+		entries := make([]models.LeaderboardEntry, 0, len(changes))
+		for i, c := range changes {
+			entries = append(entries, models.LeaderboardEntry{
+				Rank:     i + 1,
+				UserID:   c.user.ID,
+				Username: c.user.Username,
+				Ticker:   c.user.Ticker,
+				Value:    c.user.CurrentSharePrice,
+				Change:   c.change,
+			})
+		}
+		return entries
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].change > changes[j].change
+	})
+	gainers = toEntries()
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].change < changes[j].change
+	})
+	losers = toEntries()
+	return gainers, losers
+}
+
+// --- 3. Richest Traders (cash + holdings using pre-loaded data) ---
+func rankRichest(users []models.User, userMap map[int]models.User, balanceMap map[int]float64, holdingsByOwner map[int][]models.Portfolio, hidden map[int]bool) []models.LeaderboardEntry {
+	type userWealth struct {
+		user       models.User
+		totalValue float64
+	}
+	wealthEntries := make([]userWealth, 0, len(users))
+	for _, u := range users {
+		if hidden[u.ID] {
+			continue
+		}
+		cash := balanceMap[u.ID]
+		holdingsValue := 0.0
+		for _, h := range holdingsByOwner[u.ID] {
+			if su, ok := userMap[h.StockUserID]; ok {
+				holdingsValue += h.NumShares * su.CurrentSharePrice
+			}
+		}
+		wealthEntries = append(wealthEntries, userWealth{user: u, totalValue: cash + holdingsValue})
+	}
+	sort.Slice(wealthEntries, func(i, j int) bool {
+		return wealthEntries[i].totalValue > wealthEntries[j].totalValue
+	})
+
+	entries := make([]models.LeaderboardEntry, 0, len(wealthEntries))
+	for i, w := range wealthEntries {
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:     i + 1,
+			UserID:   w.user.ID,
+			Username: w.user.Username,
+			Ticker:   w.user.Ticker,
+			Value:    w.totalValue,
+		})
+	}
+	return entries
+}
+
+// --- 4. Best Portfolio Performance (using pre-loaded data) ---
+func rankPerformance(users []models.User, userMap map[int]models.User, holdingsByOwner map[int][]models.Portfolio) []models.LeaderboardEntry {
+	var perfEntries []models.LeaderboardEntry
+	for _, u := range users {
+		holdings := holdingsByOwner[u.ID]
+		if len(holdings) == 0 {
+			continue
+		}
+		var totalValue, totalCost float64
+		for _, h := range holdings {
+			if su, ok := userMap[h.StockUserID]; ok {
+				totalValue += h.NumShares * su.CurrentSharePrice
+				totalCost += h.NumShares * h.AvgPurchasePrice
+			}
+		}
+		plPercent := 0.0
+		if totalCost > 0 {
+			plPercent = ((totalValue - totalCost) / totalCost) * 100
+		}
+		perfEntries = append(perfEntries, models.LeaderboardEntry{
+			UserID:   u.ID,
+			Username: u.Username,
+			Ticker:   u.Ticker,
+			Value:    plPercent,
+		})
+	}
+	sort.Slice(perfEntries, func(i, j int) bool {
+		return perfEntries[i].Value > perfEntries[j].Value
+	})
+	for i := range perfEntries {
+		perfEntries[i].Rank = i + 1
+	}
+	return perfEntries
+}
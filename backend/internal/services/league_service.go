@@ -0,0 +1,292 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"grub-exchange/internal/utils"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxLeagueMembers = 100
+
+var (
+	// ErrLeagueNotFound is also returned to non-members so private leagues can't be probed.
+	ErrLeagueNotFound  = errors.New("league not found")
+	ErrNotLeagueAdmin  = errors.New("only league admins can do that")
+	ErrLeagueForbidden = errors.New("you were removed from this league")
+)
+
+// LeagueService manages private leagues: invite-only groups with a leaderboard restricted to
+// their members and a post feed only members can see.
+type LeagueService struct {
+	db            *sql.DB
+	leagueRepo    *repository.LeagueRepo
+	postRepo      *repository.PostRepo
+	userRepo      *repository.UserRepo
+	marketService *MarketService
+	achieveSvc    *AchievementService
+	moderationSvc *ModerationService
+	entitySvc     *PostEntityService
+}
+
+func NewLeagueService(
+	db *sql.DB,
+	leagueRepo *repository.LeagueRepo,
+	postRepo *repository.PostRepo,
+	userRepo *repository.UserRepo,
+	marketService *MarketService,
+	achieveSvc *AchievementService,
+	moderationSvc *ModerationService,
+	entitySvc *PostEntityService,
+) *LeagueService {
+	return &LeagueService{
+		db:            db,
+		leagueRepo:    leagueRepo,
+		postRepo:      postRepo,
+		userRepo:      userRepo,
+		marketService: marketService,
+		achieveSvc:    achieveSvc,
+		moderationSvc: moderationSvc,
+		entitySvc:     entitySvc,
+	}
+}
+
+func (s *LeagueService) CreateLeague(userID int, name string) (*models.League, error) {
+	name = strings.TrimSpace(name)
+	if n := utf8.RuneCountInString(name); n < 3 || n > 40 {
+		return nil, errors.New("league name must be 3-40 characters")
+	}
+
+	code, err := utils.GenerateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+	return s.leagueRepo.Create(name, code, userID)
+}
+
+func (s *LeagueService) ListLeagues(userID int) ([]models.League, error) {
+	leagues, err := s.leagueRepo.GetForUser(userID)
+	if leagues == nil {
+		leagues = []models.League{}
+	}
+	return leagues, err
+}
+
+func (s *LeagueService) JoinLeague(userID int, inviteCode string) (*models.League, error) {
+	leagueID, err := s.leagueRepo.GetIDByInviteCode(strings.ToUpper(strings.TrimSpace(inviteCode)))
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid invite code")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, kicked, err := s.leagueRepo.GetMembership(leagueID, userID)
+	switch {
+	case err == nil && kicked:
+		return nil, ErrLeagueForbidden
+	case err == nil:
+		return nil, errors.New("you are already in this league")
+	case err != sql.ErrNoRows:
+		return nil, err
+	}
+
+	league, err := s.leagueRepo.GetByID(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	if league.MemberCount >= maxLeagueMembers {
+		return nil, errors.New("this league is full")
+	}
+
+	if err := s.leagueRepo.AddMember(leagueID, userID); err != nil {
+		return nil, err
+	}
+	league.MemberCount++
+	league.MyRole = models.LeagueRoleMember
+	return league, nil
+}
+
+// GetLeague returns a league and its members. Only members can see it.
+func (s *LeagueService) GetLeague(userID, leagueID int) (*models.League, []models.LeagueMember, error) {
+	role, err := s.requireMember(userID, leagueID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	league, err := s.leagueRepo.GetByID(leagueID)
+	if err != nil {
+		return nil, nil, err
+	}
+	league.MyRole = role
+
+	members, err := s.leagueRepo.GetMembers(leagueID)
+	if members == nil {
+		members = []models.LeagueMember{}
+	}
+	return league, members, err
+}
+
+func (s *LeagueService) LeaveLeague(userID, leagueID int) error {
+	if _, err := s.requireMember(userID, leagueID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.leagueRepo.Leave(tx, leagueID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// KickMember removes a member and bars them from rejoining. Admins can't be kicked.
+func (s *LeagueService) KickMember(adminID, leagueID, memberID int) error {
+	if err := s.requireAdmin(adminID, leagueID); err != nil {
+		return err
+	}
+	if adminID == memberID {
+		return errors.New("use leave to remove yourself")
+	}
+
+	kicked, err := s.leagueRepo.Kick(leagueID, memberID)
+	if err != nil {
+		return err
+	}
+	if !kicked {
+		return errors.New("member not found or is an admin")
+	}
+	return nil
+}
+
+// RotateInviteCode replaces the invite code so old invites stop working.
+func (s *LeagueService) RotateInviteCode(adminID, leagueID int) (string, error) {
+	if err := s.requireAdmin(adminID, leagueID); err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateInviteCode()
+	if err != nil {
+		return "", err
+	}
+	if err := s.leagueRepo.SetInviteCode(leagueID, code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// GetLeaderboard returns the market leaderboard restricted to league members.
+func (s *LeagueService) GetLeaderboard(userID, leagueID int, q models.LeaderboardQuery) (*models.LeaderboardData, error) {
+	if _, err := s.requireMember(userID, leagueID); err != nil {
+		return nil, err
+	}
+
+	memberIDs, err := s.leagueRepo.GetMemberIDs(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	return s.marketService.GetLeaderboardAmong(userID, q, memberIDs)
+}
+
+func (s *LeagueService) GetPosts(userID, leagueID int) ([]models.StockPost, error) {
+	if _, err := s.requireMember(userID, leagueID); err != nil {
+		return nil, err
+	}
+
+	posts, err := s.postRepo.GetByLeague(leagueID, userID, 50)
+	if posts == nil {
+		posts = []models.StockPost{}
+	}
+	return posts, err
+}
+
+// CreatePost adds a post about a stock to the league's private feed.
+func (s *LeagueService) CreatePost(userID, leagueID int, req *models.CreateLeaguePostRequest) (*models.StockPost, error) {
+	if _, err := s.requireMember(userID, leagueID); err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(req.Content)
+	if content == "" || len(content) > 500 {
+		return nil, errors.New("content must be 1-500 characters")
+	}
+	if s.moderationSvc != nil {
+		if err := s.moderationSvc.CheckCanPost(userID, content); err != nil {
+			return nil, err
+		}
+	}
+
+	stockUser, err := s.userRepo.GetByTicker(req.Ticker)
+	if err != nil {
+		return nil, errors.New("stock not found")
+	}
+
+	var entities []models.PostEntity
+	if s.entitySvc != nil {
+		entities = s.entitySvc.Resolve(content)
+	}
+	post, err := s.postRepo.Create(userID, stockUser.ID, &leagueID, content, entities)
+	if err != nil {
+		return nil, err
+	}
+
+	post.StockTicker = stockUser.Ticker
+	author, _ := s.userRepo.GetByID(userID)
+	if author != nil {
+		post.AuthorUsername = author.Username
+		if s.entitySvc != nil {
+			s.entitySvc.NotifyMentions(post, author.Username, nil)
+		}
+	}
+
+	if s.achieveSvc != nil {
+		s.achieveSvc.RecordEvent(userID, models.EventPost, 1)
+	}
+	return post, nil
+}
+
+// CanSeePost reports whether a user may see (and vote on) a post in the given league.
+// Public posts (nil league) are visible to everyone.
+func (s *LeagueService) CanSeePost(userID int, leagueID *int) (bool, error) {
+	if leagueID == nil {
+		return true, nil
+	}
+	_, err := s.requireMember(userID, *leagueID)
+	if err == ErrLeagueNotFound || err == ErrLeagueForbidden {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// requireMember returns the user's role, or ErrLeagueNotFound if they aren't a current member.
+func (s *LeagueService) requireMember(userID, leagueID int) (string, error) {
+	role, kicked, err := s.leagueRepo.GetMembership(leagueID, userID)
+	if err == sql.ErrNoRows {
+		return "", ErrLeagueNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if kicked {
+		return "", ErrLeagueForbidden
+	}
+	return role, nil
+}
+
+func (s *LeagueService) requireAdmin(userID, leagueID int) error {
+	role, err := s.requireMember(userID, leagueID)
+	if err != nil {
+		return err
+	}
+	if role != models.LeagueRoleAdmin {
+		return ErrNotLeagueAdmin
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"database/sql"
+	"grub-exchange/internal/models"
 	"grub-exchange/internal/repository"
 	"log"
 	"math"
@@ -15,7 +16,8 @@ type MarketMaker struct {
 	balanceRepo   *repository.BalanceRepo
 	portfolioRepo *repository.PortfolioRepo
 	txnRepo       *repository.TransactionRepo
-	postRepo      *repository.PostRepo
+	sentimentSvc  *SentimentService
+	alertSvc      *AlertService
 	marketUserID  int
 }
 
@@ -25,7 +27,8 @@ func NewMarketMaker(
 	balanceRepo *repository.BalanceRepo,
 	portfolioRepo *repository.PortfolioRepo,
 	txnRepo *repository.TransactionRepo,
-	postRepo *repository.PostRepo,
+	sentimentSvc *SentimentService,
+	alertSvc *AlertService,
 ) *MarketMaker {
 	return &MarketMaker{
 		db:            db,
@@ -33,7 +36,8 @@ func NewMarketMaker(
 		balanceRepo:   balanceRepo,
 		portfolioRepo: portfolioRepo,
 		txnRepo:       txnRepo,
-		postRepo:      postRepo,
+		sentimentSvc:  sentimentSvc,
+		alertSvc:      alertSvc,
 	}
 }
 
@@ -69,9 +73,9 @@ func (m *MarketMaker) tick() {
 	}
 
 	// Batch-load all sentiments so we don't query per stock
-	sentiments, err := m.postRepo.GetAllSentiments()
+	sentiments, err := m.sentimentSvc.GetSentiments()
 	if err != nil {
-		sentiments = make(map[int]int)
+		sentiments = make(map[int]float64)
 	}
 
 	// Batch-load recent trading momentum (last 30 minutes)
@@ -80,6 +84,7 @@ func (m *MarketMaker) tick() {
 		momentum = make(map[int]float64)
 	}
 
+	var priceUpdates []models.PriceUpdate
 	for _, u := range users {
 		// Skip the MARKET system user itself
 		if u.ID == m.marketUserID {
@@ -93,9 +98,9 @@ func (m *MarketMaker) tick() {
 		buyProb := 0.65
 
 		// Adjust buy probability based on news sentiment
-		// Each net like point shifts buy probability by 2%, capped at [0.20, 0.90]
+		// Each point of weighted net likes shifts buy probability by 2%, capped at [0.20, 0.90]
 		if net, ok := sentiments[u.ID]; ok && net != 0 {
-			buyProb += float64(net) * 0.02
+			buyProb += net * 0.02
 		}
 
 		// Adjust buy probability based on recent trading momentum
@@ -146,10 +151,16 @@ func (m *MarketMaker) tick() {
 
 			if err := tx.Commit(); err != nil {
 				log.Printf("Market maker: tx commit error for user %d: %v", u.ID, err)
+				continue
 			}
+			priceUpdates = append(priceUpdates, models.PriceUpdate{StockUserID: u.ID, OldPrice: u.CurrentSharePrice, NewPrice: newPrice})
 		}
 	}
 
+	if m.alertSvc != nil {
+		m.alertSvc.OnPriceUpdates(priceUpdates)
+	}
+
 	m.snapshotPortfolios()
 }
 
@@ -5,7 +5,8 @@ import (
 	"grub-exchange/internal/models"
 	"grub-exchange/internal/repository"
 	"log"
-	"sort"
+	"math"
+	"sync"
 	"time"
 )
 
@@ -16,6 +17,14 @@ type MarketService struct {
 	txnRepo       *repository.TransactionRepo
 	snapshotRepo  *repository.MarketSnapshotRepo
 	notifRepo     *repository.NotificationRepo
+	privacyRepo   *repository.PrivacyRepo
+	achieveSvc    *AchievementService
+	alertSvc      *AlertService
+
+	// Ranked leaderboards by window, refreshed by the scheduler
+	leaderboardMu  sync.RWMutex
+	leaderboards   map[string]rankedLeaderboard
+	leaderboardsAt time.Time
 }
 
 func NewMarketService(
@@ -25,6 +34,9 @@ func NewMarketService(
 	txnRepo *repository.TransactionRepo,
 	snapshotRepo *repository.MarketSnapshotRepo,
 	notifRepo *repository.NotificationRepo,
+	privacyRepo *repository.PrivacyRepo,
+	achieveSvc *AchievementService,
+	alertSvc *AlertService,
 ) *MarketService {
 	return &MarketService{
 		userRepo:      userRepo,
@@ -33,46 +45,12 @@ func NewMarketService(
 		txnRepo:       txnRepo,
 		snapshotRepo:  snapshotRepo,
 		notifRepo:     notifRepo,
+		privacyRepo:   privacyRepo,
+		achieveSvc:    achieveSvc,
+		alertSvc:      alertSvc,
 	}
 }
 
-func (s *MarketService) GetAllStocks() ([]models.StockListItem, error) {
-	users, err := s.userRepo.GetAll()
-	if err != nil {
-		return nil, err
-	}
-
-	var stocks []models.StockListItem
-	for _, u := range users {
-		price24hAgo, _ := s.txnRepo.GetPriceAt(u.ID, time.Now().Add(-24*time.Hour))
-		changePercent := 0.0
-		if price24hAgo > 0 {
-			changePercent = ((u.CurrentSharePrice - price24hAgo) / price24hAgo) * 100
-		}
-
-		// Get sparkline data (last 20 price points)
-		history, _ := s.txnRepo.GetPriceHistory(u.ID, time.Now().Add(-7*24*time.Hour))
-		var sparkline []float64
-		for _, h := range history {
-			sparkline = append(sparkline, h.Price)
-		}
-		if len(sparkline) == 0 {
-			sparkline = []float64{u.CurrentSharePrice}
-		}
-
-		stocks = append(stocks, models.StockListItem{
-			ID:                u.ID,
-			Username:          u.Username,
-			Ticker:            u.Ticker,
-			CurrentSharePrice: u.CurrentSharePrice,
-			Change24hPercent:  changePercent,
-			SparklineData:     sparkline,
-		})
-	}
-
-	return stocks, nil
-}
-
 func (s *MarketService) GetMarketOverview() (*models.MarketOverview, error) {
 	users, err := s.userRepo.GetAll()
 	if err != nil {
@@ -177,6 +155,10 @@ func (s *MarketService) GetStockDetail(ticker string) (*models.StockDetail, erro
 
 	volume, _ := s.txnRepo.GetVolume24h(user.ID)
 	ath, atl, _ := s.txnRepo.GetAllTimePriceRange(user.ID)
+	ownership, err := s.getOwnership(user)
+	if err != nil {
+		log.Printf("Error computing ownership for %s: %v", user.Ticker, err)
+	}
 
 	return &models.StockDetail{
 		User:             *user,
@@ -188,194 +170,67 @@ func (s *MarketService) GetStockDetail(ticker string) (*models.StockDetail, erro
 		Volume24h:        volume,
 		AllTimeHigh:      ath,
 		AllTimeLow:       atl,
+		Ownership:        ownership,
 	}, nil
 }
 
-func (s *MarketService) GetLeaderboard() (*models.LeaderboardData, error) {
-	// --- Batch-load all data upfront to avoid N+1 queries ---
-	users, err := s.userRepo.GetAll()
+// GetStockHolders returns a page of a stock's shareholder registry with its ownership summary.
+func (s *MarketService) GetStockHolders(ticker string, limit, offset int) (*models.StockHolders, error) {
+	user, err := s.userRepo.GetByTicker(ticker)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build user lookup map by ID
-	userMap := make(map[int]models.User, len(users))
-	for _, u := range users {
-		userMap[u.ID] = u
-	}
-
-	// Batch: all prices 24h ago (single query)
-	prices24hAgo, _ := s.txnRepo.GetPricesAtBatch(time.Now().Add(-24 * time.Hour))
-
-	// Batch: all balances (single query)
-	allBalances, _ := s.balanceRepo.GetAllBalances()
-	balanceMap := make(map[int]float64, len(allBalances))
-	for _, b := range allBalances {
-		balanceMap[b.UserID] = b.GrubBalance
-	}
-
-	// Batch: all holdings (single query)
-	allHoldings, _ := s.portfolioRepo.GetAllHoldings()
-	// Group holdings by owner
-	holdingsByOwner := make(map[int][]models.Portfolio)
-	for _, h := range allHoldings {
-		holdingsByOwner[h.OwnerID] = append(holdingsByOwner[h.OwnerID], h)
+	holders, err := s.portfolioRepo.GetTopHolders(user.ID, limit, offset)
+	if err != nil {
+		return nil, err
 	}
-
-	// --- 1. Most Valuable Stocks ---
-	sortedByPrice := make([]models.User, len(users))
-	copy(sortedByPrice, users)
-	sort.Slice(sortedByPrice, func(i, j int) bool {
-		return sortedByPrice[i].CurrentSharePrice > sortedByPrice[j].CurrentSharePrice
-	})
-
-	var mostValuable []models.LeaderboardEntry
-	for i, u := range sortedByPrice {
-		if i >= 10 {
-			break
-		}
-		mostValuable = append(mostValuable, models.LeaderboardEntry{
-			Rank:     i + 1,
-			UserID:   u.ID,
-			Username: u.Username,
-			Ticker:   u.Ticker,
-			Value:    u.CurrentSharePrice,
-		})
+	if holders == nil {
+		holders = []models.StockHolder{}
 	}
+	fillHolderStats(holders, user.SharesOutstanding, time.Now())
 
-	// --- 2. Biggest Gainers and Losers (24h) ---
-	type userChange struct {
-		user   models.User
-		change float64
-	}
-	var changes []userChange
-	for _, u := range users {
-		price24hAgo := 10.0
-		if p, ok := prices24hAgo[u.ID]; ok {
-			price24hAgo = p
-		}
-		changePercent := 0.0
-		if price24hAgo > 0 {
-			changePercent = ((u.CurrentSharePrice - price24hAgo) / price24hAgo) * 100
-		}
-		changes = append(changes, userChange{user: u, change: changePercent})
-	}
-
-	sort.Slice(changes, func(i, j int) bool {
-		return changes[i].change > changes[j].change
-	})
-
-	var gainers []models.LeaderboardEntry
-	for i, c := range changes {
-		if i >= 10 {
-			break
-		}
-		gainers = append(gainers, models.LeaderboardEntry{
-			Rank:     i + 1,
-			UserID:   c.user.ID,
-			Username: c.user.Username,
-			Ticker:   c.user.Ticker,
-			Value:    c.user.CurrentSharePrice,
-			Change:   c.change,
-		})
+	ownership, err := s.getOwnership(user)
+	if err != nil {
+		return nil, err
 	}
 
-	sort.Slice(changes, func(i, j int) bool {
-		return changes[i].change < changes[j].change
-	})
+	return &models.StockHolders{
+		Ticker:            user.Ticker,
+		SharesOutstanding: user.SharesOutstanding,
+		Holders:           holders,
+		Ownership:         ownership,
+	}, nil
+}
 
-	var losers []models.LeaderboardEntry
-	for i, c := range changes {
-		if i >= 10 {
-			break
-		}
-		losers = append(losers, models.LeaderboardEntry{
-			Rank:     i + 1,
-			UserID:   c.user.ID,
-			Username: c.user.Username,
-			Ticker:   c.user.Ticker,
-			Value:    c.user.CurrentSharePrice,
-			Change:   c.change,
-		})
+func (s *MarketService) getOwnership(user *models.User) (models.Ownership, error) {
+	count, held, top, top5, sumSquares, err := s.portfolioRepo.GetOwnershipTotals(user.ID)
+	if err != nil {
+		return models.Ownership{}, err
 	}
 
-	// --- 3. Richest Traders (cash + holdings using pre-loaded data) ---
-	type userWealth struct {
-		user       models.User
-		totalValue float64
-	}
-	var wealthEntries []userWealth
-	for _, u := range users {
-		cash := balanceMap[u.ID]
-		holdingsValue := 0.0
-		for _, h := range holdingsByOwner[u.ID] {
-			if su, ok := userMap[h.StockUserID]; ok {
-				holdingsValue += h.NumShares * su.CurrentSharePrice
-			}
-		}
-		wealthEntries = append(wealthEntries, userWealth{user: u, totalValue: cash + holdingsValue})
-	}
-	sort.Slice(wealthEntries, func(i, j int) bool {
-		return wealthEntries[i].totalValue > wealthEntries[j].totalValue
-	})
-
-	var richest []models.LeaderboardEntry
-	for i, w := range wealthEntries {
-		if i >= 10 {
-			break
-		}
-		richest = append(richest, models.LeaderboardEntry{
-			Rank:     i + 1,
-			UserID:   w.user.ID,
-			Username: w.user.Username,
-			Ticker:   w.user.Ticker,
-			Value:    w.totalValue,
-		})
+	o := models.Ownership{HolderCount: count, SharesHeld: held}
+	outstanding := float64(user.SharesOutstanding)
+	if outstanding > 0 {
+		o.FloatShares = math.Max(outstanding-held, 0)
+		o.FloatPercent = o.FloatShares / outstanding * 100
+		o.TopHolderPercent = top / outstanding * 100
+		o.Top5Percent = top5 / outstanding * 100
+		// Sum of squared percentages: (shares/outstanding*100)^2 summed over holders
+		o.ConcentrationHHI = sumSquares / (outstanding * outstanding) * 10000
 	}
+	return o, nil
+}
 
-	// --- 4. Best Portfolio Performance (using pre-loaded data) ---
-	var perfEntries []models.LeaderboardEntry
-	for _, u := range users {
-		holdings := holdingsByOwner[u.ID]
-		if len(holdings) == 0 {
-			continue
-		}
-		var totalValue, totalCost float64
-		for _, h := range holdings {
-			if su, ok := userMap[h.StockUserID]; ok {
-				totalValue += h.NumShares * su.CurrentSharePrice
-				totalCost += h.NumShares * h.AvgPurchasePrice
-			}
-		}
-		plPercent := 0.0
-		if totalCost > 0 {
-			plPercent = ((totalValue - totalCost) / totalCost) * 100
+// fillHolderStats sets each holder's percentage of shares outstanding and how many whole
+// days they've held the position.
+func fillHolderStats(holders []models.StockHolder, sharesOutstanding int, now time.Time) {
+	for i := range holders {
+		if sharesOutstanding > 0 {
+			holders[i].OwnershipPercent = holders[i].NumShares / float64(sharesOutstanding) * 100
 		}
-		perfEntries = append(perfEntries, models.LeaderboardEntry{
-			UserID:   u.ID,
-			Username: u.Username,
-			Ticker:   u.Ticker,
-			Value:    plPercent,
-		})
+		holders[i].HoldingDays = int(now.Sub(holders[i].OpenedAt).Hours() / 24)
 	}
-	sort.Slice(perfEntries, func(i, j int) bool {
-		return perfEntries[i].Value > perfEntries[j].Value
-	})
-	for i := range perfEntries {
-		if i >= 10 {
-			perfEntries = perfEntries[:10]
-			break
-		}
-		perfEntries[i].Rank = i + 1
-	}
-
-	return &models.LeaderboardData{
-		MostValuable:    mostValuable,
-		BiggestGainers:  gainers,
-		BiggestLosers:   losers,
-		RichestTraders:  richest,
-		BestPerformance: perfEntries,
-	}, nil
 }
 
 func (s *MarketService) GetRecentTransactions(limit int) ([]models.TransactionWithDetails, error) {
@@ -391,6 +246,7 @@ func (s *MarketService) RunDailyDecay() {
 		return
 	}
 
+	var priceUpdates []models.PriceUpdate
 	for _, u := range users {
 		newPrice := ApplyDecay(u.CurrentSharePrice)
 		if newPrice != u.CurrentSharePrice {
@@ -401,9 +257,14 @@ func (s *MarketService) RunDailyDecay() {
 			if err := s.txnRepo.RecordPriceHistoryNoTx(u.ID, newPrice); err != nil {
 				log.Printf("Error recording decay price for user %d: %v", u.ID, err)
 			}
+			priceUpdates = append(priceUpdates, models.PriceUpdate{StockUserID: u.ID, OldPrice: u.CurrentSharePrice, NewPrice: newPrice})
 		}
 	}
 
+	if s.alertSvc != nil {
+		s.alertSvc.OnPriceUpdates(priceUpdates)
+	}
+
 	log.Printf("Daily decay applied to %d stocks", len(users))
 }
 
@@ -441,6 +302,9 @@ func (s *MarketService) RunDailyDividends() {
 				msg := fmt.Sprintf("You received %.2f Grub in dividends from your holdings!", dividend)
 				_ = s.notifRepo.Create(u.ID, "dividend", msg, "", "", 0)
 			}
+			if s.achieveSvc != nil {
+				s.achieveSvc.RecordEvent(u.ID, models.EventDividend, dividend)
+			}
 		}
 	}
 
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"strings"
+	"unicode"
+)
+
+const (
+	moderationQueueLimit = 100
+	maxReportDetails     = 500
+	maxBannedWordLength  = 100
+)
+
+var (
+	// ErrBannedFromPosting is returned when a banned user tries to post, reply, vote or report.
+	ErrBannedFromPosting = errors.New("you are banned from posting")
+	// ErrBlockedContent is returned for posts containing a word on the filter list.
+	ErrBlockedContent = errors.New("post contains a blocked word")
+
+	// Rejected reports and admin actions. Their messages are safe to show the caller.
+	ErrReportDetailsTooLong = fmt.Errorf("details must be at most %d characters", maxReportDetails)
+	ErrReportOwnPost        = errors.New("you can't report your own post")
+	ErrAlreadyReported      = errors.New("you already reported this post")
+	ErrPostNotHideable      = errors.New("post is already hidden or doesn't exist")
+	ErrPostNotHidden        = errors.New("post isn't hidden")
+	ErrBanSelf              = errors.New("you can't ban yourself")
+	ErrAlreadyBanned        = errors.New("user is already banned")
+	ErrNotBanned            = errors.New("user isn't banned")
+	ErrInvalidBannedWord    = fmt.Errorf("word must contain a letter or digit and be at most %d characters", maxBannedWordLength)
+	ErrWordAlreadyBlocked   = errors.New("word is already blocked")
+	ErrWordNotBlocked       = errors.New("word isn't blocked")
+)
+
+// ModerationService handles post reports, the admin review queue, bans and the word filter.
+// Posts reaching hideThreshold open reports are hidden until an admin reviews them. Every
+// action is recorded in the moderation log.
+type ModerationService struct {
+	db             *sql.DB
+	moderationRepo *repository.ModerationRepo
+	postRepo       *repository.PostRepo
+	userRepo       *repository.UserRepo
+	notifRepo      *repository.NotificationRepo
+	hideThreshold  int
+}
+
+func NewModerationService(
+	db *sql.DB,
+	moderationRepo *repository.ModerationRepo,
+	postRepo *repository.PostRepo,
+	userRepo *repository.UserRepo,
+	notifRepo *repository.NotificationRepo,
+	hideThreshold int,
+) *ModerationService {
+	return &ModerationService{
+		db:             db,
+		moderationRepo: moderationRepo,
+		postRepo:       postRepo,
+		userRepo:       userRepo,
+		notifRepo:      notifRepo,
+		hideThreshold:  hideThreshold,
+	}
+}
+
+// CheckCanPost returns ErrBannedFromPosting or ErrBlockedContent if the user may not post
+// the given content.
+func (s *ModerationService) CheckCanPost(userID int, content string) error {
+	if err := s.CheckNotBanned(userID); err != nil {
+		return err
+	}
+
+	words, err := s.moderationRepo.GetBannedWords()
+	if err != nil {
+		return err
+	}
+	text := normalizeForFilter(content)
+	for _, w := range words {
+		if containsFilteredWord(text, w.Word) {
+			return ErrBlockedContent
+		}
+	}
+	return nil
+}
+
+func (s *ModerationService) CheckNotBanned(userID int) error {
+	banned, err := s.moderationRepo.IsBanned(userID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrBannedFromPosting
+	}
+	return nil
+}
+
+// ReportPost records a report and hides the post once it has enough open reports. The
+// reason must already be one of models.ReportReasons.
+func (s *ModerationService) ReportPost(userID int, post *models.StockPost, req *models.ReportPostRequest) error {
+	if err := s.CheckNotBanned(userID); err != nil {
+		return err
+	}
+	details := strings.TrimSpace(req.Details)
+	if len(details) > maxReportDetails {
+		return ErrReportDetailsTooLong
+	}
+	if post.AuthorID == userID {
+		return ErrReportOwnPost
+	}
+
+	reported, err := s.moderationRepo.CreateReport(post.ID, userID, req.Reason, details)
+	if err != nil {
+		return err
+	}
+	if !reported {
+		return ErrAlreadyReported
+	}
+
+	count, err := s.moderationRepo.CountOpenReports(post.ID)
+	if err != nil {
+		return err
+	}
+	if s.hideThreshold > 0 && count >= s.hideThreshold {
+		note := fmt.Sprintf("%d open reports", count)
+		if _, err := s.hide(nil, post.ID, models.ModAutoHide, note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ModerationService) GetQueue() ([]models.ReportedPost, error) {
+	queue, err := s.moderationRepo.GetQueue(moderationQueueLimit)
+	if queue == nil {
+		queue = []models.ReportedPost{}
+	}
+	return queue, err
+}
+
+// HidePost hides a post and resolves its reports.
+func (s *ModerationService) HidePost(adminID, postID int, note string) error {
+	hidden, err := s.hide(&adminID, postID, models.ModHide, note)
+	if err != nil {
+		return err
+	}
+	if !hidden {
+		return ErrPostNotHideable
+	}
+	return nil
+}
+
+func (s *ModerationService) hide(actorID *int, postID int, action, note string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	hidden, err := s.moderationRepo.HidePost(tx, postID)
+	if err != nil || !hidden {
+		return false, err
+	}
+	// Automatic hides leave the reports open so an admin still reviews the post
+	if actorID != nil {
+		if err := s.moderationRepo.ResolveReports(tx, postID); err != nil {
+			return false, err
+		}
+	}
+	if err := s.moderationRepo.LogAction(tx, actorID, action, &postID, nil, note); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	if s.notifRepo != nil {
+		if post, err := s.postRepo.GetByID(postID, 0); err == nil {
+			msg := fmt.Sprintf("Your post on %s was hidden by moderators", post.StockTicker)
+			_ = s.notifRepo.Create(post.AuthorID, "post_hidden", msg, "", post.StockTicker, 0)
+		}
+	}
+	return true, nil
+}
+
+// RestorePost makes a hidden post visible again and dismisses its reports.
+func (s *ModerationService) RestorePost(adminID, postID int, note string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	restored, err := s.moderationRepo.RestorePost(tx, postID)
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return ErrPostNotHidden
+	}
+	if err := s.moderationRepo.ResolveReports(tx, postID); err != nil {
+		return err
+	}
+	if err := s.moderationRepo.LogAction(tx, &adminID, models.ModRestore, &postID, nil, note); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DismissReports closes a post's reports without hiding it.
+func (s *ModerationService) DismissReports(adminID, postID int, note string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.moderationRepo.ResolveReports(tx, postID); err != nil {
+		return err
+	}
+	if err := s.moderationRepo.LogAction(tx, &adminID, models.ModDismiss, &postID, nil, note); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetBanned bans or unbans a user from posting, replying, voting and reporting.
+func (s *ModerationService) SetBanned(adminID int, username string, banned bool, note string) error {
+	target, err := s.userRepo.GetByUsername(username)
+	if err != nil || target.Username == "MARKET" {
+		return ErrUserNotFound
+	}
+	if target.ID == adminID {
+		return ErrBanSelf
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	changed, err := s.moderationRepo.SetBanned(tx, target.ID, banned)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		if banned {
+			return ErrAlreadyBanned
+		}
+		return ErrNotBanned
+	}
+
+	action := models.ModBan
+	if !banned {
+		action = models.ModUnban
+	}
+	if err := s.moderationRepo.LogAction(tx, &adminID, action, nil, &target.ID, note); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *ModerationService) GetLog(limit int) ([]models.ModerationLogEntry, error) {
+	entries, err := s.moderationRepo.GetLog(limit)
+	if entries == nil {
+		entries = []models.ModerationLogEntry{}
+	}
+	return entries, err
+}
+
+func (s *ModerationService) GetBannedWords() ([]models.BannedWord, error) {
+	words, err := s.moderationRepo.GetBannedWords()
+	if words == nil {
+		words = []models.BannedWord{}
+	}
+	return words, err
+}
+
+func (s *ModerationService) AddBannedWord(adminID int, word string) error {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if strings.TrimSpace(normalizeForFilter(word)) == "" || len(word) > maxBannedWordLength {
+		return ErrInvalidBannedWord
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	added, err := s.moderationRepo.AddBannedWord(tx, word, adminID)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrWordAlreadyBlocked
+	}
+	if err := s.moderationRepo.LogAction(tx, &adminID, models.ModAddBannedWord, nil, nil, word); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *ModerationService) RemoveBannedWord(adminID int, word string) error {
+	word = strings.ToLower(strings.TrimSpace(word))
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	removed, err := s.moderationRepo.RemoveBannedWord(tx, word)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrWordNotBlocked
+	}
+	if err := s.moderationRepo.LogAction(tx, &adminID, models.ModRemoveBannedWord, nil, nil, word); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// normalizeForFilter lowercases text and replaces everything but letters and digits with
+// single spaces, padded at both ends, so a contains check only matches whole words.
+func normalizeForFilter(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
+
+// containsFilteredWord reports whether text, already passed through normalizeForFilter,
+// contains word as a whole word, or a phrase's words next to each other.
+func containsFilteredWord(text, word string) bool {
+	return strings.Contains(text, normalizeForFilter(word))
+}
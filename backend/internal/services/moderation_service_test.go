@@ -0,0 +1,48 @@
+package services
+
+import "testing"
+
+func TestNormalizeForFilter(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Hello World", " hello world "},
+		{"  spaced   out  ", " spaced out "},
+		{"buy $GRUB now!!!", " buy grub now "},
+		{"don't-stop", " don t stop "},
+		{"Café 42", " café 42 "},
+		{"", "  "},
+	}
+	for _, tt := range tests {
+		if got := normalizeForFilter(tt.text); got != tt.want {
+			t.Errorf("normalizeForFilter(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestContainsFilteredWord(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		word    string
+		want    bool
+	}{
+		{"whole word", "what a scam this is", "scam", true},
+		{"inside a longer word", "first class service", "ass", false},
+		{"at the start of a word", "assets are up", "ass", false},
+		{"case is ignored", "SCAM alert", "Scam", true},
+		{"punctuation around the word", "total...scam!!!", "scam", true},
+		{"next to a cashtag", "$scam to the moon", "scam", true},
+		{"phrase with any separators", "Pump-and,DUMP", "pump and dump", true},
+		{"phrase split by another word", "pump it and dump", "pump and dump", false},
+		{"at the end of the text", "it's a scam", "scam", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsFilteredWord(normalizeForFilter(tt.content), tt.word); got != tt.want {
+				t.Errorf("containsFilteredWord(%q, %q) = %v, want %v", tt.content, tt.word, got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ type PortfolioService struct {
 	balanceRepo   *repository.BalanceRepo
 	portfolioRepo *repository.PortfolioRepo
 	txnRepo       *repository.TransactionRepo
+	achieveSvc    *AchievementService
 }
 
 func NewPortfolioService(
@@ -19,12 +20,14 @@ func NewPortfolioService(
 	balanceRepo *repository.BalanceRepo,
 	portfolioRepo *repository.PortfolioRepo,
 	txnRepo *repository.TransactionRepo,
+	achieveSvc *AchievementService,
 ) *PortfolioService {
 	return &PortfolioService{
 		userRepo:      userRepo,
 		balanceRepo:   balanceRepo,
 		portfolioRepo: portfolioRepo,
 		txnRepo:       txnRepo,
+		achieveSvc:    achieveSvc,
 	}
 }
 
@@ -125,6 +128,10 @@ func (s *PortfolioService) ClaimDailyBonus(userID int) (float64, error) {
 		return 0, err
 	}
 
+	if s.achieveSvc != nil {
+		s.achieveSvc.RecordEvent(userID, models.EventDailyClaim, totalBonus)
+	}
+
 	return balance.GrubBalance + totalBonus, nil
 }
 
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"grub-exchange/internal/utils"
+	"log"
+)
+
+const (
+	// At most this many distinct users and stocks are looked up per post; later mentions
+	// and cashtags stay plain text
+	maxPostMentions = 10
+	maxPostCashtags = 10
+
+	entityBackfillBatch = 500
+)
+
+// PostEntityService resolves the @mentions and $cashtags in post content against existing
+// users and stocks, and notifies mentioned users.
+type PostEntityService struct {
+	postRepo   *repository.PostRepo
+	userRepo   *repository.UserRepo
+	leagueRepo *repository.LeagueRepo
+	notifRepo  *repository.NotificationRepo
+}
+
+func NewPostEntityService(
+	postRepo *repository.PostRepo,
+	userRepo *repository.UserRepo,
+	leagueRepo *repository.LeagueRepo,
+	notifRepo *repository.NotificationRepo,
+) *PostEntityService {
+	return &PostEntityService{
+		postRepo:   postRepo,
+		userRepo:   userRepo,
+		leagueRepo: leagueRepo,
+		notifRepo:  notifRepo,
+	}
+}
+
+// Resolve parses content into entities. Mentions of unknown users and cashtags of unknown
+// stocks are dropped; cashtags of old tickers resolve to the stock's current ticker.
+func (s *PostEntityService) Resolve(content string) []models.PostEntity {
+	return resolveEntities(utils.ParseEntities(content),
+		func(username string) *models.User { return s.lookup(s.userRepo.GetByUsername(username)) },
+		func(ticker string) *models.User { return s.lookup(s.userRepo.GetByTicker(ticker)) },
+	)
+}
+
+// resolveEntities resolves parsed entities with the given lookups, which return nil for
+// unknown users and stocks. Each distinct username and ticker is looked up once, up to
+// maxPostMentions and maxPostCashtags of them.
+func resolveEntities(parsed []models.PostEntity, lookupUser, lookupStock func(string) *models.User) []models.PostEntity {
+	users := make(map[string]*models.User)
+	stocks := make(map[string]*models.User)
+
+	entities := []models.PostEntity{}
+	for _, e := range parsed {
+		switch e.Type {
+		case models.EntityMention:
+			u, seen := users[e.Username]
+			if !seen {
+				if len(users) >= maxPostMentions {
+					continue
+				}
+				u = lookupUser(e.Username)
+				users[e.Username] = u
+			}
+			if u == nil {
+				continue
+			}
+			e.UserID, e.Username = u.ID, u.Username
+		case models.EntityCashtag:
+			u, seen := stocks[e.Ticker]
+			if !seen {
+				if len(stocks) >= maxPostCashtags {
+					continue
+				}
+				u = lookupStock(e.Ticker)
+				stocks[e.Ticker] = u
+			}
+			if u == nil {
+				continue
+			}
+			e.UserID, e.Ticker = u.ID, u.Ticker
+		}
+		entities = append(entities, e)
+	}
+	return entities
+}
+
+// lookup drops failed lookups and the MARKET system user, which can't be mentioned or traded.
+func (s *PostEntityService) lookup(u *models.User, err error) *models.User {
+	if err != nil || u.Username == "MARKET" {
+		return nil
+	}
+	return u
+}
+
+// NotifyMentions tells the users mentioned in a post, other than its author and the users
+// in skip. Mentions in league posts only notify league members.
+func (s *PostEntityService) NotifyMentions(post *models.StockPost, authorUsername string, skip map[int]bool) {
+	if s.notifRepo == nil {
+		return
+	}
+
+	notified := map[int]bool{post.AuthorID: true}
+	for _, e := range post.Entities {
+		if e.Type != models.EntityMention || notified[e.UserID] || skip[e.UserID] {
+			continue
+		}
+		notified[e.UserID] = true
+
+		if post.LeagueID != nil {
+			if _, kicked, err := s.leagueRepo.GetMembership(*post.LeagueID, e.UserID); err != nil || kicked {
+				continue
+			}
+		}
+		msg := fmt.Sprintf("%s mentioned you in a post on %s", authorUsername, post.StockTicker)
+		_ = s.notifRepo.Create(e.UserID, "mention", msg, authorUsername, post.StockTicker, 0)
+	}
+}
+
+// MentionedUsers returns the IDs of the users a post's entities mention.
+func MentionedUsers(entities []models.PostEntity) map[int]bool {
+	ids := make(map[int]bool)
+	for _, e := range entities {
+		if e.Type == models.EntityMention {
+			ids[e.UserID] = true
+		}
+	}
+	return ids
+}
+
+// BackfillEntities parses posts written before entities existed. Nobody is notified.
+func (s *PostEntityService) BackfillEntities() {
+	total := 0
+	for {
+		posts, err := s.postRepo.GetUnparsed(entityBackfillBatch)
+		if err != nil {
+			log.Printf("Error loading posts to parse: %v", err)
+			return
+		}
+		if len(posts) == 0 {
+			break
+		}
+		for _, p := range posts {
+			if err := s.postRepo.SetEntities(p.ID, p.StockUserID, s.Resolve(p.Content)); err != nil {
+				log.Printf("Error saving entities for post %d: %v", p.ID, err)
+				return
+			}
+		}
+		total += len(posts)
+	}
+	if total > 0 {
+		log.Printf("Parsed mentions and cashtags in %d existing posts", total)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"grub-exchange/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestResolveEntitiesCaps(t *testing.T) {
+	mentions := func(prefix string, n int) []models.PostEntity {
+		out := make([]models.PostEntity, n)
+		for i := range out {
+			out[i] = models.PostEntity{Type: models.EntityMention, Username: fmt.Sprintf("%s%02d", prefix, i)}
+		}
+		return out
+	}
+	cashtags := func(n int) []models.PostEntity {
+		out := make([]models.PostEntity, n)
+		for i := range out {
+			out[i] = models.PostEntity{Type: models.EntityCashtag, Ticker: fmt.Sprintf("TICK%c", 'A'+i)}
+		}
+		return out
+	}
+	concat := func(parts ...[]models.PostEntity) []models.PostEntity {
+		var out []models.PostEntity
+		for _, p := range parts {
+			out = append(out, p...)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name        string
+		parsed      []models.PostEntity
+		wantCount   int
+		wantLookups int
+	}{
+		{"mentions past the cap stay plain text", mentions("user", maxPostMentions+2), maxPostMentions, maxPostMentions},
+		{"cashtags past the cap stay plain text", cashtags(maxPostCashtags + 2), maxPostCashtags, maxPostCashtags},
+		{
+			name:        "a repeated mention past the cap still resolves",
+			parsed:      concat(mentions("user", maxPostMentions), mentions("user", 1)),
+			wantCount:   maxPostMentions + 1,
+			wantLookups: maxPostMentions,
+		},
+		{
+			name:        "unknown users count toward the cap",
+			parsed:      concat(mentions("ghost", maxPostMentions), mentions("user", 1)),
+			wantCount:   0,
+			wantLookups: maxPostMentions,
+		},
+		{
+			name:        "mentions and cashtags are capped separately",
+			parsed:      concat(mentions("user", maxPostMentions), cashtags(maxPostCashtags)),
+			wantCount:   maxPostMentions + maxPostCashtags,
+			wantLookups: maxPostMentions + maxPostCashtags,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups := 0
+			lookup := func(name string) *models.User {
+				lookups++
+				if strings.HasPrefix(name, "ghost") {
+					return nil
+				}
+				return &models.User{ID: lookups, Username: name, Ticker: name}
+			}
+
+			got := resolveEntities(tt.parsed, lookup, lookup)
+			if len(got) != tt.wantCount {
+				t.Errorf("resolved %d entities, want %d", len(got), tt.wantCount)
+			}
+			if lookups != tt.wantLookups {
+				t.Errorf("made %d lookups, want %d", lookups, tt.wantLookups)
+			}
+		})
+	}
+}
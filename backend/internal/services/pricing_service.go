@@ -1,6 +1,10 @@
 package services
 
-import "math"
+import (
+	"grub-exchange/internal/models"
+	"math"
+	"time"
+)
 
 const (
 	VolatilityFactor = 5.0
@@ -71,3 +75,32 @@ func ApplyDecay(currentPrice float64) float64 {
 	newPrice = math.Round(newPrice*100) / 100
 	return math.Max(MinPrice, math.Min(MaxPrice, newPrice))
 }
+
+// TimeWeightedAveragePrice averages a step-function price series over [from, to].
+// startPrice is the price in effect at from; each point holds until the next one.
+// Used for the delisting payout so a last-minute pump or dump barely moves the price.
+func TimeWeightedAveragePrice(startPrice float64, points []models.PriceHistory, from, to time.Time) float64 {
+	total := to.Sub(from).Seconds()
+	if total <= 0 {
+		return startPrice
+	}
+
+	var weighted float64
+	price := startPrice
+	cursor := from
+	for _, p := range points {
+		if p.Timestamp.Before(from) {
+			price = p.Price
+			continue
+		}
+		if p.Timestamp.After(to) {
+			break
+		}
+		weighted += price * p.Timestamp.Sub(cursor).Seconds()
+		price = p.Price
+		cursor = p.Timestamp
+	}
+	weighted += price * to.Sub(cursor).Seconds()
+
+	return math.Round(weighted/total*100) / 100
+}
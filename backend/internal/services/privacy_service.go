@@ -0,0 +1,43 @@
+package services
+
+import (
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+)
+
+// PrivacyService reads and updates users' privacy preferences.
+type PrivacyService struct {
+	privacyRepo *repository.PrivacyRepo
+}
+
+func NewPrivacyService(privacyRepo *repository.PrivacyRepo) *PrivacyService {
+	return &PrivacyService{privacyRepo: privacyRepo}
+}
+
+func (s *PrivacyService) GetSettings(userID int) (*models.PrivacySettings, error) {
+	return s.privacyRepo.Get(userID)
+}
+
+// UpdateSettings applies the settings present in the request and keeps the rest.
+func (s *PrivacyService) UpdateSettings(userID int, req *models.UpdatePrivacyRequest) (*models.PrivacySettings, error) {
+	settings, err := s.privacyRepo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.HideTrades != nil {
+		settings.HideTrades = *req.HideTrades
+	}
+	if req.AnonymizeTrades != nil {
+		settings.AnonymizeTrades = *req.AnonymizeTrades
+	}
+	if req.HidePortfolioValue != nil {
+		settings.HidePortfolioValue = *req.HidePortfolioValue
+	}
+	if req.HideHoldings != nil {
+		settings.HideHoldings = *req.HideHoldings
+	}
+	if err := s.privacyRepo.Save(userID, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"time"
+)
+
+const (
+	profileTopHolders  = 10
+	profileRecentPosts = 10
+	profileChartWindow = 30 * 24 * time.Hour
+)
+
+// ErrUserNotFound is returned for unknown, closed and system accounts.
+var ErrUserNotFound = errors.New("user not found")
+
+// ProfileService builds public profiles: what other users can see about someone, subject to
+// that user's privacy settings. Users viewing their own profile see everything.
+type ProfileService struct {
+	userRepo         *repository.UserRepo
+	portfolioRepo    *repository.PortfolioRepo
+	txnRepo          *repository.TransactionRepo
+	postRepo         *repository.PostRepo
+	achieveRepo      *repository.AchievementRepo
+	followRepo       *repository.FollowRepo
+	privacyRepo      *repository.PrivacyRepo
+	portfolioService *PortfolioService
+}
+
+func NewProfileService(
+	userRepo *repository.UserRepo,
+	portfolioRepo *repository.PortfolioRepo,
+	txnRepo *repository.TransactionRepo,
+	postRepo *repository.PostRepo,
+	achieveRepo *repository.AchievementRepo,
+	followRepo *repository.FollowRepo,
+	privacyRepo *repository.PrivacyRepo,
+	portfolioService *PortfolioService,
+) *ProfileService {
+	return &ProfileService{
+		userRepo:         userRepo,
+		portfolioRepo:    portfolioRepo,
+		txnRepo:          txnRepo,
+		postRepo:         postRepo,
+		achieveRepo:      achieveRepo,
+		followRepo:       followRepo,
+		privacyRepo:      privacyRepo,
+		portfolioService: portfolioService,
+	}
+}
+
+func (s *ProfileService) GetPublicProfile(viewerID int, username string) (*models.PublicProfile, error) {
+	user, err := s.userRepo.GetByUsername(username)
+	if err != nil || user.Username == "MARKET" {
+		return nil, ErrUserNotFound
+	}
+
+	isMe := user.ID == viewerID
+	privacy := &models.PrivacySettings{}
+	if !isMe {
+		if privacy, err = s.privacyRepo.Get(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	profile := &models.PublicProfile{
+		Username:          user.Username,
+		Ticker:            user.Ticker,
+		Bio:               user.Bio,
+		CurrentSharePrice: user.CurrentSharePrice,
+		SharesOutstanding: user.SharesOutstanding,
+		CreatedAt:         user.CreatedAt,
+		IsMe:              isMe,
+		Achievements:      []models.UserAchievement{},
+		TopHolders:        []models.StockHolder{},
+		RecentPosts:       []models.StockPost{},
+		Holdings:          []models.PublicHolding{},
+		Performance:       []models.PerformancePoint{},
+	}
+
+	if price24hAgo, _ := s.txnRepo.GetPriceAt(user.ID, time.Now().Add(-24*time.Hour)); price24hAgo > 0 {
+		profile.Change24hPercent = ((user.CurrentSharePrice - price24hAgo) / price24hAgo) * 100
+	}
+
+	if profile.FollowerCount, profile.FollowingCount, err = s.followRepo.GetCounts(user.ID); err != nil {
+		return nil, err
+	}
+	if !isMe {
+		if profile.IsFollowing, err = s.followRepo.IsFollowing(viewerID, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if achievements, err := s.achieveRepo.GetByUser(user.ID); err != nil {
+		return nil, err
+	} else if achievements != nil {
+		profile.Achievements = achievements
+	}
+
+	if err := s.fillTopHolders(profile, user); err != nil {
+		return nil, err
+	}
+
+	if posts, err := s.postRepo.GetByAuthor(user.ID, viewerID, profileRecentPosts); err != nil {
+		return nil, err
+	} else if posts != nil {
+		profile.RecentPosts = posts
+	}
+
+	profile.HoldingsHidden = privacy.HideHoldings
+	profile.ValueHidden = privacy.HidePortfolioValue
+	if !privacy.HideHoldings {
+		if err := s.fillHoldings(profile, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.fillPerformance(profile, user.ID); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (s *ProfileService) fillTopHolders(profile *models.PublicProfile, user *models.User) error {
+	holders, err := s.portfolioRepo.GetTopHolders(user.ID, profileTopHolders, 0)
+	if err != nil {
+		return err
+	}
+	fillHolderStats(holders, user.SharesOutstanding, time.Now())
+	if holders != nil {
+		profile.TopHolders = holders
+	}
+	return nil
+}
+
+func (s *ProfileService) fillHoldings(profile *models.PublicProfile, userID int) error {
+	portfolio, err := s.portfolioService.GetUserPortfolio(userID)
+	if err != nil {
+		return err
+	}
+	for _, h := range portfolio.Holdings {
+		holding := models.PublicHolding{
+			Ticker:            h.Ticker,
+			Username:          h.Username,
+			CurrentPrice:      h.CurrentPrice,
+			ProfitLossPercent: h.ProfitLossPercent,
+		}
+		if !profile.ValueHidden {
+			holding.NumShares = h.NumShares
+			holding.Value = h.TotalValue
+		}
+		profile.Holdings = append(profile.Holdings, holding)
+	}
+	return nil
+}
+
+// fillPerformance charts the last 30 days hourly. Returns are measured from the first point,
+// so the chart still shows performance when values are hidden.
+func (s *ProfileService) fillPerformance(profile *models.PublicProfile, userID int) error {
+	snapshots, err := s.userRepo.GetHourlyPortfolioSnapshots(userID, time.Now().Add(-profileChartWindow))
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	base := snapshots[0].TotalValue
+	for _, snap := range snapshots {
+		point := models.PerformancePoint{Timestamp: snap.Timestamp}
+		if base > 0 {
+			point.ReturnPercent = (snap.TotalValue - base) / base * 100
+		}
+		if !profile.ValueHidden {
+			point.Value = snap.TotalValue
+		}
+		profile.Performance = append(profile.Performance, point)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"log"
+	"time"
+)
+
+// retentionTier thins out rows older than after to one bucket per resolution.
+type retentionTier struct {
+	name       string
+	after      time.Duration
+	resolution time.Duration
+}
+
+// The last hour is kept raw, then 1-minute resolution for a week, hourly for 90 days and
+// daily after that.
+var retentionTiers = []retentionTier{
+	{name: "1m", after: time.Hour, resolution: time.Minute},
+	{name: "1h", after: 7 * 24 * time.Hour, resolution: time.Hour},
+	{name: "1d", after: 90 * 24 * time.Hour, resolution: 24 * time.Hour},
+}
+
+// retentionTables are compacted by the retention job. price_history must not be compacted
+// past the candle rollup's watermark, or candles would be built from thinned-out rows.
+var retentionTables = []string{"price_history", "portfolio_snapshots"}
+
+const (
+	// Each compaction transaction covers at most this much history
+	retentionChunk = 7 * 24 * time.Hour
+
+	retentionReportRuns = 50
+)
+
+// RetentionService compacts old price history and portfolio snapshots into coarser buckets
+// as they age, keeping enough rows per bucket that prices at bucket boundaries, all-time
+// highs and lows, and charts stay correct.
+type RetentionService struct {
+	retentionRepo *repository.RetentionRepo
+	candleRepo    *repository.CandleRepo
+}
+
+func NewRetentionService(retentionRepo *repository.RetentionRepo, candleRepo *repository.CandleRepo) *RetentionService {
+	return &RetentionService{retentionRepo: retentionRepo, candleRepo: candleRepo}
+}
+
+// Run compacts every table to every tier, picking up where the previous run stopped.
+func (s *RetentionService) Run() {
+	candlesDone, err := s.candleRepo.GetWatermark()
+	if err != nil {
+		log.Printf("Error reading candle watermark: %v", err)
+		return
+	}
+
+	for _, table := range retentionTables {
+		for _, tier := range retentionTiers {
+			cutoff := time.Now().Add(-tier.after)
+			if table == "price_history" && candlesDone.Before(cutoff) {
+				cutoff = candlesDone
+			}
+			cutoff = cutoff.Truncate(tier.resolution)
+
+			if err := s.compact(table, tier, cutoff); err != nil {
+				log.Printf("Error compacting %s to %s: %v", table, tier.name, err)
+				return
+			}
+		}
+	}
+}
+
+func (s *RetentionService) compact(table string, tier retentionTier, cutoff time.Time) error {
+	watermarkName := fmt.Sprintf("retention:%s:%s", table, tier.name)
+	from, err := s.retentionRepo.GetWatermark(watermarkName)
+	if err != nil {
+		return err
+	}
+	if from.IsZero() {
+		if from, err = s.retentionRepo.GetOldest(table); err != nil || from.IsZero() {
+			return err
+		}
+		from = from.Truncate(tier.resolution)
+	}
+	if !from.Before(cutoff) {
+		return nil
+	}
+
+	started := time.Now()
+	var deleted int64
+	for from.Before(cutoff) {
+		to := from.Add(retentionChunk)
+		if to.After(cutoff) {
+			to = cutoff
+		}
+		n, err := s.retentionRepo.Compact(table, from, to, tier.resolution, watermarkName)
+		if err != nil {
+			return err
+		}
+		deleted += n
+		from = to
+	}
+
+	// Only runs that reclaimed something are worth reporting
+	if deleted == 0 {
+		return nil
+	}
+	log.Printf("Compacted %s to %s resolution up to %s: %d rows deleted", table, tier.name, cutoff.Format(time.RFC3339), deleted)
+	return s.retentionRepo.RecordRun(&models.RetentionRun{
+		Table:       table,
+		Resolution:  tier.name,
+		Cutoff:      cutoff,
+		RowsDeleted: deleted,
+		DurationMs:  int(time.Since(started).Milliseconds()),
+	})
+}
+
+// GetReport shows the retention policy, the rows reclaimed so far and the latest runs.
+func (s *RetentionService) GetReport() (*models.RetentionReport, error) {
+	report := &models.RetentionReport{Tiers: []models.RetentionTier{}}
+	for _, tier := range retentionTiers {
+		report.Tiers = append(report.Tiers, models.RetentionTier{Resolution: tier.name, AfterHours: tier.after.Hours()})
+	}
+
+	var err error
+	if report.RowsReclaimed, err = s.retentionRepo.GetRowsReclaimed(); err != nil {
+		return nil, err
+	}
+	if report.EstimatedRows, err = s.retentionRepo.GetEstimatedRows(retentionTables); err != nil {
+		return nil, err
+	}
+	if report.RecentRuns, err = s.retentionRepo.GetRecentRuns(retentionReportRuns); err != nil {
+		return nil, err
+	}
+	if report.RecentRuns == nil {
+		report.RecentRuns = []models.RetentionRun{}
+	}
+	return report, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxSearchQueryLength = 100
+	// Searching everything merges ranked pages of users and posts, so deep pages get costly
+	maxSearchOffset = 1000
+)
+
+// ErrInvalidSearch is returned for an empty or overlong query or an unknown result type.
+var ErrInvalidSearch = errors.New("invalid search")
+
+// SearchService searches traders by username, ticker and bio and public posts by content
+// using Postgres full-text search.
+type SearchService struct {
+	userRepo *repository.UserRepo
+	postRepo *repository.PostRepo
+}
+
+func NewSearchService(userRepo *repository.UserRepo, postRepo *repository.PostRepo) *SearchService {
+	return &SearchService{userRepo: userRepo, postRepo: postRepo}
+}
+
+// Search returns a page of results for the query, best match first. resultType limits the
+// results to models.SearchTypeUser or models.SearchTypePost; empty searches both.
+func (s *SearchService) Search(userID int, query, resultType string, limit, offset int) (*models.SearchPage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || len(query) > maxSearchQueryLength {
+		return nil, fmt.Errorf("%w: q must be 1 to %d characters", ErrInvalidSearch, maxSearchQueryLength)
+	}
+	if offset > maxSearchOffset {
+		return nil, fmt.Errorf("%w: offset must be at most %d", ErrInvalidSearch, maxSearchOffset)
+	}
+
+	// Fetch one extra result to learn whether there's another page
+	var results []models.SearchResult
+	var err error
+	switch resultType {
+	case models.SearchTypeUser:
+		results, err = s.userRepo.Search(query, tickerPrefix(query), limit+1, offset)
+	case models.SearchTypePost:
+		results, err = s.postRepo.Search(query, userID, limit+1, offset)
+	case "":
+		results, err = s.searchAll(userID, query, limit+1, offset)
+	default:
+		return nil, fmt.Errorf("%w: type must be %q or %q", ErrInvalidSearch, models.SearchTypeUser, models.SearchTypePost)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.SearchPage{Query: query, Results: []models.SearchResult{}}
+	if len(results) > limit {
+		results = results[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	if results != nil {
+		page.Results = results
+	}
+	return page, nil
+}
+
+// searchAll merges the best offset+limit users and posts by rank and returns the requested
+// window. Users win ties, so an exact ticker match stays on top.
+func (s *SearchService) searchAll(userID int, query string, limit, offset int) ([]models.SearchResult, error) {
+	users, err := s.userRepo.Search(query, tickerPrefix(query), offset+limit, 0)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.postRepo.Search(query, userID, offset+limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	results := append(users, posts...)
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Rank > results[j].Rank })
+	if offset >= len(results) {
+		return nil, nil
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// tickerPrefix returns the query as a ticker prefix, or "" if it can't be the start of one.
+func tickerPrefix(query string) string {
+	query = strings.TrimPrefix(query, "$")
+	if query == "" {
+		return ""
+	}
+	for _, r := range query {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return ""
+		}
+	}
+	return strings.ToUpper(query)
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// seasonPrizes are paid out when a season is finalized, by final rank.
+var seasonPrizes = []struct {
+	MaxRank int
+	Reward  float64
+	Badge   string
+}{
+	{1, 1000, "champion"},
+	{2, 500, "runner_up"},
+	{3, 250, "third_place"},
+	{10, 100, "top_10"},
+}
+
+// SeasonService runs competitive seasons. Standings rank users by the return on their total
+// portfolio value (cash + holdings) over the season, so late joiners compete on equal terms.
+type SeasonService struct {
+	db          *sql.DB
+	seasonRepo  *repository.SeasonRepo
+	balanceRepo *repository.BalanceRepo
+	notifRepo   *repository.NotificationRepo
+	privacyRepo *repository.PrivacyRepo
+	length      time.Duration
+	firstStart  time.Time
+}
+
+// NewSeasonService creates the service. Seasons last length; firstStart, if non-zero,
+// sets when the very first season begins (otherwise it starts immediately).
+func NewSeasonService(
+	db *sql.DB,
+	seasonRepo *repository.SeasonRepo,
+	balanceRepo *repository.BalanceRepo,
+	notifRepo *repository.NotificationRepo,
+	privacyRepo *repository.PrivacyRepo,
+	length time.Duration,
+	firstStart time.Time,
+) *SeasonService {
+	return &SeasonService{
+		db:          db,
+		seasonRepo:  seasonRepo,
+		balanceRepo: balanceRepo,
+		notifRepo:   notifRepo,
+		privacyRepo: privacyRepo,
+		length:      length,
+		firstStart:  firstStart,
+	}
+}
+
+// RunSeasonJobs finalizes any seasons that have ended and makes sure one is running.
+func (s *SeasonService) RunSeasonJobs() {
+	due, err := s.seasonRepo.GetDueForFinalization()
+	if err != nil {
+		log.Printf("Error loading seasons to finalize: %v", err)
+		return
+	}
+	for _, season := range due {
+		if err := s.finalize(season); err != nil {
+			log.Printf("Error finalizing %s: %v", season.Name, err)
+		}
+	}
+
+	if err := s.ensureCurrentSeason(); err != nil {
+		log.Printf("Error starting season: %v", err)
+	}
+}
+
+// ensureCurrentSeason starts the next season when the latest one has ended. The next season
+// starts where the last one ended to keep the cadence, unless the server was down for a whole
+// season, in which case it starts now.
+func (s *SeasonService) ensureCurrentSeason() error {
+	latest, err := s.seasonRepo.GetLatest()
+	if err != nil {
+		return err
+	}
+	if latest != nil && time.Now().Before(latest.EndsAt) {
+		return nil
+	}
+
+	now := time.Now()
+	start := now
+	switch {
+	case latest != nil && now.Before(latest.EndsAt.Add(s.length)):
+		start = latest.EndsAt
+	case latest == nil && !s.firstStart.IsZero():
+		start = s.firstStart
+	}
+
+	count, err := s.seasonRepo.Count()
+	if err != nil {
+		return err
+	}
+	season, err := s.seasonRepo.Create(fmt.Sprintf("Season %d", count+1), start, start.Add(s.length))
+	if err != nil {
+		return err
+	}
+	log.Printf("%s started, ends %s", season.Name, season.EndsAt.Format(time.RFC3339))
+	return nil
+}
+
+// finalize archives a season's standings and pays out prizes in one transaction.
+func (s *SeasonService) finalize(season models.Season) error {
+	standings, err := s.rankedStandings(season)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	marked, err := s.seasonRepo.MarkFinalized(tx, season.ID)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return nil
+	}
+
+	for _, st := range standings {
+		if err := s.seasonRepo.SaveStanding(tx, season.ID, st); err != nil {
+			return err
+		}
+		if st.Reward > 0 {
+			if err := s.balanceRepo.UpdateBalance(tx, st.UserID, st.Reward); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("%s finalized with %d ranked traders", season.Name, len(standings))
+
+	if s.notifRepo != nil {
+		for _, st := range standings {
+			msg := fmt.Sprintf("%s has ended. You finished #%d of %d with a %.2f%% return.",
+				season.Name, st.Rank, len(standings), st.ReturnPercent)
+			if st.Reward > 0 {
+				msg += fmt.Sprintf(" You earned %.2f Grub!", st.Reward)
+			}
+			_ = s.notifRepo.Create(st.UserID, "season_end", msg, "", "", 0)
+		}
+	}
+	return nil
+}
+
+// rankedStandings computes a season's standings up to its end (or now, for a live season),
+// ranked by return with prizes attached.
+func (s *SeasonService) rankedStandings(season models.Season) ([]models.SeasonStanding, error) {
+	end := season.EndsAt
+	if now := time.Now(); now.Before(end) {
+		end = now
+	}
+
+	standings, err := s.seasonRepo.ComputeStandings(season.StartsAt, end)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].ReturnPercent > standings[j].ReturnPercent
+	})
+	for i := range standings {
+		st := &standings[i]
+		st.Rank = i + 1
+		st.ReturnPercent = math.Round(st.ReturnPercent*100) / 100
+		for _, p := range seasonPrizes {
+			if st.Rank <= p.MaxRank {
+				st.Reward = p.Reward
+				st.Badge = p.Badge
+				break
+			}
+		}
+	}
+	return standings, nil
+}
+
+func (s *SeasonService) ListSeasons() ([]models.Season, error) {
+	seasons, err := s.seasonRepo.GetAll()
+	if seasons == nil {
+		seasons = []models.Season{}
+	}
+	return seasons, err
+}
+
+// GetCurrentLeaderboard returns live standings for the running season.
+func (s *SeasonService) GetCurrentLeaderboard(userID, limit int) (*models.SeasonLeaderboard, error) {
+	latest, err := s.seasonRepo.GetLatest()
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, errors.New("no season is running")
+	}
+	return s.GetLeaderboard(latest.ID, userID, limit)
+}
+
+// GetLeaderboard returns a season's standings: archived for completed seasons, live otherwise.
+// Prizes shown for a live season are what each rank would win if it ended now.
+func (s *SeasonService) GetLeaderboard(seasonID, userID, limit int) (*models.SeasonLeaderboard, error) {
+	season, err := s.seasonRepo.GetByID(seasonID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("season not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var standings []models.SeasonStanding
+	if season.FinalizedAt != nil {
+		standings, err = s.seasonRepo.GetStandings(season.ID)
+	} else {
+		standings, err = s.rankedStandings(*season)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Other users' portfolio values are omitted if they hide them; the return still ranks them
+	hiddenValues, err := s.privacyRepo.GetPortfolioValueHidden()
+	if err != nil {
+		return nil, err
+	}
+
+	board := &models.SeasonLeaderboard{Season: *season, Standings: []models.SeasonStanding{}}
+	for i, st := range standings {
+		if st.UserID == userID {
+			mine := st
+			board.MyRank = &mine
+		} else if hiddenValues[st.UserID] {
+			st.StartValue, st.EndValue = 0, 0
+		}
+		if i < limit {
+			board.Standings = append(board.Standings, st)
+		}
+	}
+	return board, nil
+}
+
+func (s *SeasonService) GetBadges(userID int) ([]models.SeasonBadge, error) {
+	badges, err := s.seasonRepo.GetBadges(userID)
+	if badges == nil {
+		badges = []models.SeasonBadge{}
+	}
+	return badges, err
+}
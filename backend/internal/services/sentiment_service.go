@@ -0,0 +1,256 @@
+package services
+
+import (
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Only votes on posts written within the window count, and a vote's weight halves for
+	// every half-life of the post's age, so sentiment follows current news.
+	sentimentWindow   = 72 * time.Hour
+	sentimentHalfLife = 24 * time.Hour
+
+	// Voters who vote the same way on the same post within ringVoteGap of each other, on at
+	// least ringMinSharedPosts posts about one stock, are linked; ringMinSize or more linked
+	// voters form a voting ring, and their votes on that stock don't count.
+	ringVoteGap        = 15 * time.Minute
+	ringMinSharedPosts = 3
+	ringMinSize        = 3
+)
+
+// SentimentService turns votes on stock posts into the sentiment scores the market maker
+// trades on, discounting votes that look like manipulation: votes on posts about the
+// voter's own stock and votes from coordinated voting rings.
+type SentimentService struct {
+	postRepo *repository.PostRepo
+}
+
+func NewSentimentService(postRepo *repository.PostRepo) *SentimentService {
+	return &SentimentService{postRepo: postRepo}
+}
+
+// GetSentiments returns the weighted net sentiment of every stock with recent votes.
+func (s *SentimentService) GetSentiments() (map[int]float64, error) {
+	sentiments, _, err := s.compute(time.Now())
+	return sentiments, err
+}
+
+// GetReport explains the current sentiment: each stock's raw and weighted totals, the
+// voting rings found and the votes that were discounted. An empty ticker covers all stocks.
+func (s *SentimentService) GetReport(ticker string) (*models.SentimentReport, error) {
+	_, report, err := s.compute(time.Now())
+	if err != nil || ticker == "" {
+		return report, err
+	}
+
+	filtered := &models.SentimentReport{
+		WindowHours:     report.WindowHours,
+		HalfLifeHours:   report.HalfLifeHours,
+		Stocks:          []models.StockSentiment{},
+		Rings:           []models.VotingRing{},
+		DiscountedVotes: []models.DiscountedVote{},
+	}
+	for _, st := range report.Stocks {
+		if strings.EqualFold(st.Ticker, ticker) {
+			filtered.Stocks = append(filtered.Stocks, st)
+		}
+	}
+	for _, ring := range report.Rings {
+		if strings.EqualFold(ring.Ticker, ticker) {
+			filtered.Rings = append(filtered.Rings, ring)
+		}
+	}
+	for _, v := range report.DiscountedVotes {
+		if strings.EqualFold(v.Ticker, ticker) {
+			filtered.DiscountedVotes = append(filtered.DiscountedVotes, v)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *SentimentService) compute(now time.Time) (map[int]float64, *models.SentimentReport, error) {
+	votes, err := s.postRepo.GetVotesSince(now.Add(-sentimentWindow))
+	if err != nil {
+		return nil, nil, err
+	}
+	sentiments, report := scoreSentiment(votes, now)
+	return sentiments, report, nil
+}
+
+// scoreSentiment weighs votes ordered by post into each stock's sentiment, as of now.
+func scoreSentiment(votes []models.SentimentVote, now time.Time) (map[int]float64, *models.SentimentReport) {
+	ringMembers, rings := detectVotingRings(votes)
+
+	report := &models.SentimentReport{
+		WindowHours:     sentimentWindow.Hours(),
+		HalfLifeHours:   sentimentHalfLife.Hours(),
+		Stocks:          []models.StockSentiment{},
+		Rings:           rings,
+		DiscountedVotes: []models.DiscountedVote{},
+	}
+	stats := make(map[int]*models.StockSentiment)
+	for _, v := range votes {
+		st, ok := stats[v.StockUserID]
+		if !ok {
+			st = &models.StockSentiment{Ticker: v.Ticker}
+			stats[v.StockUserID] = st
+		}
+		st.Votes++
+		st.RawNet += v.VoteType
+
+		reason := ""
+		if v.VoterID == v.StockUserID {
+			// Voting on your own stock is blocked, but votes cast before that still exist
+			reason = models.DiscountOwnStock
+		} else if ringMembers[v.StockUserID][v.VoterID] {
+			reason = models.DiscountVotingRing
+		}
+		if reason != "" {
+			st.DiscountedVotes++
+			report.DiscountedVotes = append(report.DiscountedVotes, models.DiscountedVote{
+				PostID:        v.PostID,
+				Ticker:        v.Ticker,
+				VoterUsername: v.VoterUsername,
+				VoteType:      v.VoteType,
+				VotedAt:       v.VotedAt,
+				Reason:        reason,
+			})
+			continue
+		}
+
+		age := now.Sub(v.PostCreatedAt)
+		st.WeightedNet += float64(v.VoteType) * math.Pow(0.5, age.Hours()/sentimentHalfLife.Hours())
+	}
+
+	sentiments := make(map[int]float64, len(stats))
+	for stockUserID, st := range stats {
+		sentiments[stockUserID] = st.WeightedNet
+		report.Stocks = append(report.Stocks, *st)
+	}
+	sort.Slice(report.Stocks, func(i, j int) bool {
+		return math.Abs(report.Stocks[i].WeightedNet) > math.Abs(report.Stocks[j].WeightedNet)
+	})
+	return sentiments, report
+}
+
+type voterPair struct {
+	stockUserID int
+	a, b        int
+}
+
+type stockVoter struct {
+	stockUserID int
+	voterID     int
+}
+
+// detectVotingRings finds voting rings among votes ordered by post. It returns the ring
+// members per stock and a description of each ring.
+func detectVotingRings(votes []models.SentimentVote) (map[int]map[int]bool, []models.VotingRing) {
+	usernames := make(map[int]string)
+	tickers := make(map[int]string)
+
+	// Posts on which each pair of voters voted the same way at nearly the same time
+	shared := make(map[voterPair]map[int]bool)
+	for start := 0; start < len(votes); {
+		end := start
+		for end < len(votes) && votes[end].PostID == votes[start].PostID {
+			end++
+		}
+		post := votes[start:end]
+		for i := range post {
+			usernames[post[i].VoterID] = post[i].VoterUsername
+			tickers[post[i].StockUserID] = post[i].Ticker
+			for j := i + 1; j < len(post); j++ {
+				if post[i].VoteType != post[j].VoteType || post[j].VotedAt.Sub(post[i].VotedAt) > ringVoteGap {
+					continue
+				}
+				pair := voterPair{stockUserID: post[i].StockUserID, a: post[i].VoterID, b: post[j].VoterID}
+				if pair.a > pair.b {
+					pair.a, pair.b = pair.b, pair.a
+				}
+				if shared[pair] == nil {
+					shared[pair] = make(map[int]bool)
+				}
+				shared[pair][post[i].PostID] = true
+			}
+		}
+		start = end
+	}
+
+	// Link voters who coordinated often enough, then group linked voters per stock
+	parent := make(map[stockVoter]stockVoter)
+	var find func(v stockVoter) stockVoter
+	find = func(v stockVoter) stockVoter {
+		if p := parent[v]; p != v {
+			root := find(p)
+			parent[v] = root
+			return root
+		}
+		return v
+	}
+	for pair, posts := range shared {
+		if len(posts) < ringMinSharedPosts {
+			continue
+		}
+		a := stockVoter{stockUserID: pair.stockUserID, voterID: pair.a}
+		b := stockVoter{stockUserID: pair.stockUserID, voterID: pair.b}
+		for _, v := range []stockVoter{a, b} {
+			if _, ok := parent[v]; !ok {
+				parent[v] = v
+			}
+		}
+		parent[find(b)] = find(a)
+	}
+
+	groups := make(map[stockVoter][]int)
+	for v := range parent {
+		root := find(v)
+		groups[root] = append(groups[root], v.voterID)
+	}
+
+	members := make(map[int]map[int]bool)
+	var rings []models.VotingRing
+	for root, voters := range groups {
+		if len(voters) < ringMinSize {
+			continue
+		}
+		inRing := make(map[int]bool, len(voters))
+		for _, id := range voters {
+			inRing[id] = true
+		}
+		if members[root.stockUserID] == nil {
+			members[root.stockUserID] = make(map[int]bool)
+		}
+		ringPosts := make(map[int]bool)
+		for pair, posts := range shared {
+			if pair.stockUserID == root.stockUserID && inRing[pair.a] && inRing[pair.b] && len(posts) >= ringMinSharedPosts {
+				for postID := range posts {
+					ringPosts[postID] = true
+				}
+			}
+		}
+
+		ring := models.VotingRing{Ticker: tickers[root.stockUserID], SharedPosts: len(ringPosts)}
+		for _, id := range voters {
+			members[root.stockUserID][id] = true
+			ring.Members = append(ring.Members, usernames[id])
+		}
+		sort.Strings(ring.Members)
+		rings = append(rings, ring)
+	}
+	sort.Slice(rings, func(i, j int) bool {
+		if rings[i].Ticker != rings[j].Ticker {
+			return rings[i].Ticker < rings[j].Ticker
+		}
+		return rings[i].Members[0] < rings[j].Members[0]
+	})
+	if rings == nil {
+		rings = []models.VotingRing{}
+	}
+	return members, rings
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"grub-exchange/internal/models"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const sentimentStock = 100
+
+var sentimentNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// sharedVotes has each voter like ringMinSharedPosts posts about sentimentStock, one after
+// another gap apart, ordered by post like GetVotesSince.
+func sharedVotes(voters []int, gap time.Duration) []models.SentimentVote {
+	var votes []models.SentimentVote
+	for post := 1; post <= ringMinSharedPosts; post++ {
+		votedAt := sentimentNow.Add(-time.Duration(post) * time.Hour)
+		for i, voter := range voters {
+			votes = append(votes, models.SentimentVote{
+				PostID:        post,
+				StockUserID:   sentimentStock,
+				VoterID:       voter,
+				VoteType:      1,
+				VotedAt:       votedAt.Add(time.Duration(i) * gap),
+				PostCreatedAt: votedAt,
+			})
+		}
+	}
+	return votes
+}
+
+func TestDetectVotingRings(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes []models.SentimentVote
+		want  map[int]map[int]bool
+	}{
+		{
+			name:  "three linked voters form a ring",
+			votes: sharedVotes([]int{1, 2, 3}, time.Minute),
+			want:  map[int]map[int]bool{sentimentStock: {1: true, 2: true, 3: true}},
+		},
+		{
+			name:  "a linked pair is not a ring",
+			votes: sharedVotes([]int{1, 2}, time.Minute),
+			want:  map[int]map[int]bool{},
+		},
+		{
+			name:  "votes further apart than ringVoteGap don't link",
+			votes: sharedVotes([]int{1, 2, 3}, ringVoteGap+time.Second),
+			want:  map[int]map[int]bool{},
+		},
+		{
+			name:  "too few shared posts don't link",
+			votes: sharedVotes([]int{1, 2, 3}, time.Minute)[3:],
+			want:  map[int]map[int]bool{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rings := detectVotingRings(tt.votes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectVotingRings() members = %v, want %v", got, tt.want)
+			}
+			if len(rings) != len(tt.want) {
+				t.Errorf("detectVotingRings() found %d rings, want %d", len(rings), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestScoreSentiment(t *testing.T) {
+	like := func(voter int, postAge time.Duration) models.SentimentVote {
+		return models.SentimentVote{
+			PostID:        99,
+			StockUserID:   sentimentStock,
+			VoterID:       voter,
+			VoteType:      1,
+			VotedAt:       sentimentNow,
+			PostCreatedAt: sentimentNow.Add(-postAge),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		votes      []models.SentimentVote
+		want       float64
+		discounted int
+	}{
+		{"a fresh vote counts fully", []models.SentimentVote{like(7, 0)}, 1, 0},
+		{"weight after one half-life is a half", []models.SentimentVote{like(7, sentimentHalfLife)}, 0.5, 0},
+		{"a self-vote is discounted", []models.SentimentVote{like(sentimentStock, 0)}, 0, 1},
+		{
+			name:       "a voting ring is discounted",
+			votes:      append(sharedVotes([]int{1, 2, 3}, time.Minute), like(7, 0)),
+			want:       1,
+			discounted: 3 * ringMinSharedPosts,
+		},
+		{
+			name:  "a linked pair still counts",
+			votes: append(sharedVotes([]int{1, 2}, time.Minute), like(7, 0)),
+			// The pair likes posts written one, two and three hours ago
+			want: 1 + 2*(math.Pow(0.5, 1.0/24)+math.Pow(0.5, 2.0/24)+math.Pow(0.5, 3.0/24)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentiments, report := scoreSentiment(tt.votes, sentimentNow)
+			if got := sentiments[sentimentStock]; math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("sentiment = %v, want %v", got, tt.want)
+			}
+			if got := len(report.DiscountedVotes); got != tt.discounted {
+				t.Errorf("discounted %d votes, want %d", got, tt.discounted)
+			}
+		})
+	}
+}
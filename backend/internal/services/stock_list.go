@@ -0,0 +1,156 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"grub-exchange/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Stock list sparklines cover the last week in a fixed number of points, one per bucket
+	sparklineWindow = 7 * 24 * time.Hour
+	sparklinePoints = 28
+)
+
+// ListStocks returns a page of the stock list. q.Cursor is the NextCursor of the previous
+// page with the same sort, or empty for the first page.
+func (s *MarketService) ListStocks(q models.StockListQuery) (*models.StockListPage, error) {
+	var after *models.StockListCursor
+	if q.Cursor != "" {
+		c, err := decodeStockListCursor(q.Cursor, q.Sort, q.Ascending)
+		if err != nil {
+			return nil, err
+		}
+		after = c
+	}
+
+	// Fetch one extra stock to learn whether there's another page
+	stocks, err := s.userRepo.ListStocks(q, after, q.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.StockListPage{Stocks: []models.StockListItem{}}
+	if len(stocks) > q.Limit {
+		stocks = stocks[:q.Limit]
+		last := stocks[q.Limit-1]
+		page.NextCursor = encodeStockListCursor(q.Sort, q.Ascending, models.StockListCursor{
+			Value: stockSortValue(last, q.Sort),
+			ID:    last.ID,
+		})
+	}
+	if len(stocks) == 0 {
+		return page, nil
+	}
+
+	if err := s.fillSparklines(stocks, time.Now()); err != nil {
+		return nil, err
+	}
+	page.Stocks = stocks
+	return page, nil
+}
+
+// fillSparklines gives each stock sparklinePoints prices over the sparkline window: the last
+// price in each bucket, or the previous bucket's price if nothing traded. The final point is
+// always the current price.
+func (s *MarketService) fillSparklines(stocks []models.StockListItem, now time.Time) error {
+	ids := make([]int, len(stocks))
+	for i, st := range stocks {
+		ids[i] = st.ID
+	}
+
+	since := now.Add(-sparklineWindow)
+	bucket := sparklineWindow / sparklinePoints
+	opens, err := s.txnRepo.GetPricesAtFor(ids, since)
+	if err != nil {
+		return err
+	}
+	closes, err := s.txnRepo.GetBucketCloses(ids, since, bucket, sparklinePoints)
+	if err != nil {
+		return err
+	}
+
+	for i, st := range stocks {
+		open, hasOpen := opens[st.ID]
+		stocks[i].SparklineData = downsampleSparkline(open, hasOpen, closes[st.ID], st.CurrentSharePrice)
+	}
+	return nil
+}
+
+// downsampleSparkline builds one stock's sparkline from its bucket closes. Before the first
+// close it uses the price at the start of the window, or failing that the first close.
+func downsampleSparkline(open float64, hasOpen bool, closes map[int]float64, current float64) []float64 {
+	price := open
+	if !hasOpen {
+		price = current
+		for i := 0; i < sparklinePoints; i++ {
+			if p, found := closes[i]; found {
+				price = p
+				break
+			}
+		}
+	}
+
+	points := make([]float64, sparklinePoints)
+	for i := range points {
+		if p, found := closes[i]; found {
+			price = p
+		}
+		points[i] = price
+	}
+	points[len(points)-1] = current
+	return points
+}
+
+// stockSortValue is a stock's value of the sort key, as compared by UserRepo.ListStocks.
+func stockSortValue(st models.StockListItem, sort string) float64 {
+	switch sort {
+	case models.StockSortChange24h:
+		return st.Change24hPercent
+	case models.StockSortVolume:
+		return st.Volume24h
+	case models.StockSortMarketCap:
+		return st.MarketCap
+	case models.StockSortNewest:
+		return float64(st.ID)
+	default:
+		return st.CurrentSharePrice
+	}
+}
+
+// Stock list cursors are opaque to clients: base64 of "sort:direction:value:id". A cursor
+// only continues the listing it came from, so it's rejected under a different sort.
+func encodeStockListCursor(sort string, ascending bool, c models.StockListCursor) string {
+	raw := fmt.Sprintf("%s:%s:%s:%d", sort, sortDirection(ascending), strconv.FormatFloat(c.Value, 'g', -1, 64), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeStockListCursor(cursor, sort string, ascending bool) (*models.StockListCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] != sort || parts[1] != sortDirection(ascending) {
+		return nil, ErrInvalidCursor
+	}
+	value, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &models.StockListCursor{Value: value, ID: id}, nil
+}
+
+func sortDirection(ascending bool) string {
+	if ascending {
+		return "asc"
+	}
+	return "desc"
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"grub-exchange/internal/models"
+	"reflect"
+	"testing"
+)
+
+// sparkline returns sparklinePoints copies of fill, with the given points overridden.
+func sparkline(fill float64, points map[int]float64) []float64 {
+	out := make([]float64, sparklinePoints)
+	for i := range out {
+		out[i] = fill
+	}
+	for i, p := range points {
+		out[i] = p
+	}
+	return out
+}
+
+func TestDownsampleSparkline(t *testing.T) {
+	last := sparklinePoints - 1
+
+	tests := []struct {
+		name    string
+		open    float64
+		hasOpen bool
+		closes  map[int]float64
+		current float64
+		want    []float64
+	}{
+		{
+			name:    "nothing traded keeps the opening price",
+			open:    10,
+			hasOpen: true,
+			current: 10.5,
+			want:    sparkline(10, map[int]float64{last: 10.5}),
+		},
+		{
+			name:    "no history at all is flat at the current price",
+			current: 7,
+			want:    sparkline(7, nil),
+		},
+		{
+			name:    "closes carry forward until the next one",
+			open:    10,
+			hasOpen: true,
+			closes:  map[int]float64{0: 11, 5: 13},
+			current: 14,
+			want: func() []float64 {
+				s := sparkline(13, map[int]float64{last: 14})
+				for i := 0; i < 5; i++ {
+					s[i] = 11
+				}
+				return s
+			}(),
+		},
+		{
+			name:    "without an opening price the first close fills the start",
+			closes:  map[int]float64{3: 12, 10: 9},
+			current: 9.5,
+			want: func() []float64 {
+				s := sparkline(9, map[int]float64{last: 9.5})
+				for i := 0; i < 10; i++ {
+					s[i] = 12
+				}
+				return s
+			}(),
+		},
+		{
+			name:    "the current price replaces the last bucket's close",
+			open:    10,
+			hasOpen: true,
+			closes:  map[int]float64{last: 20},
+			current: 21,
+			want:    sparkline(10, map[int]float64{last: 21}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := downsampleSparkline(tt.open, tt.hasOpen, tt.closes, tt.current)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("downsampleSparkline() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStockListCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		sort      string
+		ascending bool
+		cursor    models.StockListCursor
+	}{
+		{models.StockSortPrice, false, models.StockListCursor{Value: 12.34, ID: 7}},
+		{models.StockSortChange24h, true, models.StockListCursor{Value: -3.125, ID: 1}},
+		{models.StockSortVolume, false, models.StockListCursor{Value: 0, ID: 42}},
+		{models.StockSortMarketCap, true, models.StockListCursor{Value: 1.5e12, ID: 99999}},
+		{models.StockSortNewest, false, models.StockListCursor{Value: 0.1 + 0.2, ID: 3}},
+	}
+
+	for _, tt := range tests {
+		encoded := encodeStockListCursor(tt.sort, tt.ascending, tt.cursor)
+		got, err := decodeStockListCursor(encoded, tt.sort, tt.ascending)
+		if err != nil {
+			t.Errorf("%s: decode(%q): %v", tt.sort, encoded, err)
+			continue
+		}
+		if *got != tt.cursor {
+			t.Errorf("%s: round trip = %+v, want %+v", tt.sort, *got, tt.cursor)
+		}
+	}
+}
+
+func TestDecodeStockListCursorRejects(t *testing.T) {
+	valid := encodeStockListCursor(models.StockSortPrice, false, models.StockListCursor{Value: 10, ID: 5})
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name      string
+		cursor    string
+		sort      string
+		ascending bool
+	}{
+		{"different sort", valid, models.StockSortVolume, false},
+		{"different direction", valid, models.StockSortPrice, true},
+		{"not base64", "not a cursor!", models.StockSortPrice, false},
+		{"too few parts", raw("price:desc:10"), models.StockSortPrice, false},
+		{"too many parts", raw("price:desc:10:5:1"), models.StockSortPrice, false},
+		{"bad value", raw("price:desc:ten:5"), models.StockSortPrice, false},
+		{"bad id", raw("price:desc:10:five"), models.StockSortPrice, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeStockListCursor(tt.cursor, tt.sort, tt.ascending); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeStockListCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"grub-exchange/internal/models"
+	"grub-exchange/internal/repository"
+	"grub-exchange/internal/utils"
+	"log"
+	"time"
+)
+
+// TickerChangeCooldown is how long a user must wait between ticker changes.
+const TickerChangeCooldown = 30 * 24 * time.Hour
+
+// TickerTakenError is returned when a requested ticker is in use. It carries
+// available alternatives so the client can offer them.
+type TickerTakenError struct {
+	Ticker      string
+	Suggestions []string
+}
+
+func (e *TickerTakenError) Error() string {
+	return fmt.Sprintf("ticker %s is already taken", e.Ticker)
+}
+
+type TickerService struct {
+	db            *sql.DB
+	userRepo      *repository.UserRepo
+	portfolioRepo *repository.PortfolioRepo
+	notifRepo     *repository.NotificationRepo
+}
+
+func NewTickerService(
+	db *sql.DB,
+	userRepo *repository.UserRepo,
+	portfolioRepo *repository.PortfolioRepo,
+	notifRepo *repository.NotificationRepo,
+) *TickerService {
+	return &TickerService{
+		db:            db,
+		userRepo:      userRepo,
+		portfolioRepo: portfolioRepo,
+		notifRepo:     notifRepo,
+	}
+}
+
+// Suggest returns up to limit available alternatives to a taken ticker.
+func (s *TickerService) Suggest(base string, limit int) ([]string, error) {
+	var available []string
+	for _, candidate := range utils.SuggestTickers(base) {
+		exists, err := s.userRepo.ExistsByTicker(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			available = append(available, candidate)
+			if len(available) >= limit {
+				break
+			}
+		}
+	}
+	return available, nil
+}
+
+// Check reports whether a ticker is free and, if not, suggests alternatives.
+func (s *TickerService) Check(ticker string) (*models.TickerAvailability, error) {
+	ticker = utils.SanitizeTicker(ticker)
+	if !utils.ValidateTicker(ticker) {
+		return nil, errors.New("ticker must be 2-15 letters, optionally followed by digits")
+	}
+
+	exists, err := s.userRepo.ExistsByTicker(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.TickerAvailability{Ticker: ticker, Available: !exists, Suggestions: []string{}}
+	if exists {
+		suggestions, err := s.Suggest(ticker, 5)
+		if err != nil {
+			return nil, err
+		}
+		result.Suggestions = suggestions
+	}
+	return result, nil
+}
+
+// Resolve returns the ticker to register with. If the requested ticker is taken and
+// autoAssign is set (the ticker was derived from a first name rather than chosen),
+// the first available suggestion is used; otherwise a *TickerTakenError is returned.
+func (s *TickerService) Resolve(ticker string, autoAssign bool) (string, error) {
+	exists, err := s.userRepo.ExistsByTicker(ticker)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return ticker, nil
+	}
+
+	suggestions, err := s.Suggest(ticker, 5)
+	if err != nil {
+		return "", err
+	}
+	if autoAssign && len(suggestions) > 0 {
+		return suggestions[0], nil
+	}
+	return "", &TickerTakenError{Ticker: ticker, Suggestions: suggestions}
+}
+
+// ChangeTicker renames a user's stock. The old ticker is kept as an alias so existing
+// URLs and posts still resolve, and every holder is notified.
+func (s *TickerService) ChangeTicker(userID int, requested string) (*models.User, error) {
+	newTicker := utils.SanitizeTicker(requested)
+	if !utils.ValidateTicker(newTicker) {
+		return nil, errors.New("ticker must be 2-15 letters, optionally followed by digits")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Ticker == newTicker {
+		return nil, errors.New("that is already your ticker")
+	}
+
+	changedAt, err := s.userRepo.GetTickerChangedAt(userID)
+	if err != nil {
+		return nil, err
+	}
+	if changedAt != nil && time.Since(*changedAt) < TickerChangeCooldown {
+		next := changedAt.Add(TickerChangeCooldown)
+		return nil, fmt.Errorf("you can change your ticker again on %s", next.Format("Jan 2, 2006"))
+	}
+
+	available, err := s.userRepo.TickerAvailableFor(newTicker, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !available {
+		suggestions, err := s.Suggest(newTicker, 5)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &TickerTakenError{Ticker: newTicker, Suggestions: suggestions}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.userRepo.ChangeTicker(tx, userID, user.Ticker, newTicker); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.notifyHolders(user, newTicker)
+
+	return s.userRepo.GetByID(userID)
+}
+
+func (s *TickerService) notifyHolders(user *models.User, newTicker string) {
+	if s.notifRepo == nil {
+		return
+	}
+
+	holderIDs, err := s.portfolioRepo.GetOwnerIDsByStock(user.ID)
+	if err != nil {
+		log.Printf("Error loading holders of %s for ticker change: %v", user.Ticker, err)
+		return
+	}
+
+	msg := fmt.Sprintf("%s changed their ticker from %s to %s. Your shares carry over.", user.Username, user.Ticker, newTicker)
+	for _, holderID := range holderIDs {
+		_ = s.notifRepo.Create(holderID, "ticker_change", msg, user.Username, newTicker, 0)
+	}
+}
@@ -16,7 +16,9 @@ type TradingService struct {
 	portfolioRepo *repository.PortfolioRepo
 	txnRepo       *repository.TransactionRepo
 	notifRepo     *repository.NotificationRepo
+	privacyRepo   *repository.PrivacyRepo
 	achieveSvc    *AchievementService
+	alertSvc      *AlertService
 }
 
 func NewTradingService(
@@ -26,7 +28,9 @@ func NewTradingService(
 	portfolioRepo *repository.PortfolioRepo,
 	txnRepo *repository.TransactionRepo,
 	notifRepo *repository.NotificationRepo,
+	privacyRepo *repository.PrivacyRepo,
 	achieveSvc *AchievementService,
+	alertSvc *AlertService,
 ) *TradingService {
 	return &TradingService{
 		db:            db,
@@ -35,7 +39,9 @@ func NewTradingService(
 		portfolioRepo: portfolioRepo,
 		txnRepo:       txnRepo,
 		notifRepo:     notifRepo,
+		privacyRepo:   privacyRepo,
 		achieveSvc:    achieveSvc,
+		alertSvc:      alertSvc,
 	}
 }
 
@@ -62,14 +68,15 @@ func ResolveShares(numShares, grubAmount, executionPrice float64) (float64, erro
 	return math.Round(numShares*10000) / 10000, nil
 }
 
-func (s *TradingService) ExecuteBuy(buyerID int, stockTicker string, numShares float64, grubAmount float64) (*models.TransactionWithDetails, error) {
+// ExecuteBuy buys shares and returns the transaction along with any achievements it unlocked.
+func (s *TradingService) ExecuteBuy(buyerID int, stockTicker string, numShares float64, grubAmount float64) (*models.TransactionWithDetails, []models.UserAchievement, error) {
 	stockUser, err := s.userRepo.GetByTicker(stockTicker)
 	if err != nil {
-		return nil, errors.New("stock not found")
+		return nil, nil, errors.New("stock not found")
 	}
 
 	if stockUser.ID == buyerID {
-		return nil, errors.New("cannot buy your own stock")
+		return nil, nil, errors.New("cannot buy your own stock")
 	}
 
 	// Calculate execution price FIRST so both share-based and grub-based orders
@@ -87,34 +94,34 @@ func (s *TradingService) ExecuteBuy(buyerID int, stockTicker string, numShares f
 		newPrice, execPrice = CalculateTradeExecution(stockUser.CurrentSharePrice, estShares, float64(stockUser.SharesOutstanding))
 		finalShares, err = ResolveShares(0, grubAmount, execPrice)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		// Recalculate with actual shares
 		newPrice, execPrice = CalculateTradeExecution(stockUser.CurrentSharePrice, finalShares, float64(stockUser.SharesOutstanding))
 	} else {
 		finalShares, err = ResolveShares(numShares, 0, stockUser.CurrentSharePrice)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		newPrice, execPrice = CalculateTradeExecution(stockUser.CurrentSharePrice, finalShares, float64(stockUser.SharesOutstanding))
 	}
 
 	balance, err := s.balanceRepo.GetByUserID(buyerID)
 	if err != nil {
-		return nil, errors.New("balance not found")
+		return nil, nil, errors.New("balance not found")
 	}
 
 	// Cost is based on execution price (includes slippage), not spot price
 	totalCost := finalShares * execPrice
 	totalCost = math.Round(totalCost*100) / 100
 	if balance.GrubBalance < totalCost {
-		return nil, errors.New("insufficient Grub balance")
+		return nil, nil, errors.New("insufficient Grub balance")
 	}
 
 	// Read existing holding BEFORE starting transaction
 	existing, err := s.portfolioRepo.GetHolding(buyerID, stockUser.ID)
 	if err != nil && err != sql.ErrNoRows {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var newAvgPrice float64
@@ -129,26 +136,26 @@ func (s *TradingService) ExecuteBuy(buyerID int, stockTicker string, numShares f
 
 	tx, err := s.db.Begin()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer tx.Rollback()
 
 	if err := s.balanceRepo.UpdateBalance(tx, buyerID, -totalCost); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := s.portfolioRepo.UpsertHolding(tx, buyerID, stockUser.ID, newSharesTotal, newAvgPrice); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := s.userRepo.UpdateSharePrice(tx, stockUser.ID, newPrice); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// 2% appreciation to stock owner (based on execution cost)
 	appreciation := totalCost * 0.02
 	if err := s.balanceRepo.UpdateBalance(tx, stockUser.ID, appreciation); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	txn := &models.Transaction{
@@ -160,15 +167,19 @@ func (s *TradingService) ExecuteBuy(buyerID int, stockTicker string, numShares f
 		TotalGrub:       totalCost,
 	}
 	if err := s.txnRepo.Create(tx, txn); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := s.txnRepo.RecordPriceHistory(tx, stockUser.ID, newPrice); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if s.alertSvc != nil {
+		s.alertSvc.OnPriceUpdates([]models.PriceUpdate{{StockUserID: stockUser.ID, OldPrice: stockUser.CurrentSharePrice, NewPrice: newPrice}})
 	}
 
 	buyer, _ := s.userRepo.GetByID(buyerID)
@@ -179,13 +190,17 @@ func (s *TradingService) ExecuteBuy(buyerID int, stockTicker string, numShares f
 
 	// Notify the stock owner that someone bought their stock
 	if s.notifRepo != nil && stockUser.ID != buyerID {
-		msg := fmt.Sprintf("%s just bought %.2f shares of you!", buyerUsername, finalShares)
-		_ = s.notifRepo.Create(stockUser.ID, "trade_buy", msg, buyerUsername, stockUser.Ticker, finalShares)
+		actor, name := s.notificationActor(buyerID, buyerUsername)
+		msg := fmt.Sprintf("%s just bought %.2f shares of you!", name, finalShares)
+		_ = s.notifRepo.Create(stockUser.ID, "trade_buy", msg, actor, stockUser.Ticker, finalShares)
 	}
 
 	// Check achievements for the buyer
+	unlocked := []models.UserAchievement{}
 	if s.achieveSvc != nil {
-		s.achieveSvc.CheckAfterTrade(buyerID)
+		if earned := s.achieveSvc.RecordEvent(buyerID, models.EventTrade, totalCost); earned != nil {
+			unlocked = earned
+		}
 	}
 
 	return &models.TransactionWithDetails{
@@ -195,13 +210,14 @@ func (s *TradingService) ExecuteBuy(buyerID int, stockTicker string, numShares f
 		NumShares:       finalShares,
 		PricePerShare:   execPrice,
 		TotalGrub:       totalCost,
-	}, nil
+	}, unlocked, nil
 }
 
-func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares float64, grubAmount float64) (*models.TransactionWithDetails, error) {
+// ExecuteSell sells shares and returns the transaction along with any achievements it unlocked.
+func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares float64, grubAmount float64) (*models.TransactionWithDetails, []models.UserAchievement, error) {
 	stockUser, err := s.userRepo.GetByTicker(stockTicker)
 	if err != nil {
-		return nil, errors.New("stock not found")
+		return nil, nil, errors.New("stock not found")
 	}
 
 	// Calculate execution price for sells too — seller eats downward slippage
@@ -216,20 +232,20 @@ func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares
 		newPrice, execPrice = CalculateTradeExecution(stockUser.CurrentSharePrice, -estShares, float64(stockUser.SharesOutstanding))
 		finalShares, err = ResolveShares(0, grubAmount, execPrice)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		newPrice, execPrice = CalculateTradeExecution(stockUser.CurrentSharePrice, -finalShares, float64(stockUser.SharesOutstanding))
 	} else {
 		finalShares, err = ResolveShares(numShares, 0, stockUser.CurrentSharePrice)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		newPrice, execPrice = CalculateTradeExecution(stockUser.CurrentSharePrice, -finalShares, float64(stockUser.SharesOutstanding))
 	}
 
 	holding, err := s.portfolioRepo.GetHolding(sellerID, stockUser.ID)
 	if err != nil {
-		return nil, errors.New("you don't own any shares of this stock")
+		return nil, nil, errors.New("you don't own any shares of this stock")
 	}
 
 	if holding.NumShares < finalShares {
@@ -239,7 +255,7 @@ func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares
 			// Recalculate with exact shares
 			newPrice, execPrice = CalculateTradeExecution(stockUser.CurrentSharePrice, -finalShares, float64(stockUser.SharesOutstanding))
 		} else {
-			return nil, errors.New("insufficient shares to sell")
+			return nil, nil, errors.New("insufficient shares to sell")
 		}
 	}
 
@@ -254,12 +270,12 @@ func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares
 
 	tx, err := s.db.Begin()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer tx.Rollback()
 
 	if err := s.balanceRepo.UpdateBalance(tx, sellerID, totalProceeds); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	remainingShares := holding.NumShares - finalShares
@@ -267,16 +283,16 @@ func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares
 	dustValue := remainingShares * stockUser.CurrentSharePrice
 	if remainingShares <= 0.01 || dustValue < 0.10 {
 		if err := s.portfolioRepo.DeleteHolding(tx, sellerID, stockUser.ID); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	} else {
 		if err := s.portfolioRepo.UpsertHolding(tx, sellerID, stockUser.ID, remainingShares, holding.AvgPurchasePrice); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	if err := s.userRepo.UpdateSharePrice(tx, stockUser.ID, newPrice); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	txn := &models.Transaction{
@@ -288,15 +304,19 @@ func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares
 		TotalGrub:       totalProceeds,
 	}
 	if err := s.txnRepo.Create(tx, txn); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := s.txnRepo.RecordPriceHistory(tx, stockUser.ID, newPrice); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if s.alertSvc != nil {
+		s.alertSvc.OnPriceUpdates([]models.PriceUpdate{{StockUserID: stockUser.ID, OldPrice: stockUser.CurrentSharePrice, NewPrice: newPrice}})
 	}
 
 	seller, _ := s.userRepo.GetByID(sellerID)
@@ -307,13 +327,17 @@ func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares
 
 	// Notify the stock owner that someone sold their stock
 	if s.notifRepo != nil && stockUser.ID != sellerID {
-		msg := fmt.Sprintf("%s just sold %.2f shares of you", sellerUsername, finalShares)
-		_ = s.notifRepo.Create(stockUser.ID, "trade_sell", msg, sellerUsername, stockUser.Ticker, finalShares)
+		actor, name := s.notificationActor(sellerID, sellerUsername)
+		msg := fmt.Sprintf("%s just sold %.2f shares of you", name, finalShares)
+		_ = s.notifRepo.Create(stockUser.ID, "trade_sell", msg, actor, stockUser.Ticker, finalShares)
 	}
 
 	// Check achievements for the seller
+	unlocked := []models.UserAchievement{}
 	if s.achieveSvc != nil {
-		s.achieveSvc.CheckAfterTrade(sellerID)
+		if earned := s.achieveSvc.RecordEvent(sellerID, models.EventTrade, totalProceeds); earned != nil {
+			unlocked = earned
+		}
 	}
 
 	return &models.TransactionWithDetails{
@@ -323,5 +347,17 @@ func (s *TradingService) ExecuteSell(sellerID int, stockTicker string, numShares
 		NumShares:       finalShares,
 		PricePerShare:   execPrice,
 		TotalGrub:       totalProceeds,
-	}, nil
+	}, unlocked, nil
+}
+
+// notificationActor returns the actor username and display name for a trade notification
+// sent to a stock's owner. Traders who hide or anonymize their trades appear as "Someone".
+func (s *TradingService) notificationActor(traderID int, username string) (actor, name string) {
+	if s.privacyRepo != nil {
+		settings, err := s.privacyRepo.Get(traderID)
+		if err != nil || settings.HideTrades || settings.AnonymizeTrades {
+			return "", "Someone"
+		}
+	}
+	return username, username
 }
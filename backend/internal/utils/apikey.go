@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const APIKeyPrefix = "grub_"
+
+// GenerateAPIKey returns a new key of the form grub_<prefix>_<secret> and its display prefix.
+// The prefix is stored in plaintext so users can tell keys apart; the full key is only shown once.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	idBytes := make([]byte, 6)
+	secretBytes := make([]byte, 24)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+
+	prefix = APIKeyPrefix + hex.EncodeToString(idBytes)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return key, prefix, nil
+}
+
+// HashAPIKey returns the hex SHA-256 of a key for storage and lookup.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"grub-exchange/internal/models"
+	"strings"
+	"unicode"
+)
+
+// ParseEntities finds @mentions, $cashtags and http(s) links in text, with character
+// offsets. Mentions and cashtags must start a word, so emails and prices like $10 aren't
+// picked up. Mentions get the username as written and cashtags the ticker in upper case;
+// neither is checked against existing users.
+func ParseEntities(text string) []models.PostEntity {
+	runes := []rune(text)
+	var entities []models.PostEntity
+	for i := 0; i < len(runes); i++ {
+		if i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+
+		var e *models.PostEntity
+		switch runes[i] {
+		case '@':
+			end := i + 1
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			if name := string(runes[i+1 : end]); ValidateUsername(name) {
+				e = &models.PostEntity{Type: models.EntityMention, Username: name}
+				e.Start, e.End = i, end
+			}
+		case '$':
+			end := i + 1
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			if ticker := string(runes[i+1 : end]); ValidateTicker(ticker) {
+				e = &models.PostEntity{Type: models.EntityCashtag, Ticker: strings.ToUpper(ticker)}
+				e.Start, e.End = i, end
+			}
+		case 'h', 'H':
+			scheme := strings.ToLower(string(runes[i:min(i+8, len(runes))]))
+			prefixLen := 0
+			if strings.HasPrefix(scheme, "https://") {
+				prefixLen = len("https://")
+			} else if strings.HasPrefix(scheme, "http://") {
+				prefixLen = len("http://")
+			} else {
+				break
+			}
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			// Leave out punctuation that ends the sentence rather than the link
+			for end > i && strings.ContainsRune(".,!?;:'\")]", runes[end-1]) {
+				end--
+			}
+			if end-i > prefixLen {
+				e = &models.PostEntity{Type: models.EntityURL, URL: string(runes[i:end])}
+				e.Start, e.End = i, end
+			}
+		}
+
+		if e != nil {
+			e.Text = string(runes[e.Start:e.End])
+			entities = append(entities, *e)
+			i = e.End - 1
+		}
+	}
+	return entities
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
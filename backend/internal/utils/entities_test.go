@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"grub-exchange/internal/models"
+	"reflect"
+	"testing"
+)
+
+func mention(text string, start int) models.PostEntity {
+	return models.PostEntity{Type: models.EntityMention, Text: text, Start: start, End: start + len([]rune(text)), Username: text[1:]}
+}
+
+func cashtag(text, ticker string, start int) models.PostEntity {
+	return models.PostEntity{Type: models.EntityCashtag, Text: text, Start: start, End: start + len([]rune(text)), Ticker: ticker}
+}
+
+func link(url string, start int) models.PostEntity {
+	return models.PostEntity{Type: models.EntityURL, Text: url, Start: start, End: start + len([]rune(url)), URL: url}
+}
+
+func TestParseEntities(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []models.PostEntity
+	}{
+		{"mention", "hi @alice_1", []models.PostEntity{mention("@alice_1", 3)}},
+		{"email is not a mention", "mail bob@example.com", nil},
+		{"username too short", "@ab", nil},
+		{"cashtag in parentheses", "($grub)", []models.PostEntity{cashtag("$grub", "GRUB", 1)}},
+		{"cashtags before punctuation", "$GRUB, $alex2. $Bob's", []models.PostEntity{
+			cashtag("$GRUB", "GRUB", 0),
+			cashtag("$alex2", "ALEX2", 7),
+			cashtag("$Bob", "BOB", 15),
+		}},
+		{"price is not a cashtag", "up $10 today", nil},
+		{"cashtag inside a word", "a$GRUB", nil},
+		{"offsets count characters", "café @alice", []models.PostEntity{mention("@alice", 5)}},
+		{"link drops trailing punctuation", "see (https://example.com/a?b=1).", []models.PostEntity{link("https://example.com/a?b=1", 5)}},
+		{"bare scheme is not a link", "http:// nothing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseEntities(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEntities(%q) =\n%+v\nwant\n%+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -11,9 +11,14 @@ import (
 type Claims struct {
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
+	// Purpose is empty for session tokens. Short-lived tokens issued for a
+	// specific step (e.g. a pending 2FA login) set it so they can't be used as sessions.
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
+const PurposeTwoFactor = "2fa_challenge"
+
 func getJWTSecret() []byte {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
@@ -37,6 +42,45 @@ func GenerateToken(userID int, username string) (string, error) {
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" || claims.UserID == 0 {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// GenerateChallengeToken issues a short-lived token for the second step of a 2FA login.
+func GenerateChallengeToken(userID int, username string) (string, error) {
+	claims := &Claims{
+		UserID:   userID,
+		Username: username,
+		Purpose:  PurposeTwoFactor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(getJWTSecret())
+}
+
+// ValidateChallengeToken parses a token and checks that it was issued for the given purpose.
+func ValidateChallengeToken(tokenString, purpose string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func parseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -55,3 +99,45 @@ func ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// OIDCStateClaims carries the per-login OIDC values between the redirect and the callback.
+// It's signed so the state, nonce and PKCE verifier can live in a cookie without server storage.
+type OIDCStateClaims struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	jwt.RegisteredClaims
+}
+
+func GenerateOIDCStateToken(state, nonce, codeVerifier string) (string, error) {
+	claims := &OIDCStateClaims{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   "oidc_state",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(getJWTSecret())
+}
+
+func ValidateOIDCStateToken(tokenString string) (*OIDCStateClaims, error) {
+	claims := &OIDCStateClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return getJWTSecret(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.Subject != "oidc_state" {
+		return nil, errors.New("invalid state token")
+	}
+	return claims, nil
+}
@@ -1,21 +1,25 @@
 package utils
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
 
 var (
 	usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
-	tickerRegex   = regexp.MustCompile(`^[a-zA-Z]+$`)
+	// Letters, optionally followed by digits (e.g. ALEX, ALEX2)
+	tickerRegex = regexp.MustCompile(`^[a-zA-Z]+[0-9]*$`)
 )
 
+const maxTickerLength = 15
+
 func ValidateUsername(username string) bool {
 	return len(username) >= 3 && len(username) <= 20 && usernameRegex.MatchString(username)
 }
 
 func ValidateTicker(ticker string) bool {
-	return len(ticker) >= 2 && len(ticker) <= 15 && tickerRegex.MatchString(ticker)
+	return len(ticker) >= 2 && len(ticker) <= maxTickerLength && tickerRegex.MatchString(ticker)
 }
 
 func SanitizeTicker(firstName string) string {
@@ -23,3 +27,29 @@ func SanitizeTicker(firstName string) string {
 	ticker = strings.ToUpper(ticker)
 	return ticker
 }
+
+// SuggestTickers returns alternative tickers for a taken base, in order of preference:
+// numeric suffixes first (ALEX2..ALEX9), then letter suffixes (ALEXA..ALEXZ).
+// The base is shortened if needed so every suggestion passes ValidateTicker.
+func SuggestTickers(base string) []string {
+	base = strings.TrimRightFunc(SanitizeTicker(base), func(r rune) bool { return r >= '0' && r <= '9' })
+	if len(base) > maxTickerLength-1 {
+		base = base[:maxTickerLength-1]
+	}
+
+	var suggestions []string
+	for i := 2; i <= 9; i++ {
+		suggestions = append(suggestions, fmt.Sprintf("%s%d", base, i))
+	}
+	for c := 'A'; c <= 'Z'; c++ {
+		suggestions = append(suggestions, base+string(c))
+	}
+
+	valid := suggestions[:0]
+	for _, s := range suggestions {
+		if ValidateTicker(s) {
+			valid = append(valid, s)
+		}
+	}
+	return valid
+}
@@ -104,8 +104,20 @@ export async function getMarketOverview(): Promise<MarketOverview> {
 }
 
 // Stocks
+// The stock list is paginated, so follow next_cursor until every stock is loaded.
 export async function getStocks(): Promise<{ stocks: StockListItem[] }> {
-  return fetchAPI("/api/stocks");
+  const stocks: StockListItem[] = [];
+  let cursor = "";
+  do {
+    const params = new URLSearchParams({ limit: "200" });
+    if (cursor) params.set("cursor", cursor);
+    const page = await fetchAPI<{ stocks: StockListItem[]; next_cursor?: string }>(
+      `/api/stocks?${params}`
+    );
+    stocks.push(...page.stocks);
+    cursor = page.next_cursor ?? "";
+  } while (cursor);
+  return { stocks };
 }
 
 export async function getStockDetail(ticker: string): Promise<StockDetail> {